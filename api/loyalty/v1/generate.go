@@ -0,0 +1,4 @@
+// Package loyaltyv1 содержит сгенерированный код gRPC API системы лояльности.
+package loyaltyv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative loyalty/v1/loyalty.proto
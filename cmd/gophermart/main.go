@@ -4,19 +4,32 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
+	"github.com/FollowLille/loyalty/internal/accrual"
 	"github.com/FollowLille/loyalty/internal/agent"
-	"github.com/FollowLille/loyalty/internal/app/handlers"
-	"github.com/FollowLille/loyalty/internal/app/middleware"
-	"github.com/FollowLille/loyalty/internal/compress"
+	"github.com/FollowLille/loyalty/internal/app/grpcserver"
+	"github.com/FollowLille/loyalty/internal/app/router"
+	"github.com/FollowLille/loyalty/internal/auth"
 	"github.com/FollowLille/loyalty/internal/config"
 	"github.com/FollowLille/loyalty/internal/database"
+	"github.com/FollowLille/loyalty/internal/events"
+	"github.com/FollowLille/loyalty/internal/health"
+	"github.com/FollowLille/loyalty/internal/metrics"
+	"github.com/FollowLille/loyalty/internal/notify"
+	"github.com/FollowLille/loyalty/internal/services"
+	"github.com/FollowLille/loyalty/internal/tracing"
 )
 
 func main() {
@@ -31,36 +44,124 @@ func main() {
 		os.Exit(1)
 	}
 	config.Logger.Info("Logger initialized")
+	applyTiers()
+
+	notify.Init(flagNotifyFile)
+
+	shutdownTracing, err := tracing.Init(context.Background(), flagTraceExporter)
+	if err != nil {
+		config.Logger.Error("Failed to initialize tracing", zap.Error(err))
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			config.Logger.Error("Failed to shut down tracing", zap.Error(err))
+		}
+	}()
 
 	if err := prepareDB(); err != nil {
 		config.Logger.Error("Failed to prepare database", zap.Error(err))
 		os.Exit(1)
 	}
 
-	router := gin.New()
-	router.Use(gin.Recovery(), config.RequestLogger(), config.ResponseLogger())
-	router.Use(compress.GzipMiddleware(), compress.GzipResponseMiddleware())
-
-	public := router.Group("/api/user")
-	{
-		public.POST("/register", handlers.Register)
-		public.POST("/login", handlers.Login)
+	if flagPromoteAdmin != "" {
+		if err := services.GrantRole(context.Background(), flagPromoteAdmin, auth.RoleAdmin); err != nil {
+			config.Logger.Error("Failed to promote user to admin", zap.String("user", flagPromoteAdmin), zap.Error(err))
+			os.Exit(1)
+		}
+		config.Logger.Info("User promoted to admin", zap.String("user", flagPromoteAdmin))
+		return
 	}
 
-	protected := router.Group("/api/user")
-	protected.Use(middleware.AuthMiddleware())
-	{
-		protected.POST("/orders", handlers.UploadOrder)
-		protected.GET("/orders", handlers.GetOrders)
-		protected.GET("/balance", handlers.GetBalance)
-		protected.POST("/balance/withdraw", handlers.GetWithdrawRequest)
-		protected.GET("/withdrawals", handlers.GetWithdrawals)
+	engine, err := router.New()
+	if err != nil {
+		config.Logger.Error("Failed to create router", zap.Error(err))
+		os.Exit(1)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	config.Logger.Info("Starting server...", zap.String("address", flagAddress))
 
-	agent.StartAgent(flagAPI)
-	router.Run(flagAddress)
+	orderAgent := agent.StartAgent(flagAPI)
+	registerHealthChecks(orderAgent)
+	go func() {
+		if err := events.Default.Listen(flagDatabaseAddress, ctx.Done()); err != nil {
+			config.Logger.Error("Failed to listen for user events", zap.Error(err))
+		}
+	}()
+	if flagMetricsAddress != "" {
+		if err := metrics.RegisterDBStats(database.DB); err != nil {
+			config.Logger.Error("Failed to register database metrics", zap.Error(err))
+		}
+		go func() {
+			if err := metrics.Run(flagMetricsAddress); err != nil {
+				config.Logger.Error("Metrics server stopped", zap.Error(err))
+			}
+		}()
+	}
+	var grpcServer *grpc.Server
+	if flagGRPCAddress != "" {
+		grpcServer = grpcserver.New()
+		go func() {
+			if err := grpcserver.Run(grpcServer, flagGRPCAddress); err != nil {
+				config.Logger.Error("gRPC server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	server := &http.Server{Addr: flagAddress, Handler: engine}
+	// Потоки событий не завершаются сами, поэтому при остановке сервера их подписки закрываются
+	server.RegisterOnShutdown(events.Default.Close)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			config.Logger.Error("HTTP server stopped", zap.Error(err))
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	shutdown(server, grpcServer, orderAgent)
+}
+
+// registerHealthChecks регистрирует проверки зависимостей, по которым /readyz определяет готовность сервиса.
+func registerHealthChecks(orderAgent *agent.OrderAgent) {
+	health.Default.Register("database", database.Ping)
+	health.Default.Register("migrations", database.CheckSchemaVersion)
+	health.Default.Register("agent", orderAgent.CheckHealth)
+	if flagAPI {
+		health.Default.Register("accrual", accrual.Ping)
+	}
+}
+
+// shutdown плавно завершает работу сервиса: сначала /readyz начинает отвечать 503,
+// через config.ShutdownDelay серверы перестают принимать запросы, закрывают потоки событий и дожидаются
+// текущих запросов, после чего останавливается агент.
+func shutdown(server *http.Server, grpcServer *grpc.Server, orderAgent *agent.OrderAgent) {
+	config.Logger.Info("Shutting down...", zap.Duration("delay", config.ShutdownDelay))
+	health.Default.SetShuttingDown()
+	time.Sleep(config.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		config.Logger.Error("Failed to shut down HTTP server", zap.Error(err))
+	}
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
+	orderAgent.StopAgent()
+	config.Logger.Info("Server stopped")
 }
 
 func prepareDB() error {
@@ -71,6 +172,9 @@ func prepareDB() error {
 	if err := database.PrepareDB(); err != nil {
 		return fmt.Errorf("failed to prepare database: %w", err)
 	}
+	if err := database.SyncTiers(config.Tiers); err != nil {
+		return fmt.Errorf("failed to sync tiers: %w", err)
+	}
 	config.Logger.Info("Database prepared")
 	return nil
 }
@@ -3,16 +3,22 @@
 package accrual
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 
 	"github.com/FollowLille/loyalty/internal/config"
 	"github.com/FollowLille/loyalty/internal/database"
 	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/metrics"
+	"github.com/FollowLille/loyalty/internal/tracing"
 )
 
 // ExternalAccrualResponse описывает структуру ответа от внешней системы начислений.
@@ -25,39 +31,55 @@ type ExternalAccrualResponse struct {
 // FetchOrderAccrual возвращает информацию о начислениях по указанному номеру заказа
 // Если произошла ошибка при выполнении запроса, программа завершается с кодом ошибки.
 // В случае успеха, возвращает структуру ExternalAccrualResponse
+// Идентификатор запроса из контекста передается во внешнюю систему в заголовке X-Request-ID,
+// контекст трассы - в заголовке traceparent.
 // Параметры:
+//   - ctx: контекст запроса
 //   - orderNumber: номер заказа
 //
 // Возвращаемое значение:
 //   - ExternalAccrualResponse: структура с информацией о начислениях
 //   - error: в случае ошибки
-func FetchOrderAccrual(orderNumber string) (*ExternalAccrualResponse, error) {
+func FetchOrderAccrual(ctx context.Context, orderNumber string) (result *ExternalAccrualResponse, err error) {
+	logger := config.LoggerFromContext(ctx)
 	url := fmt.Sprintf("%s/api/orders/%s", config.AccrualAPIURL, orderNumber)
-	config.Logger.Info("Requesting external accrual API", zap.String("url", url))
+	ctx, span := tracing.StartClient(ctx, "accrual.FetchOrderAccrual",
+		attribute.String("http.request.method", http.MethodGet),
+		attribute.String("url.full", url),
+	)
+	defer func() { tracing.End(span, err) }()
+	logger.Info("Requesting external accrual API", zap.String("url", url))
 
 	client := &http.Client{
 		Timeout: 30 * time.Second, // Устанавливаем таймаут на запрос.
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		config.Logger.Error("Failed to create request", zap.Error(err))
+		logger.Error("Failed to create request", zap.Error(err))
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if requestID := config.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(config.RequestIDHeader, requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	resp, err := client.Do(req)
 	if err != nil {
-		config.Logger.Error("Failed to perform request", zap.Error(err))
+		metrics.ObserveAccrualRequest(0)
+		logger.Error("Failed to perform request", zap.Error(err))
 		return nil, err
 	}
 	defer resp.Body.Close()
+	metrics.ObserveAccrualRequest(resp.StatusCode)
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	switch resp.StatusCode {
 	case http.StatusOK: // 200 OK
 		var response ExternalAccrualResponse
 		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			config.Logger.Error("Failed to decode response", zap.Error(err))
+			logger.Error("Failed to decode response", zap.Error(err))
 			return nil, err
 		}
 		return &response, nil
@@ -67,19 +89,19 @@ func FetchOrderAccrual(orderNumber string) (*ExternalAccrualResponse, error) {
 		if retryAfter == "" {
 			retryAfter = "60"
 		}
-		config.Logger.Warn("Too many requests", zap.String("retry-after", retryAfter))
+		logger.Warn("Too many requests", zap.String("retry-after", retryAfter))
 		return nil, fmt.Errorf("too many requests, retry after %s seconds", retryAfter)
 
 	case http.StatusNoContent: // 204 No Content
-		config.Logger.Info("Order not found in external system", zap.String("order", orderNumber))
+		logger.Info("Order not found in external system", zap.String("order", orderNumber))
 		return nil, cstmerr.ErrOrderNotFound
 
 	case http.StatusInternalServerError: // 500 Internal Server Error
-		config.Logger.Error("External API returned internal server error")
+		logger.Error("External API returned internal server error")
 		return nil, fmt.Errorf("internal server error from external API")
 
 	default:
-		config.Logger.Error("Unexpected status code from external API", zap.Int("status", resp.StatusCode))
+		logger.Error("Unexpected status code from external API", zap.Int("status", resp.StatusCode))
 		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
 	}
 }
@@ -87,24 +109,46 @@ func FetchOrderAccrual(orderNumber string) (*ExternalAccrualResponse, error) {
 // ProcessOrderAccrual обрабатывает информацию о начислениях по указанному номеру заказа
 // Если произошла ошибка при обработке заказа, программа завершается с кодом ошибки.
 // Параметры:
+//   - ctx: контекст запроса
 //   - orderNumber: номер заказа
 //
 // Возвращаемое значение:
 //   - error: в случае ошибки
-func ProcessOrderAccrual(orderNumber string) error {
-	config.Logger.Info("Processing order accrual", zap.String("order", orderNumber))
+func ProcessOrderAccrual(ctx context.Context, orderNumber string) error {
+	logger := config.LoggerFromContext(ctx)
+	logger.Info("Processing order accrual", zap.String("order", orderNumber))
 
-	response, err := FetchOrderAccrual(orderNumber)
+	response, err := FetchOrderAccrual(ctx, orderNumber)
 	if err != nil {
 		return err
 	}
 
-	err = database.UpdateOrder(orderNumber, response.Status, response.Accrual)
+	err = database.UpdateOrder(ctx, orderNumber, response.Status, response.Accrual)
 	if err != nil {
-		config.Logger.Error("Failed to update order", zap.Error(err))
+		logger.Error("Failed to update order", zap.Error(err))
 		return fmt.Errorf("failed to update order: %w", err)
 	}
 
-	config.Logger.Info("Order processed", zap.String("order", orderNumber))
+	logger.Info("Order processed", zap.String("order", orderNumber))
+	return nil
+}
+
+// Ping проверяет, что система начислений отвечает на HTTP-запросы.
+// Любой ответ, включая ошибку HTTP, означает, что система доступна.
+// Параметры:
+//   - ctx: контекст запроса
+//
+// Возвращаемое значение:
+//   - error: в случае, если ответ не получен
+func Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.AccrualAPIURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("accrual system is unreachable: %w", err)
+	}
+	resp.Body.Close()
 	return nil
 }
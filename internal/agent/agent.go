@@ -1,6 +1,11 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -8,12 +13,18 @@ import (
 	accrualHandler "github.com/FollowLille/loyalty/internal/accrual"
 	"github.com/FollowLille/loyalty/internal/config"
 	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/metrics"
+	"github.com/FollowLille/loyalty/internal/services"
 )
 
 type OrderAgent struct {
 	Interval       time.Duration
 	UseExternalAPI bool
-	stopCh         chan struct{} // канал для завершения работы агента
+	MaxCycleAge    time.Duration  // время без успешной обработки заказов, после которого агент считается неработающим
+	stopCh         chan struct{}  // канал для завершения работы агента
+	wg             sync.WaitGroup // фоновые задачи агента
+	lastSuccess    atomic.Int64   // время последнего успешно обновленного заказа или цикла без ошибок, UnixNano
 }
 
 var statuses = []string{"PROCESSING", "PROCESSED", "INVALID"}
@@ -41,6 +52,12 @@ func generateStatusAndAccrual() (string, float64) {
 	return status, 0
 }
 
+// newCycleContext возвращает контекст очередного цикла фоновой задачи с собственным идентификатором запроса,
+// по которому в логах можно найти все записи этого цикла
+func newCycleContext() context.Context {
+	return config.WithRequestID(context.Background(), config.NewRequestID())
+}
+
 // processOrders обрабатывает актуальные заказы
 // Агент постоянно ходит в базу данных, проверяет наличие необработанных заказов и обрабатывает их
 func (a *OrderAgent) processOrders() {
@@ -51,20 +68,31 @@ func (a *OrderAgent) processOrders() {
 	for {
 		select {
 		case <-ticker.C:
-			orders, err := database.GetOrdersByStatus()
+			start := time.Now()
+			ctx := newCycleContext()
+			logger := config.LoggerFromContext(ctx)
+			orders, err := database.GetOrdersByStatus(ctx)
 			if err != nil {
-				config.Logger.Error("Failed to get orders", zap.Error(err))
+				logger.Error("Failed to get orders", zap.Error(err))
 				continue
 			}
+			metrics.SetOrderBacklog(len(orders))
+			// Успех отмечается после каждого обновленного заказа, поэтому медленная система начислений
+			// при большом числе заказов не делает агента неработающим, пока заказы обновляются
+			failed := false
 			for _, order := range orders {
 				var status string
 				var accrual float64
 
 				if a.UseExternalAPI {
-					config.Logger.Info("Get order from external API", zap.String("order_number", order.Number))
-					response, err := accrualHandler.FetchOrderAccrual(order.Number)
+					logger.Info("Get order from external API", zap.String("order_number", order.Number))
+					response, err := accrualHandler.FetchOrderAccrual(ctx, order.Number)
 					if err != nil {
-						config.Logger.Error("Failed to get order from external API", zap.Error(err))
+						// Заказ, еще не зарегистрированный в системе начислений, не считается сбоем агента
+						if !errors.Is(err, cstmerr.ErrOrderNotFound) {
+							failed = true
+						}
+						logger.Error("Failed to get order from external API", zap.Error(err))
 						continue
 					}
 
@@ -74,18 +102,24 @@ func (a *OrderAgent) processOrders() {
 					status, accrual = generateStatusAndAccrual()
 				}
 
-				err = database.UpdateOrder(order.Number, status, accrual)
+				err = database.UpdateOrder(ctx, order.Number, status, accrual)
 				if err != nil {
-					config.Logger.Error("Failed to update order", zap.Error(err))
+					failed = true
+					logger.Error("Failed to update order", zap.Error(err))
 					continue
 				}
+				a.markSuccess()
 
-				config.Logger.Info("Updated order",
+				logger.Info("Updated order",
 					zap.String("order_number", order.Number),
 					zap.String("status", status),
 					zap.Float64("accrual", accrual),
 				)
 			}
+			if !failed {
+				a.markSuccess()
+			}
+			metrics.ObserveAgentCycle("process_orders", time.Since(start))
 		case <-a.stopCh:
 			config.Logger.Info("Stopping order processing")
 			return
@@ -93,19 +127,163 @@ func (a *OrderAgent) processOrders() {
 	}
 }
 
+// cleanupIdempotencyKeys периодически удаляет ключи идемпотентности с истекшим сроком хранения
+func (a *OrderAgent) cleanupIdempotencyKeys() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			start := time.Now()
+			ctx := newCycleContext()
+			if err := database.DeleteExpiredIdempotencyKeys(ctx, config.IdempotencyKeyTTL); err != nil {
+				config.LoggerFromContext(ctx).Error("Failed to delete expired idempotency keys", zap.Error(err))
+			}
+			metrics.ObserveAgentCycle("cleanup_idempotency_keys", time.Since(start))
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// cleanupUserEvents периодически удаляет события пользователей с истекшим сроком хранения
+func (a *OrderAgent) cleanupUserEvents() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			start := time.Now()
+			ctx := newCycleContext()
+			if err := services.DeleteExpiredUserEvents(ctx); err != nil {
+				config.LoggerFromContext(ctx).Error("Failed to delete expired user events", zap.Error(err))
+			}
+			metrics.ObserveAgentCycle("cleanup_user_events", time.Since(start))
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// dispatchWebhooks периодически доставляет события подписчикам вебхуков
+func (a *OrderAgent) dispatchWebhooks() {
+	ticker := time.NewTicker(config.WebhookDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			start := time.Now()
+			ctx := newCycleContext()
+			if err := services.DispatchWebhooks(ctx); err != nil {
+				config.LoggerFromContext(ctx).Error("Failed to dispatch webhooks", zap.Error(err))
+			}
+			metrics.ObserveAgentCycle("dispatch_webhooks", time.Since(start))
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// expirePoints периодически списывает баллы с истекшим сроком жизни
+func (a *OrderAgent) expirePoints() {
+	ticker := time.NewTicker(config.PointsExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			start := time.Now()
+			ctx := newCycleContext()
+			if err := services.ExpirePoints(ctx); err != nil {
+				config.LoggerFromContext(ctx).Error("Failed to expire points", zap.Error(err))
+			}
+			metrics.ObserveAgentCycle("expire_points", time.Since(start))
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// evaluateTiers ежесуточно в config.TierEvaluationHour (UTC) пересчитывает уровни пользователей
+func (a *OrderAgent) evaluateTiers() {
+	timer := time.NewTimer(untilNextDailyRun(time.Now(), config.TierEvaluationHour))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			start := time.Now()
+			ctx := newCycleContext()
+			if err := services.EvaluateTiers(ctx); err != nil {
+				config.LoggerFromContext(ctx).Error("Failed to evaluate tiers", zap.Error(err))
+			}
+			metrics.ObserveAgentCycle("evaluate_tiers", time.Since(start))
+			timer.Reset(untilNextDailyRun(time.Now(), config.TierEvaluationHour))
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// untilNextDailyRun возвращает время, оставшееся до ближайшего наступления часа hour (UTC)
+func untilNextDailyRun(now time.Time, hour int) time.Duration {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next.Sub(now)
+}
+
 // StartAgent запускает агента с генерацией случайных данных
 func StartAgent(apiFlag bool) *OrderAgent {
 	agent := &OrderAgent{
 		Interval:       5 * time.Second,
 		UseExternalAPI: apiFlag,
+		MaxCycleAge:    time.Minute,
 		stopCh:         make(chan struct{}),
 	}
-	go agent.processOrders()
+	// До первого цикла отсчет ведется от запуска агента
+	agent.markSuccess()
+	for _, task := range []func(){
+		agent.processOrders,
+		agent.cleanupIdempotencyKeys,
+		agent.cleanupUserEvents,
+		agent.expirePoints,
+		agent.dispatchWebhooks,
+		agent.evaluateTiers,
+	} {
+		agent.wg.Add(1)
+		go func(task func()) {
+			defer agent.wg.Done()
+			task()
+		}(task)
+	}
 	return agent
 }
 
-// StopAgent завершает работу агента
+// markSuccess отмечает успешную обработку заказов
+func (a *OrderAgent) markSuccess() {
+	a.lastSuccess.Store(time.Now().UnixNano())
+}
+
+// CheckHealth проверяет, что обработка заказов последний раз завершилась успешно не раньше MaxCycleAge назад:
+// был обновлен заказ или завершился цикл без ошибок. Если все запросы к системе начислений
+// или все обновления заказов завершаются ошибкой, агент считается неработающим
+func (a *OrderAgent) CheckHealth(_ context.Context) error {
+	age := time.Since(time.Unix(0, a.lastSuccess.Load()))
+	if age > a.MaxCycleAge {
+		return fmt.Errorf("last successful order processing was %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// StopAgent завершает работу агента и дожидается окончания текущих циклов фоновых задач
 func (a *OrderAgent) StopAgent() {
 	close(a.stopCh)
+	a.wg.Wait()
 	config.Logger.Info("Agent stopped")
 }
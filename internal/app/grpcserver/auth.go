@@ -0,0 +1,63 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	loyaltyv1 "github.com/FollowLille/loyalty/api/loyalty/v1"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// userIDKey - ключ контекста, под которым хранится идентификатор аутентифицированного пользователя.
+type userIDKey struct{}
+
+// publicMethods перечисляет методы, которые не требуют токена.
+var publicMethods = map[string]bool{
+	loyaltyv1.LoyaltyService_Register_FullMethodName: true,
+	loyaltyv1.LoyaltyService_Login_FullMethodName:    true,
+}
+
+// authInterceptor проверяет JWT-токен из метаданных authorization так же, как AuthMiddleware для HTTP.
+// Идентификатор пользователя сохраняется в контексте вызова.
+func authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+loyaltyv1.LoyaltyService_ServiceDesc.ServiceName+"/") || publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, toStatus(fmt.Errorf("%w: authorization metadata required", cstmerr.ErrorUnauthorized))
+	}
+	userID, _, err := services.AuthenticateToken(ctx, token)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return handler(context.WithValue(ctx, userIDKey{}, userID), req)
+}
+
+// bearerToken возвращает токен из метаданных authorization: "Bearer <token>".
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(values[0], "Bearer ")
+}
+
+// userIDFromContext возвращает идентификатор пользователя, сохраненный authInterceptor.
+func userIDFromContext(ctx context.Context) (int64, error) {
+	userID, ok := ctx.Value(userIDKey{}).(int64)
+	if !ok {
+		return 0, toStatus(cstmerr.ErrorUnauthorized)
+	}
+	return userID, nil
+}
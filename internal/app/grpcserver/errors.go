@@ -0,0 +1,61 @@
+package grpcserver
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// errorDomain - домен ошибок в errdetails.ErrorInfo.
+const errorDomain = "loyalty"
+
+// grpcCodes сопоставляет HTTP-статус доменной ошибки коду gRPC.
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusPaymentRequired:     codes.FailedPrecondition,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusLocked:              codes.ResourceExhausted,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+}
+
+// toStatus переводит доменную ошибку в статус gRPC.
+// Сопоставление ошибок берется из пакета problem, стабильный код ошибки передается в errdetails.ErrorInfo.
+// Внутренние ошибки логируются, их текст клиенту не передается.
+func toStatus(err error) error {
+	p := problem.New(err)
+	code, ok := grpcCodes[p.Status]
+	if !ok {
+		config.Logger.Error("gRPC call failed", zap.Error(err))
+		return status.Error(codes.Internal, p.Title)
+	}
+
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+	st := status.New(code, message)
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: p.Code, Domain: errorDomain}}
+	var blockErr *cstmerr.LoginBlockedError
+	if errors.As(err, &blockErr) {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(blockErr.RetryAfter)})
+	}
+	if withDetails, detailsErr := st.WithDetails(details...); detailsErr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
@@ -0,0 +1,31 @@
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// requestIDMetadataKey - ключ метаданных с идентификатором запроса, аналог заголовка X-Request-ID.
+const requestIDMetadataKey = "x-request-id"
+
+// requestIDInterceptor присваивает вызову идентификатор из метаданных x-request-id или генерирует новый
+// так же, как RequestIDMiddleware для HTTP. Идентификатор возвращается в заголовке ответа,
+// а в контексте вызова сохраняется логгер, добавляющий его к каждой записи.
+func requestIDInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMetadataKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !config.ValidRequestID(requestID) {
+		requestID = config.NewRequestID()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+	return handler(config.WithRequestID(ctx, requestID), req)
+}
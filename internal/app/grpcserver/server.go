@@ -0,0 +1,44 @@
+// Package grpcserver предоставляет gRPC API системы лояльности.
+// Операции повторяют HTTP-хендлеры /api/user и используют тот же слой services.
+package grpcserver
+
+import (
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	loyaltyv1 "github.com/FollowLille/loyalty/api/loyalty/v1"
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// New создает gRPC-сервер с зарегистрированным LoyaltyService, идентификаторами запросов, проверкой JWT-токена
+// и server reflection.
+//
+// Возвращает:
+//   - *grpc.Server: gRPC-сервер.
+func New() *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(requestIDInterceptor, authInterceptor))
+	loyaltyv1.RegisterLoyaltyServiceServer(server, &loyaltyServer{})
+	reflection.Register(server)
+	return server
+}
+
+// Run запускает gRPC-сервер на указанном адресе. Блокируется до остановки сервера.
+//
+// Параметры:
+//   - server: gRPC-сервер.
+//   - address: адрес, на котором принимаются соединения.
+//
+// Возвращает:
+//   - error: ошибка, если не удалось начать принимать соединения.
+func Run(server *grpc.Server, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	config.Logger.Info("Starting gRPC server...", zap.String("address", address))
+	return server.Serve(listener)
+}
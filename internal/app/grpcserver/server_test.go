@@ -0,0 +1,153 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	loyaltyv1 "github.com/FollowLille/loyalty/api/loyalty/v1"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// dial запускает gRPC-сервер в памяти и возвращает подключение к нему.
+func dial(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := New()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := loyaltyv1.NewLoyaltyServiceClient(dial(t))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "no_token"},
+		{name: "invalid_token", token: "Bearer invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.token)
+			}
+			_, err := client.GetBalance(ctx, &loyaltyv1.GetBalanceRequest{})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	client := loyaltyv1.NewLoyaltyServiceClient(dial(t))
+
+	tests := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{name: "propagated", requestID: "req-42", wantSame: true},
+		{name: "generated"},
+		{name: "invalid", requestID: "req 42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", tt.requestID)
+			}
+			var header metadata.MD
+			_, err := client.GetBalance(ctx, &loyaltyv1.GetBalanceRequest{}, grpc.Header(&header))
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+			values := header.Get("x-request-id")
+			require.Len(t, values, 1)
+			if tt.wantSame {
+				assert.Equal(t, tt.requestID, values[0])
+			} else {
+				assert.NotEqual(t, tt.requestID, values[0])
+				assert.NotEmpty(t, values[0])
+			}
+		})
+	}
+}
+
+func TestRegister_InvalidRequest(t *testing.T) {
+	client := loyaltyv1.NewLoyaltyServiceClient(dial(t))
+
+	_, err := client.Register(context.Background(), &loyaltyv1.RegisterRequest{Login: "user"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestReflection(t *testing.T) {
+	client := grpc_reflection_v1.NewServerReflectionClient(dial(t))
+
+	stream, err := client.ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&grpc_reflection_v1.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_ListServices{},
+	}))
+	response, err := stream.Recv()
+	require.NoError(t, err)
+
+	var services []string
+	for _, service := range response.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	assert.Contains(t, services, loyaltyv1.LoyaltyService_ServiceDesc.ServiceName)
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{name: "insufficient_balance", err: cstmerr.ErrorInsufficientBalance, wantCode: codes.FailedPrecondition, wantReason: "insufficient_balance"},
+		{name: "invalid_order_number", err: cstmerr.ErrorInvalidOrderNumber, wantCode: codes.InvalidArgument, wantReason: "invalid_order_number"},
+		{name: "uploaded_by_another_user", err: cstmerr.ErrorOrderUploadedByAnotherUser, wantCode: codes.AlreadyExists, wantReason: "order_uploaded_by_another_user"},
+		{name: "invalid_credentials", err: cstmerr.ErrorInvalidCredentials, wantCode: codes.Unauthenticated, wantReason: "invalid_credentials"},
+		{name: "login_blocked", err: &cstmerr.LoginBlockedError{Err: cstmerr.ErrorAccountLocked, RetryAfter: time.Minute}, wantCode: codes.ResourceExhausted, wantReason: "account_locked"},
+		{name: "internal", err: assert.AnError, wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			assert.Equal(t, tt.wantCode, st.Code())
+
+			var reason string
+			for _, detail := range st.Details() {
+				if info, ok := detail.(*errdetails.ErrorInfo); ok {
+					reason = info.GetReason()
+				}
+			}
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
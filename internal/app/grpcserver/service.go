@@ -0,0 +1,211 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	loyaltyv1 "github.com/FollowLille/loyalty/api/loyalty/v1"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// loyaltyServer реализует loyaltyv1.LoyaltyServiceServer поверх пакета services.
+type loyaltyServer struct {
+	loyaltyv1.UnimplementedLoyaltyServiceServer
+}
+
+// Register регистрирует пользователя и возвращает токен.
+// RegisterRequest не содержит реферального кода, поэтому регистрация по приглашению доступна только через HTTP API.
+func (s *loyaltyServer) Register(ctx context.Context, req *loyaltyv1.RegisterRequest) (*loyaltyv1.AuthResponse, error) {
+	if req.GetLogin() == "" || req.GetPassword() == "" {
+		return nil, toStatus(fmt.Errorf("%w: login and password are required", cstmerr.ErrorInvalidRequest))
+	}
+	token, err := services.RegisterUser(ctx, req.GetLogin(), req.GetPassword(), "", clientIP(ctx), userAgent(ctx))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &loyaltyv1.AuthResponse{Token: token}, nil
+}
+
+// Login выполняет вход пользователя и возвращает токен.
+func (s *loyaltyServer) Login(ctx context.Context, req *loyaltyv1.LoginRequest) (*loyaltyv1.AuthResponse, error) {
+	if req.GetLogin() == "" || req.GetPassword() == "" {
+		return nil, toStatus(fmt.Errorf("%w: login and password are required", cstmerr.ErrorInvalidRequest))
+	}
+	token, err := services.LoginUser(ctx, req.GetLogin(), req.GetPassword(), clientIP(ctx), userAgent(ctx))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &loyaltyv1.AuthResponse{Token: token}, nil
+}
+
+// UploadOrder загружает номер заказа. Повторная загрузка заказа тем же пользователем ошибкой не считается.
+func (s *loyaltyServer) UploadOrder(ctx context.Context, req *loyaltyv1.UploadOrderRequest) (*loyaltyv1.UploadOrderResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = services.UploadOrder(ctx, userID, req.GetNumber())
+	if errors.Is(err, cstmerr.ErrorOrderAlreadyUploaded) {
+		return &loyaltyv1.UploadOrderResponse{AlreadyUploaded: true}, nil
+	} else if err != nil {
+		return nil, toStatus(err)
+	}
+	return &loyaltyv1.UploadOrderResponse{}, nil
+}
+
+// ListOrders возвращает страницу загруженных заказов.
+func (s *loyaltyServer) ListOrders(ctx context.Context, req *loyaltyv1.ListOrdersRequest) (*loyaltyv1.ListOrdersResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := services.GetOrders(ctx, userID, services.OrdersQuery{
+		Limit:     int(req.GetLimit()),
+		Cursor:    req.GetCursor(),
+		Statuses:  req.GetStatuses(),
+		From:      optionalTime(req.GetFrom()),
+		To:        optionalTime(req.GetTo()),
+		Ascending: req.GetAscending(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &loyaltyv1.ListOrdersResponse{NextCursor: page.NextCursor}
+	for _, order := range page.Orders {
+		response.Orders = append(response.Orders, &loyaltyv1.Order{
+			Number:     order.Number,
+			Status:     order.Status,
+			Accrual:    order.Accrual,
+			UploadedAt: timestamppb.New(order.UploadedAt),
+		})
+	}
+	return response, nil
+}
+
+// GetBalance возвращает текущий баланс пользователя.
+func (s *loyaltyServer) GetBalance(ctx context.Context, _ *loyaltyv1.GetBalanceRequest) (*loyaltyv1.Balance, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := services.FetchUserBalance(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &loyaltyv1.Balance{
+		Current:   balance.Current,
+		Withdrawn: balance.Withdrawn,
+		Pending:   &loyaltyv1.PendingBalance{Orders: balance.Pending.Orders, Accrual: balance.Pending.Accrual},
+	}
+	for _, expiring := range balance.ExpiringSoon {
+		response.ExpiringSoon = append(response.ExpiringSoon, &loyaltyv1.ExpiringPoints{
+			Amount:    expiring.Amount,
+			ExpiresAt: timestamppb.New(expiring.ExpiresAt),
+		})
+	}
+	return response, nil
+}
+
+// Withdraw списывает баллы в счет оплаты заказа.
+// WithdrawRequest не содержит мерчанта, поэтому списание не закрепляется за мерчантом, как при указании merchant в HTTP API.
+func (s *loyaltyServer) Withdraw(ctx context.Context, req *loyaltyv1.WithdrawRequest) (*loyaltyv1.WithdrawResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = services.ProcessWithdrawRequest(ctx, userID, services.WithdrawRequest{Order: req.GetOrder(), Sum: req.GetSum()}); err != nil {
+		return nil, toStatus(err)
+	}
+	return &loyaltyv1.WithdrawResponse{}, nil
+}
+
+// ListWithdrawals возвращает страницу списаний.
+// В сообщениях gRPC нет мерчанта списания и суммы возвратов в итогах за период, эти поля доступны только через HTTP API.
+func (s *loyaltyServer) ListWithdrawals(ctx context.Context, req *loyaltyv1.ListWithdrawalsRequest) (*loyaltyv1.ListWithdrawalsResponse, error) {
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := services.FetchWithdrawalsPage(ctx, userID, services.WithdrawalsQuery{
+		Limit:   int(req.GetLimit()),
+		Cursor:  req.GetCursor(),
+		From:    optionalTime(req.GetFrom()),
+		To:      optionalTime(req.GetTo()),
+		Summary: req.GetSummary(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &loyaltyv1.ListWithdrawalsResponse{NextCursor: page.NextCursor}
+	for _, withdrawal := range page.Withdrawals {
+		response.Withdrawals = append(response.Withdrawals, withdrawalToProto(withdrawal))
+	}
+	if page.Summary != nil {
+		response.Summary = &loyaltyv1.WithdrawalsSummary{Count: page.Summary.Count, Total: page.Summary.Total}
+	}
+	return response, nil
+}
+
+// withdrawalToProto переводит списание в сообщение gRPC.
+func withdrawalToProto(withdrawal services.WithdrawResponse) *loyaltyv1.Withdrawal {
+	message := &loyaltyv1.Withdrawal{
+		Order:       withdrawal.Order,
+		Sum:         withdrawal.Sum,
+		ProcessedAt: timestamppb.New(withdrawal.ProcessedAt),
+		Refunded:    withdrawal.Refunded,
+	}
+	for _, reversal := range withdrawal.Reversals {
+		message.Reversals = append(message.Reversals, &loyaltyv1.Reversal{
+			Id:        reversal.ID,
+			Order:     reversal.Order,
+			Amount:    reversal.Amount,
+			Reason:    reversal.Reason,
+			Source:    reversal.Source,
+			CreatedAt: timestamppb.New(reversal.CreatedAt),
+		})
+	}
+	return message
+}
+
+// optionalTime переводит необязательную метку времени в *time.Time.
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// clientIP возвращает IP-адрес клиента из информации о соединении.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// userAgent возвращает User-Agent клиента из метаданных вызова.
+func userAgent(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для административных операций. Все обработчики доступны только
+// пользователям с ролью администратора, а каждое изменение записывается в журнал с идентификатором администратора.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// AdminGetUser возвращает сводную информацию о пользователе: баланс, заказы, списания и корректировки.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminGetUser(c *gin.Context) {
+	overview, err := services.GetUserOverview(c.Request.Context(), c.Param("login"))
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
+
+// AdminAdjustBalance начисляет или списывает баллы пользователя вручную с обязательным кодом причины.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminAdjustBalance(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var request services.AdjustmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	adjustment, err := services.AdjustBalance(c.Request.Context(), adminID, c.Param("login"), request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+// AdminReprocessOrder возвращает заказ в статус NEW, чтобы агент заново запросил по нему начисление.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminReprocessOrder(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := services.ReprocessOrder(c.Request.Context(), adminID, c.Param("number")); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "order scheduled for reprocessing"})
+}
+
+// AdminInvalidateOrder переводит заказ в статус INVALID.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminInvalidateOrder(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := services.InvalidateOrder(c.Request.Context(), adminID, c.Param("number")); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "order marked as invalid"})
+}
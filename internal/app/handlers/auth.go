@@ -3,20 +3,22 @@
 package handlers
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/FollowLille/loyalty/internal/app/problem"
 	"github.com/FollowLille/loyalty/internal/config"
-	cstmerr "github.com/FollowLille/loyalty/internal/errors"
 	"github.com/FollowLille/loyalty/internal/services"
 )
 
 // Register обрабатывает POST-запрос на регистрацию нового пользователя.
 // Если пользователь существует, возвращает ошибку cstmerr.ErrorUserAlreadyExists.
+// Если пароль не удовлетворяет требованиям сложности, возвращает 400.
 // Если пользователь не существует, создает нового пользователя и возвращает сообщение "Successful registration".
+// Необязательный referral_code регистрирует пользователя как приглашенного владельцем кода.
+// Ошибки возвращаются в формате application/problem+json.
 //
 // Параметры:
 //   - c: контекст запроса.
@@ -24,26 +26,21 @@ func Register(c *gin.Context) {
 	var user struct {
 		Username string `json:"login" binding:"required"`
 		Password string `json:"password" binding:"required"`
+		Referral string `json:"referral_code"`
 	}
 
 	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.RespondInvalidRequest(c, err.Error())
 		return
 	}
 
-	token, err := services.RegisterUser(user.Username, user.Password)
+	token, err := services.RegisterUser(c.Request.Context(), user.Username, user.Password, user.Referral, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		if errors.Is(err, cstmerr.ErrorUserAlreadyExists) {
-			config.Logger.Warn("User already exists", zap.String("user", user.Username))
-			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
-			return
-		}
-		config.Logger.Error("Failed to register user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		problem.Respond(c, err)
 		return
 	}
 
-	config.Logger.Info("User registered", zap.String("user", user.Username))
+	config.LoggerFromContext(c.Request.Context()).Info("User registered", zap.String("user", user.Username))
 
 	c.Header("Authorization", "Bearer "+token)
 	c.JSON(http.StatusOK, gin.H{"message": "Successful registration"})
@@ -51,6 +48,9 @@ func Register(c *gin.Context) {
 
 // Login обрабатывает POST-запрос на вход в систему лояльности.
 // Если пользователь существует, создает новый токен и возвращает его в качестве ответа.
+// Если логин заблокирован после серии неудачных попыток, возвращает 423,
+// если попытки идут слишком часто, возвращает 429. В обоих случаях выставляется заголовок Retry-After.
+// Ошибки возвращаются в формате application/problem+json.
 //
 // Параметры:
 //   - c: контекст запроса.
@@ -60,19 +60,12 @@ func Login(c *gin.Context) {
 		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&loginData); err != nil {
-		config.Logger.Error("Failed to bind JSON", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.RespondInvalidRequest(c, err.Error())
 		return
 	}
-	token, err := services.LoginUser(loginData.Username, loginData.Password)
+	token, err := services.LoginUser(c.Request.Context(), loginData.Username, loginData.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		if errors.Is(err, cstmerr.ErrorUserDoesNotExist) || err.Error() == "invalid password" {
-			config.Logger.Error("User does not exist", zap.String("user", loginData.Username))
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
-		} else {
-			config.Logger.Error("Failed to process login", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		}
+		problem.Respond(c, err)
 		return
 	}
 
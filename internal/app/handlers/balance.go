@@ -3,14 +3,14 @@
 package handlers
 
 import (
-	"github.com/FollowLille/loyalty/internal/services"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/FollowLille/loyalty/internal/app/problem"
 	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/services"
 )
 
 // GetUserBalance возвращает информацию о балансе пользователя
@@ -18,34 +18,80 @@ import (
 // Параметры:
 //   - c: контекст HTTP-запроса.
 func GetBalance(c *gin.Context) {
-	userID, ok := c.Get("user_id")
+	userIDInt, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	userBalance, err := services.FetchUserBalance(c.Request.Context(), userIDInt)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	config.LoggerFromContext(c.Request.Context()).Info("Fetched user balance", zap.Float64("current_balance", userBalance.Current), zap.Float64("total_withdrawn", userBalance.Withdrawn))
+	c.JSON(http.StatusOK, userBalance)
+}
+
+// TransferPoints переводит баллы пользователя другому пользователю по логину.
+// Возвращает выполненный перевод.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func TransferPoints(c *gin.Context) {
+	userIDInt, ok := userIDFromContext(c)
 	if !ok {
-		config.Logger.Error("Failed to get user ID")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	userIDStr, ok := userID.(string)
+	var request services.TransferRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	transfer, err := services.TransferPoints(c.Request.Context(), userIDInt, request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// GetTier возвращает текущий уровень пользователя в программе лояльности и прогресс до следующего уровня.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func GetTier(c *gin.Context) {
+	userIDInt, ok := userIDFromContext(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "user_id is not a string"})
 		return
 	}
 
-	userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
+	tier, err := services.FetchUserTier(c.Request.Context(), userIDInt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user_id format"})
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tier)
+}
+
+// GetReferrals возвращает реферальный код пользователя, приглашенных им пользователей и начисленные за них бонусы.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func GetReferrals(c *gin.Context) {
+	userIDInt, ok := userIDFromContext(c)
+	if !ok {
 		return
 	}
-	userBalance, err := services.FetchUserBalance(userIDInt)
+
+	referrals, err := services.GetReferrals(c.Request.Context(), userIDInt)
 	if err != nil {
-		config.Logger.Error("Failed to fetch user balance", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user balance"})
+		problem.Respond(c, err)
 		return
 	}
 
-	config.Logger.Info("Fetched user balance", zap.Float64("current_balance", userBalance.Current), zap.Float64("total_withdrawn", userBalance.Withdrawn))
-	c.JSON(http.StatusOK, services.UserBalance{
-		Current:   userBalance.Current,
-		Withdrawn: userBalance.Withdrawn,
-	})
+	c.JSON(http.StatusOK, referrals)
 }
@@ -0,0 +1,153 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции администратора для управления маркетинговыми кампаниями и активацию промокодов пользователем
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// RedeemPromo активирует промокод и начисляет пользователю бонус кампании.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func RedeemPromo(c *gin.Context) {
+	userIDInt, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var request services.PromoRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	redemption, err := services.RedeemPromoCode(c.Request.Context(), userIDInt, request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, redemption)
+}
+
+// AdminCreateCampaign создает маркетинговую кампанию: промокод с фиксированным бонусом
+// или множитель начислений в период действия.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminCreateCampaign(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var request services.CampaignRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	campaign, err := services.CreateCampaign(c.Request.Context(), adminID, request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// AdminListCampaigns возвращает список кампаний вместе с израсходованным бюджетом.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminListCampaigns(c *gin.Context) {
+	campaigns, err := services.ListCampaigns(c.Request.Context())
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+// AdminGetCampaign возвращает кампанию по идентификатору.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminGetCampaign(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.RespondInvalidRequest(c, "invalid campaign id")
+		return
+	}
+
+	campaign, err := services.GetCampaign(c.Request.Context(), campaignID)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// AdminUpdateCampaign изменяет параметры кампании. Тип кампании изменить нельзя.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminUpdateCampaign(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.RespondInvalidRequest(c, "invalid campaign id")
+		return
+	}
+
+	var request services.CampaignRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	campaign, err := services.UpdateCampaign(c.Request.Context(), adminID, campaignID, request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// AdminArchiveCampaign завершает кампанию. Уже начисленные бонусы сохраняются.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminArchiveCampaign(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.RespondInvalidRequest(c, "invalid campaign id")
+		return
+	}
+
+	if err := services.ArchiveCampaign(c.Request.Context(), adminID, campaignID); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "campaign archived"})
+}
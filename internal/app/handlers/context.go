@@ -0,0 +1,42 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// userIDFromContext извлекает идентификатор пользователя, сохраненный AuthMiddleware.
+// Если идентификатор получить не удалось, сам отвечает клиенту ошибкой и возвращает false.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//
+// Возвращает:
+//   - int64: идентификатор пользователя.
+//   - bool: true, если идентификатор получен.
+func userIDFromContext(c *gin.Context) (int64, bool) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		problem.Respond(c, cstmerr.ErrorUnauthorized)
+		return 0, false
+	}
+
+	userIDStr, ok := userID.(string)
+	if !ok {
+		problem.Respond(c, fmt.Errorf("user_id is not a string: %T", userID))
+		return 0, false
+	}
+
+	userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		problem.Respond(c, fmt.Errorf("invalid user_id format: %w", err))
+		return 0, false
+	}
+	return userIDInt, true
+}
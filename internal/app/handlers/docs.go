@@ -0,0 +1,45 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для выдачи документации API
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/openapi"
+)
+
+// swaggerUIPage - страница Swagger UI, которая загружает спецификацию с /openapi.json.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Gophermart loyalty API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// OpenAPISpec отдает спецификацию OpenAPI пользовательского API.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func OpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openapi.Spec)
+}
+
+// SwaggerUI отдает страницу Swagger UI для просмотра спецификации.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
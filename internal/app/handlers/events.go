@@ -0,0 +1,121 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для потоковой выдачи событий пользователя
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// OrderEvents отдает поток server-sent events об изменениях заказов и баланса пользователя.
+// Клиент может возобновить поток, передав идентификатор последнего события в заголовке Last-Event-ID
+// или в параметре запроса last_event_id. Пока событий нет, в поток периодически пишется heartbeat.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func OrderEvents(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	lastEventID, err := parseLastEventID(c)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	subscription, missed, err := services.SubscribeUserEvents(c.Request.Context(), userID, lastEventID)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+	defer subscription.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range missed {
+		if err = writeEvent(c.Writer, event); err != nil {
+			return
+		}
+		lastEventID = event.ID
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(config.EventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			if event.ID <= lastEventID {
+				continue
+			}
+			if err = writeEvent(c.Writer, event); err != nil {
+				return
+			}
+			lastEventID = event.ID
+		case <-heartbeat.C:
+			if _, err = io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// parseLastEventID разбирает идентификатор последнего полученного события.
+// Если идентификатор не передан, возвращает 0.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//
+// Возвращает:
+//   - int64: идентификатор события.
+//   - error: ошибка, если идентификатор некорректен.
+func parseLastEventID(c *gin.Context) (int64, error) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("%w: Last-Event-ID must be a non-negative integer", cstmerr.ErrorInvalidRequest)
+	}
+	return id, nil
+}
+
+// writeEvent записывает событие в поток в формате server-sent events.
+//
+// Параметры:
+//   - w: поток ответа.
+//   - event: событие.
+//
+// Возвращает:
+//   - error: ошибка записи.
+func writeEvent(w io.Writer, event services.UserEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload)
+	return err
+}
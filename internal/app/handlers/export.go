@@ -0,0 +1,360 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для выгрузки истории пользователя в форматах CSV и JSON Lines
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// Форматы выгрузки, выбираемые по заголовку Accept.
+const (
+	MIMECSV    = "text/csv"
+	MIMENDJSON = "application/x-ndjson"
+)
+
+// exportFlushEvery - число строк, после которого выгрузка отправляется клиенту.
+const exportFlushEvery = 100
+
+// exportFormat выбирает формат ответа по заголовку Accept.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//
+// Возвращает:
+//   - string: MIMECSV или MIMENDJSON, пустая строка для обычного ответа JSON.
+func exportFormat(c *gin.Context) string {
+	switch c.NegotiateFormat(gin.MIMEJSON, MIMECSV, MIMENDJSON) {
+	case MIMECSV:
+		return MIMECSV
+	case MIMENDJSON:
+		return MIMENDJSON
+	}
+	return ""
+}
+
+// exportWriter построчно пишет выгрузку в ответ. Заголовки ответа отправляются при первой записи,
+// поэтому ошибку, возникшую до нее, еще можно вернуть клиенту обычным ответом.
+// Если filename не пуст, ответ отдается как вложение с этим именем файла.
+type exportWriter struct {
+	c        *gin.Context
+	format   string
+	filename string
+	header   []string
+	buf      *bufio.Writer
+	csv      *csv.Writer
+	started  bool
+	rows     int
+}
+
+// newExportWriter создает выгрузку в формате format.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - format: MIMECSV или MIMENDJSON.
+//   - name: имя файла без расширения для заголовка Content-Disposition.
+//   - header: заголовок таблицы CSV.
+func newExportWriter(c *gin.Context, format, name string, header []string) *exportWriter {
+	w := &exportWriter{c: c, format: format, header: header, filename: name + ".ndjson"}
+	if format == MIMECSV {
+		w.filename = name + ".csv"
+	}
+	return w
+}
+
+// start отправляет заголовки ответа и заголовок таблицы CSV.
+func (w *exportWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	w.c.Header("Content-Type", w.format+"; charset=utf-8")
+	if w.filename != "" {
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+	}
+	w.c.Header("X-Content-Type-Options", "nosniff")
+	w.c.Status(http.StatusOK)
+
+	w.buf = bufio.NewWriter(w.c.Writer)
+	if w.format == MIMECSV {
+		w.csv = csv.NewWriter(w.buf)
+		return w.csv.Write(w.header)
+	}
+	return nil
+}
+
+// write пишет строку выгрузки: record для CSV или value для JSON Lines.
+func (w *exportWriter) write(record []string, value interface{}) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	if w.format == MIMECSV {
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if _, err = w.buf.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+
+	w.rows++
+	if w.rows%exportFlushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush отправляет накопленные строки клиенту.
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+// finish завершает выгрузку. Пустая выгрузка состоит только из заголовка таблицы.
+//
+// Параметры:
+//   - err: ошибка чтения выгрузки.
+func (w *exportWriter) finish(err error) {
+	if err == nil {
+		if err = w.start(); err == nil {
+			err = w.flush()
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	if !w.started {
+		problem.Respond(w.c, err)
+		return
+	}
+	// Заголовки уже отправлены, и сообщить об ошибке можно только оборвав выгрузку.
+	// В выписке признаком неполного ответа служит отсутствие итоговой строки
+	config.LoggerFromContext(w.c.Request.Context()).Error("Failed to stream export", zap.Error(err), zap.String("format", w.format))
+	w.c.Abort()
+}
+
+// exportOrders выгружает заказы пользователя в формате CSV или JSON Lines.
+func exportOrders(c *gin.Context, userID int64, query services.OrdersQuery, format string) {
+	w := newExportWriter(c, format, "orders", []string{"number", "status", "accrual", "uploaded_at"})
+	err := services.StreamOrders(c.Request.Context(), userID, query, func(order services.Order) error {
+		return w.write([]string{
+			csvText(order.Number),
+			csvText(order.Status),
+			formatPoints(order.Accrual),
+			order.UploadedAt.Format(time.RFC3339),
+		}, order)
+	})
+	w.finish(err)
+}
+
+// withdrawalsExportColumns - столбцы выгрузки списаний в CSV.
+var withdrawalsExportColumns = []string{"order", "sum", "refunded", "processed_at"}
+
+// exportWithdrawals выгружает списания пользователя в формате CSV или JSON Lines.
+func exportWithdrawals(c *gin.Context, userID int64, query services.WithdrawalsQuery, format string) {
+	w := newExportWriter(c, format, "withdrawals", withdrawalsExportColumns)
+	err := services.StreamWithdrawals(c.Request.Context(), userID, query, func(withdrawal services.WithdrawResponse) error {
+		return writeWithdrawal(w, withdrawal)
+	})
+	w.finish(err)
+}
+
+// writeWithdrawal пишет строку выгрузки списания вместе с суммой возвратов по нему.
+func writeWithdrawal(w *exportWriter, withdrawal services.WithdrawResponse) error {
+	return w.write([]string{
+		csvText(withdrawal.Order),
+		formatPoints(withdrawal.Sum),
+		formatPoints(withdrawal.Refunded),
+		withdrawal.ProcessedAt.Format(time.RFC3339),
+	}, withdrawal)
+}
+
+// GetStatement возвращает выписку по счету пользователя за период from - to (RFC3339):
+// баланс на начало периода, все операции с балансом после каждой из них и итоги.
+// Формат ответа выбирается по заголовку Accept: JSON (по умолчанию), CSV или JSON Lines.
+// Выписка передается клиенту по мере чтения из базы данных.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func GetStatement(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var query services.StatementQuery
+	var err error
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	format := exportFormat(c)
+	var w services.StatementWriter
+	var export *exportWriter
+	if format == "" {
+		sw := &statementJSONWriter{exportWriter: exportWriter{c: c, format: gin.MIMEJSON}}
+		w, export = sw, &sw.exportWriter
+	} else {
+		sw := &statementRowsWriter{exportWriter: *newExportWriter(c, format, "statement",
+			[]string{"type", "order", "amount", "balance", "created_at", "counterparty", "campaign"})}
+		w, export = sw, &sw.exportWriter
+	}
+
+	err = services.StreamStatement(c.Request.Context(), userID, query, w)
+	export.finish(err)
+}
+
+// statementRowsWriter выводит выписку в CSV или JSON Lines: первая строка - баланс на начало периода,
+// затем операции, последняя строка - итоги и баланс на конец периода.
+type statementRowsWriter struct {
+	exportWriter
+	query services.StatementQuery
+}
+
+// Opening пишет строку с балансом на начало периода.
+func (w *statementRowsWriter) Opening(query services.StatementQuery, balance float64) error {
+	w.query = query
+	return w.write(
+		[]string{"opening", "", "", formatPoints(balance), formatOptionalTime(query.From), "", ""},
+		gin.H{"type": "opening", "balance": balance, "created_at": query.From},
+	)
+}
+
+// Entry пишет строку операции.
+func (w *statementRowsWriter) Entry(entry services.StatementEntry) error {
+	return w.write(
+		[]string{csvText(entry.Type), csvText(entry.Order), formatPoints(entry.Amount), formatPoints(entry.Balance), entry.CreatedAt.Format(time.RFC3339),
+			csvText(entry.Counterparty), csvText(entry.Campaign)},
+		entry,
+	)
+}
+
+// Closing пишет строку с итогами и балансом на конец периода.
+func (w *statementRowsWriter) Closing(totals services.StatementTotals) error {
+	net := totals.Closing - totals.Opening
+	return w.write(
+		[]string{"closing", "", formatPoints(net), formatPoints(totals.Closing), formatOptionalTime(w.query.To), "", ""},
+		struct {
+			Type string `json:"type"`
+			services.StatementTotals
+			CreatedAt *time.Time `json:"created_at"`
+		}{Type: "closing", StatementTotals: totals, CreatedAt: w.query.To},
+	)
+}
+
+// statementJSONWriter выводит выписку одним объектом JSON, массив операций пишется по мере чтения.
+type statementJSONWriter struct {
+	exportWriter
+}
+
+// Opening пишет начало объекта и баланс на начало периода.
+func (w *statementJSONWriter) Opening(query services.StatementQuery, balance float64) error {
+	head, err := json.Marshal(struct {
+		From    *time.Time `json:"from,omitempty"`
+		To      *time.Time `json:"to,omitempty"`
+		Opening float64    `json:"opening_balance"`
+	}{From: query.From, To: query.To, Opening: balance})
+	if err != nil {
+		return err
+	}
+	if err = w.start(); err != nil {
+		return err
+	}
+	// Открытый объект продолжается массивом операций
+	_, err = w.buf.Write(append(head[:len(head)-1], `,"entries":[`...))
+	return err
+}
+
+// Entry пишет операцию в массив операций.
+func (w *statementJSONWriter) Entry(entry services.StatementEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if w.rows > 0 {
+		if err = w.buf.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	if _, err = w.buf.Write(data); err != nil {
+		return err
+	}
+	w.rows++
+	if w.rows%exportFlushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// Closing закрывает массив операций и пишет итоги.
+func (w *statementJSONWriter) Closing(totals services.StatementTotals) error {
+	data, err := json.Marshal(totals)
+	if err != nil {
+		return err
+	}
+	if _, err = w.buf.WriteString(`],"totals":`); err != nil {
+		return err
+	}
+	if _, err = w.buf.Write(data); err != nil {
+		return err
+	}
+	return w.buf.WriteByte('}')
+}
+
+// csvText экранирует текстовую ячейку CSV, чтобы табличный редактор не выполнил ее как формулу:
+// к значению, начинающемуся с =, +, -, @, табуляции или возврата каретки, добавляется апостроф.
+// Числовые ячейки, например отрицательные суммы, передаются без экранирования.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// formatPoints форматирует сумму баллов для CSV без лишних нулей.
+func formatPoints(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// formatOptionalTime форматирует необязательное время для CSV, nil - пустая строка.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+func newExportContext(accept string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/user/statement", nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	return c, recorder
+}
+
+// writeStatement выводит выписку из двух операций через w.
+func writeStatement(t *testing.T, w services.StatementWriter) {
+	t.Helper()
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, w.Opening(services.StatementQuery{From: &from, To: &to}, 100))
+	require.NoError(t, w.Entry(services.StatementEntry{Type: "accrual", Order: "12345678903", Amount: 50.5, Balance: 150.5, CreatedAt: from.Add(time.Hour)}))
+	require.NoError(t, w.Entry(services.StatementEntry{Type: "withdrawal", Order: "2377225624", Amount: -20, Balance: 130.5, CreatedAt: from.Add(2 * time.Hour)}))
+	require.NoError(t, w.Closing(services.StatementTotals{Opening: 100, Credits: 50.5, Debits: 20, Closing: 130.5, Count: 2}))
+}
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: ""},
+		{accept: "application/json", want: ""},
+		{accept: "*/*", want: ""},
+		{accept: "text/csv", want: MIMECSV},
+		{accept: "application/x-ndjson", want: MIMENDJSON},
+		{accept: "text/html, text/csv;q=0.9", want: MIMECSV},
+	}
+	for _, tt := range tests {
+		c, _ := newExportContext(tt.accept)
+		assert.Equal(t, tt.want, exportFormat(c), "Accept: %q", tt.accept)
+	}
+}
+
+func TestStatementRowsWriter_CSV(t *testing.T) {
+	c, recorder := newExportContext(MIMECSV)
+	w := &statementRowsWriter{exportWriter: *newExportWriter(c, MIMECSV, "statement",
+		[]string{"type", "order", "amount", "balance", "created_at", "counterparty", "campaign"})}
+
+	writeStatement(t, w)
+	w.finish(nil)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Header().Get("Content-Disposition"), `filename="statement.csv"`)
+	assert.Equal(t, strings.Join([]string{
+		"type,order,amount,balance,created_at,counterparty,campaign",
+		"opening,,,100,2024-01-01T00:00:00Z,,",
+		"accrual,12345678903,50.5,150.5,2024-01-01T01:00:00Z,,",
+		"withdrawal,2377225624,-20,130.5,2024-01-01T02:00:00Z,,",
+		"closing,,30.5,130.5,2024-02-01T00:00:00Z,,",
+		"",
+	}, "\n"), recorder.Body.String())
+}
+
+func TestStatementRowsWriter_CSVEscapesFormulas(t *testing.T) {
+	c, recorder := newExportContext(MIMECSV)
+	w := &statementRowsWriter{exportWriter: *newExportWriter(c, MIMECSV, "statement",
+		[]string{"type", "order", "amount", "balance", "created_at", "counterparty", "campaign"})}
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.Entry(services.StatementEntry{Type: "transfer_in", Amount: 10, Balance: 10, CreatedAt: createdAt,
+		Counterparty: "=HYPERLINK(\"http://evil\")", Campaign: "@SUM(A1)"}))
+	require.NoError(t, w.Entry(services.StatementEntry{Type: "transfer_out", Amount: -10, Balance: 0, CreatedAt: createdAt,
+		Counterparty: "+1", Campaign: "-cmd"}))
+	w.finish(nil)
+
+	assert.Equal(t, strings.Join([]string{
+		"type,order,amount,balance,created_at,counterparty,campaign",
+		`transfer_in,,10,10,2024-01-01T00:00:00Z,"'=HYPERLINK(""http://evil"")",'@SUM(A1)`,
+		"transfer_out,,-10,0,2024-01-01T00:00:00Z,'+1,'-cmd",
+		"",
+	}, "\n"), recorder.Body.String())
+}
+
+func TestWriteWithdrawal_CSV(t *testing.T) {
+	c, recorder := newExportContext(MIMECSV)
+	w := newExportWriter(c, MIMECSV, "withdrawals", withdrawalsExportColumns)
+
+	processedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, writeWithdrawal(w, services.WithdrawResponse{Order: "2377225624", Sum: 500, Refunded: 120.5, ProcessedAt: processedAt}))
+	require.NoError(t, writeWithdrawal(w, services.WithdrawResponse{Order: "12345678903", Sum: 20, ProcessedAt: processedAt.Add(time.Hour)}))
+	w.finish(nil)
+
+	assert.Equal(t, strings.Join([]string{
+		"order,sum,refunded,processed_at",
+		"2377225624,500,120.5,2024-01-01T00:00:00Z",
+		"12345678903,20,0,2024-01-01T01:00:00Z",
+		"",
+	}, "\n"), recorder.Body.String())
+}
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "accrual", want: "accrual"},
+		{value: "=1+1", want: "'=1+1"},
+		{value: "+1", want: "'+1"},
+		{value: "-1", want: "'-1"},
+		{value: "@cmd", want: "'@cmd"},
+		{value: "\tcmd", want: "'\tcmd"},
+		{value: "\rcmd", want: "'\rcmd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, csvText(tt.value))
+		})
+	}
+}
+
+func TestStatementRowsWriter_NDJSON(t *testing.T) {
+	c, recorder := newExportContext(MIMENDJSON)
+	w := &statementRowsWriter{exportWriter: *newExportWriter(c, MIMENDJSON, "statement", nil)}
+
+	writeStatement(t, w)
+	w.finish(nil)
+
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	require.Len(t, lines, 4)
+	var types []string
+	for _, line := range lines {
+		var row map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &row))
+		types = append(types, row["type"].(string))
+	}
+	assert.Equal(t, []string{"opening", "accrual", "withdrawal", "closing"}, types)
+	assert.Contains(t, lines[3], `"closing_balance":130.5`)
+}
+
+func TestStatementJSONWriter(t *testing.T) {
+	c, recorder := newExportContext("")
+	w := &statementJSONWriter{exportWriter: exportWriter{c: c, format: gin.MIMEJSON}}
+
+	writeStatement(t, w)
+	w.finish(nil)
+
+	assert.Empty(t, recorder.Header().Get("Content-Disposition"))
+	var statement struct {
+		From    time.Time                 `json:"from"`
+		Opening float64                   `json:"opening_balance"`
+		Entries []services.StatementEntry `json:"entries"`
+		Totals  services.StatementTotals  `json:"totals"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &statement), recorder.Body.String())
+	assert.Equal(t, 100.0, statement.Opening)
+	assert.Len(t, statement.Entries, 2)
+	assert.Equal(t, 130.5, statement.Totals.Closing)
+}
+
+func TestExportWriter_ErrorBeforeFirstRow(t *testing.T) {
+	c, recorder := newExportContext(MIMECSV)
+	w := newExportWriter(c, MIMECSV, "orders", []string{"number"})
+
+	w.finish(cstmerr.ErrorInvalidFilter)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, problem.ContentType, recorder.Header().Get("Content-Type"))
+}
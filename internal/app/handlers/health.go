@@ -0,0 +1,33 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции проверки жизнеспособности и готовности сервиса
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/health"
+)
+
+// Healthz сообщает, что процесс запущен и обрабатывает запросы. Зависимости не проверяются.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz проверяет готовность сервиса к приему запросов и возвращает результат по каждой зависимости.
+// Если хотя бы одна проверка не пройдена или сервис завершает работу, возвращается 503.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func Readyz(c *gin.Context) {
+	report := health.Default.Check(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
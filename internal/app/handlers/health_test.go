@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/health"
+)
+
+func TestHealthz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	Healthz(c)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := health.Default
+	t.Cleanup(func() { health.Default = previous })
+
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "ready", wantCode: http.StatusOK, wantStatus: health.StatusReady},
+		{name: "not_ready", checkErr: errors.New("connection refused"), wantCode: http.StatusServiceUnavailable, wantStatus: health.StatusNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health.Default = health.NewChecker()
+			health.Default.Register("database", func(context.Context) error { return tt.checkErr })
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			Readyz(c)
+
+			assert.Equal(t, tt.wantCode, recorder.Code)
+			var report health.Report
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Contains(t, report.Checks, "database")
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для запросов мерчантов, аутентифицированных по API-ключу,
+// и функции администратора для управления API-ключами
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// MerchantUploadOrder привязывает номер заказа к пользователю, указанному по логину.
+// Коды ответов совпадают с загрузкой заказа самим пользователем, плюс 404, если пользователь не найден.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func MerchantUploadOrder(c *gin.Context) {
+	var request services.MerchantOrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Login == "" || request.Order == "" {
+		problem.RespondInvalidRequest(c, "login and order are required")
+		return
+	}
+
+	if err := services.UploadMerchantOrder(c.Request.Context(), request); err != nil {
+		respondUploadOrderError(c, err)
+		return
+	}
+
+	config.LoggerFromContext(c.Request.Context()).Info("Order uploaded by merchant",
+		zap.String("merchant", c.GetString("merchant_name")),
+		zap.String("user", request.Login),
+		zap.String("order_number", request.Order))
+	c.JSON(http.StatusAccepted, gin.H{"message": "order accepted for processing"})
+}
+
+// AdminCreateMerchantKey выпускает новый API-ключ мерчанта. Ключ возвращается в ответе только один раз.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminCreateMerchantKey(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var request services.MerchantKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	key, err := services.CreateMerchantKey(c.Request.Context(), adminID, request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// AdminListMerchantKeys возвращает список API-ключей мерчантов без их открытых значений.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminListMerchantKeys(c *gin.Context) {
+	keys, err := services.ListMerchantKeys(c.Request.Context())
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// AdminRevokeMerchantKey отзывает API-ключ мерчанта.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminRevokeMerchantKey(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.RespondInvalidRequest(c, "invalid key id")
+		return
+	}
+
+	if err := services.RevokeMerchantKey(c.Request.Context(), adminID, keyID); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "merchant key revoked"})
+}
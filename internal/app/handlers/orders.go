@@ -3,102 +3,128 @@
 package handlers
 
 import (
-	"github.com/FollowLille/loyalty/internal/services"
-	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
+	"errors"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
 	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/services"
 )
 
-// GetOrders возвращает информацию о заказах пользователя
+// GetOrders возвращает страницу списка заказов пользователя.
+// Поддерживает параметры запроса limit, cursor, status (через запятую), from и to (RFC3339) и sort (asc или desc).
+// Курсор следующей страницы возвращается в заголовке X-Next-Cursor и в заголовке Link с rel="next".
+// Если клиент запрашивает text/csv или application/x-ndjson, выгружаются все заказы по фильтрам без разбиения на страницы.
 //
 // Параметры:
 //   - c: контекст HTTP-запроса.
 func GetOrders(c *gin.Context) {
-	userID, ok := c.Get("user_id")
+	userIDInt, ok := userIDFromContext(c)
 	if !ok {
-		config.Logger.Error("Failed to get user ID")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	userIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "user_id is not a string"})
+	query, err := parseOrdersQuery(c)
+	if err != nil {
+		problem.Respond(c, err)
 		return
 	}
-
-	userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user_id format"})
+	if format := exportFormat(c); format != "" {
+		exportOrders(c, userIDInt, query, format)
 		return
 	}
 
-	orders, err := services.GetOrders(userIDInt)
+	page, err := services.GetOrders(c.Request.Context(), userIDInt, query)
 	if err != nil {
-		config.Logger.Error("Failed to get orders", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
+		problem.Respond(c, err)
 		return
 	}
-	if orders == nil {
-		config.Logger.Info("No orders found")
+	if len(page.Orders) == 0 {
+		config.LoggerFromContext(c.Request.Context()).Info("No orders found")
 		c.JSON(http.StatusNoContent, nil)
 		return
 	}
 
-	c.JSON(http.StatusOK, orders)
+	setNextPageHeaders(c, page.NextCursor)
+	c.JSON(http.StatusOK, page.Orders)
 }
 
-// UploadOrder обрабатывает информацию о заказе пользователя
+// parseOrdersQuery разбирает параметры запроса списка заказов.
 //
 // Параметры:
 //   - c: контекст HTTP-запроса.
-func UploadOrder(c *gin.Context) {
-	userID, ok := c.Get("user_id")
-	if !ok {
-		config.Logger.Error("Failed to get user ID")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
+//
+// Возвращает:
+//   - services.OrdersQuery: параметры запроса.
+//   - error: ошибка, если параметры некорректны.
+func parseOrdersQuery(c *gin.Context) (services.OrdersQuery, error) {
+	var query services.OrdersQuery
+	var err error
 
-	userIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "user_id is not a string"})
-		return
+	if query.Limit, err = parseLimit(c); err != nil {
+		return services.OrdersQuery{}, err
+	}
+	query.Cursor = c.Query("cursor")
+	if query.Ascending, err = parseSort(c); err != nil {
+		return services.OrdersQuery{}, err
+	}
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		return services.OrdersQuery{}, err
 	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		return services.OrdersQuery{}, err
+	}
+	if status := c.Query("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+				query.Statuses = append(query.Statuses, s)
+			}
+		}
+	}
+	return query, nil
+}
 
-	userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user_id format"})
+// UploadOrder обрабатывает информацию о заказе пользователя
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func UploadOrder(c *gin.Context) {
+	userIDInt, ok := userIDFromContext(c)
+	if !ok {
 		return
 	}
 
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil || len(body) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		problem.RespondInvalidRequest(c, "order number is required")
 		return
 	}
 	orderNumber := strings.TrimSpace(string(body))
 
-	if err := services.UploadOrder(userIDInt, orderNumber); err != nil {
-		config.Logger.Error("Failed to upload order", zap.Error(err))
-		switch err.Error() {
-		case "invalid order number":
-			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid order number"})
-		case "order already uploaded by you":
-			c.JSON(http.StatusOK, gin.H{"error": "order already uploaded by you"})
-		case "order already uploaded by another user":
-			c.JSON(http.StatusConflict, gin.H{"error": "order already uploaded by another user"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upload order"})
-		}
+	if err := services.UploadOrder(c.Request.Context(), userIDInt, orderNumber); err != nil {
+		respondUploadOrderError(c, err)
 		return
 	}
 
-	config.Logger.Info("Order created successfully")
+	config.LoggerFromContext(c.Request.Context()).Info("Order created successfully")
 	c.JSON(http.StatusAccepted, gin.H{"message": "order accepted for processing"})
 }
+
+// respondUploadOrderError отвечает клиенту в соответствии с ошибкой загрузки заказа.
+// Повторная загрузка заказа тем же пользователем ошибкой не считается и возвращает 200.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - err: ошибка services.UploadOrder.
+func respondUploadOrderError(c *gin.Context, err error) {
+	if errors.Is(err, cstmerr.ErrorOrderAlreadyUploaded) {
+		c.JSON(http.StatusOK, gin.H{"message": "order already uploaded by you"})
+		return
+	}
+	problem.Respond(c, err)
+}
@@ -0,0 +1,91 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для разбора параметров постраничной выдачи списков
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// parseLimit разбирает параметр запроса limit. Если параметр не указан, возвращает 0.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//
+// Возвращает:
+//   - int: размер страницы.
+//   - error: ошибка, если параметр некорректен.
+func parseLimit(c *gin.Context) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("%w: limit must be a positive integer", cstmerr.ErrorInvalidFilter)
+	}
+	return limit, nil
+}
+
+// parseSort разбирает параметр запроса sort.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//
+// Возвращает:
+//   - bool: true для сортировки от старых к новым.
+//   - error: ошибка, если параметр некорректен.
+func parseSort(c *gin.Context) (bool, error) {
+	switch c.DefaultQuery("sort", "desc") {
+	case "asc":
+		return true, nil
+	case "desc":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: sort must be asc or desc", cstmerr.ErrorInvalidFilter)
+}
+
+// parseTimeParam разбирает параметр запроса в формате RFC3339. Если параметр не указан, возвращает nil.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - name: имя параметра.
+//
+// Возвращает:
+//   - *time.Time: значение параметра.
+//   - error: ошибка, если параметр некорректен.
+func parseTimeParam(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be in RFC3339 format", cstmerr.ErrorInvalidFilter, name)
+	}
+	return &t, nil
+}
+
+// setNextPageHeaders выставляет заголовки X-Next-Cursor и Link со ссылкой на следующую страницу.
+// Остальные параметры запроса сохраняются в ссылке без изменений.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - cursor: курсор следующей страницы, пустая строка если страница последняя.
+func setNextPageHeaders(c *gin.Context, cursor string) {
+	if cursor == "" {
+		return
+	}
+	next := *c.Request.URL
+	params := next.Query()
+	params.Set("cursor", cursor)
+	next.RawQuery = params.Encode()
+
+	c.Header("X-Next-Cursor", cursor)
+	c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+}
@@ -0,0 +1,88 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для смены и сброса пароля пользователя
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// ChangePassword обрабатывает PUT-запрос на смену пароля аутентифицированного пользователя.
+// Требует текущий пароль. После смены все ранее выданные токены становятся недействительными,
+// новый токен возвращается в заголовке Authorization.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func ChangePassword(c *gin.Context) {
+	userIDInt, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	token, err := services.ChangePassword(c.Request.Context(), userIDInt, request.OldPassword, request.NewPassword)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.Header("Authorization", "Bearer "+token)
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// RequestPasswordReset обрабатывает POST-запрос на сброс пароля.
+// Токен сброса отправляется пользователю через механизм уведомлений.
+// Ответ не зависит от того, существует ли пользователь.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func RequestPasswordReset(c *gin.Context) {
+	var request struct {
+		Username string `json:"login" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	if err := services.RequestPasswordReset(c.Request.Context(), request.Username); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the user exists, a reset token has been sent"})
+}
+
+// ConfirmPasswordReset обрабатывает POST-запрос на установку нового пароля по токену сброса.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func ConfirmPasswordReset(c *gin.Context) {
+	var request struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	if err := services.ResetPassword(c.Request.Context(), request.Token, request.NewPassword); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
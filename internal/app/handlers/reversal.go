@@ -0,0 +1,63 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции для возврата списанных баллов администратором и мерчантом
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// AdminReverseWithdrawal возвращает пользователю баллы, списанные по заказу, полностью или частично.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminReverseWithdrawal(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var request services.ReversalRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	reversal, err := services.ReverseWithdrawalByAdmin(c.Request.Context(), adminID, c.Param("order"), request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, reversal)
+}
+
+// MerchantReverseWithdrawal возвращает пользователю баллы, списанные по заказу, при отмене заказа в магазине.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func MerchantReverseWithdrawal(c *gin.Context) {
+	var request services.ReversalRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	merchant := services.Merchant{KeyID: c.GetInt64("merchant_key_id"), Name: c.GetString("merchant_name")}
+	reversal, err := services.ReverseWithdrawalByMerchant(c.Request.Context(), merchant, c.Param("order"), request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	config.LoggerFromContext(c.Request.Context()).Info("Withdrawal reversed by merchant",
+		zap.String("merchant", merchant.Name),
+		zap.String("order_number", reversal.Order),
+		zap.Float64("amount", reversal.Amount))
+	c.JSON(http.StatusCreated, reversal)
+}
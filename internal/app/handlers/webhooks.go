@@ -0,0 +1,134 @@
+// Package handlers предоставляет функции для обработки HTTP-запросов в системе лояльности.
+// Включает в себя функции администратора для управления подписками на вебхуки и просмотра журнала доставок
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// AdminCreateWebhook создает подписку на вебхуки. Секрет подписи возвращается в ответе только один раз.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminCreateWebhook(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var request services.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.RespondInvalidRequest(c, err.Error())
+		return
+	}
+
+	webhook, err := services.CreateWebhook(c.Request.Context(), adminID, request)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// AdminListWebhooks возвращает список подписок на вебхуки без секретов.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminListWebhooks(c *gin.Context) {
+	webhooks, err := services.ListWebhooks(c.Request.Context())
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// AdminRevokeWebhook отзывает подписку на вебхуки.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminRevokeWebhook(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.RespondInvalidRequest(c, "invalid webhook id")
+		return
+	}
+
+	if err := services.RevokeWebhook(c.Request.Context(), adminID, subscriptionID); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook revoked"})
+}
+
+// AdminListWebhookDeliveries возвращает журнал доставок подписки вместе с попытками.
+// Поддерживает параметры status (pending, delivered, dead) и limit.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminListWebhookDeliveries(c *gin.Context) {
+	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.RespondInvalidRequest(c, "invalid webhook id")
+		return
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+	if limit == 0 {
+		limit = config.PageDefaultLimit
+	}
+	if limit > config.PageMaxLimit {
+		limit = config.PageMaxLimit
+	}
+
+	deliveries, err := services.ListWebhookDeliveries(c.Request.Context(), subscriptionID, c.Query("status"), limit)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// AdminRetryWebhookDelivery возвращает доставку в состоянии dead в очередь на отправку.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+func AdminRetryWebhookDelivery(c *gin.Context) {
+	adminID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.RespondInvalidRequest(c, "invalid delivery id")
+		return
+	}
+
+	if err := services.RetryWebhookDelivery(c.Request.Context(), adminID, deliveryID); err != nil {
+		problem.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "delivery scheduled for retry"})
+}
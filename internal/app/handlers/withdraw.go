@@ -2,12 +2,15 @@
 package handlers
 
 import (
-	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
 	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
 	"github.com/FollowLille/loyalty/internal/services"
 )
 
@@ -18,87 +21,96 @@ import (
 // Параметры:
 //   - c: контекст HTTP-запроса.
 func GetWithdrawRequest(c *gin.Context) {
-	userID, ok := c.Get("user_id")
-	if !ok {
-		config.Logger.Error("Failed to get user ID")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-
-	userIDStr, ok := userID.(string)
+	userIDInt, ok := userIDFromContext(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "user_id is not a string"})
-		return
-	}
-
-	userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user_id format"})
 		return
 	}
 
 	var request services.WithdrawRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		config.Logger.Error("Failed to bind JSON", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		problem.RespondInvalidRequest(c, err.Error())
 		return
 	}
 
-	if err := services.ProcessWithdrawRequest(userIDInt, request); err != nil {
-		if err.Error() == "invalid order number" {
-			config.Logger.Error("Failed to process withdraw due to invalid order number")
-			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid order"})
-			return
-		} else if err.Error() == "insufficient balance" {
-			config.Logger.Error("Failed to process withdraw due to insufficient balance")
-			c.JSON(http.StatusPaymentRequired, gin.H{"error": "insufficient balance"})
-			return
-		}
-
-		config.Logger.Error("Failed to process withdraw", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process withdraw"})
+	if err := services.ProcessWithdrawRequest(c.Request.Context(), userIDInt, request); err != nil {
+		problem.Respond(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Withdraw request processed"})
 }
 
-// GetWithdrawals возвращает список выводов баланса пользователя.
+// GetWithdrawals возвращает страницу списка выводов баланса пользователя.
+// Поддерживает параметры запроса limit, cursor, from и to (RFC3339).
+// При summary=true вместо массива возвращает объект со списаниями и итогами за период,
+// иначе курсор следующей страницы передается в заголовках X-Next-Cursor и Link.
+// Если клиент запрашивает text/csv или application/x-ndjson, выгружаются все списания за период без разбиения на страницы.
 //
 // Параметры:
 //   - c: контекст HTTP-запроса.
 func GetWithdrawals(c *gin.Context) {
-	userID, ok := c.Get("user_id")
+	userIDInt, ok := userIDFromContext(c)
 	if !ok {
-		config.Logger.Error("Failed to get user ID")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	userIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "user_id is not a string"})
+	query, err := parseWithdrawalsQuery(c)
+	if err != nil {
+		problem.Respond(c, err)
+		return
+	}
+	if format := exportFormat(c); format != "" {
+		exportWithdrawals(c, userIDInt, query, format)
 		return
 	}
 
-	userIDInt, err := strconv.ParseInt(userIDStr, 10, 64)
+	page, err := services.FetchWithdrawalsPage(c.Request.Context(), userIDInt, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user_id format"})
+		problem.Respond(c, err)
 		return
 	}
 
-	withdrawals, err := services.FetchWithdrawals(userIDInt)
-	if err != nil {
-		config.Logger.Error("Failed to fetch user withdrawals", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user withdrawals"})
+	setNextPageHeaders(c, page.NextCursor)
+	if query.Summary {
+		c.JSON(http.StatusOK, page)
 		return
 	}
 
-	if len(withdrawals) == 0 {
-		config.Logger.Info("No withdrawals found")
+	if len(page.Withdrawals) == 0 {
+		config.LoggerFromContext(c.Request.Context()).Info("No withdrawals found")
 		c.JSON(http.StatusNoContent, gin.H{"message": "No withdrawals found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, withdrawals)
+	c.JSON(http.StatusOK, page.Withdrawals)
+}
+
+// parseWithdrawalsQuery разбирает параметры запроса списка выводов.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//
+// Возвращает:
+//   - services.WithdrawalsQuery: параметры запроса.
+//   - error: ошибка, если параметры некорректны.
+func parseWithdrawalsQuery(c *gin.Context) (services.WithdrawalsQuery, error) {
+	var query services.WithdrawalsQuery
+	var err error
+
+	if query.Limit, err = parseLimit(c); err != nil {
+		return services.WithdrawalsQuery{}, err
+	}
+	query.Cursor = c.Query("cursor")
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		return services.WithdrawalsQuery{}, err
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		return services.WithdrawalsQuery{}, err
+	}
+	if summary := c.Query("summary"); summary != "" {
+		if query.Summary, err = strconv.ParseBool(summary); err != nil {
+			return services.WithdrawalsQuery{}, fmt.Errorf("%w: summary must be a boolean", cstmerr.ErrorInvalidFilter)
+		}
+	}
+	return query, nil
 }
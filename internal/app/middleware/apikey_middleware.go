@@ -0,0 +1,43 @@
+// Package middleware предоставляет функции для обработки запросов на взаимодействие с программой лояльности
+// Включает в себя функции для проверки API-ключей мерчантов
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/services"
+)
+
+// APIKeyMiddleware проверяет API-ключ мерчанта из заголовка X-API-Key
+// и то, что ключу разрешена указанная операция.
+//
+// Параметры:
+//   - scope: требуемая область действия ключа.
+func APIKeyMiddleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-API-Key")
+		if len(apiKey) == 0 {
+			problem.Respond(c, fmt.Errorf("%w: X-API-Key header required", cstmerr.ErrorInvalidAPIKey))
+			return
+		}
+
+		merchant, err := services.AuthenticateMerchant(c.Request.Context(), apiKey)
+		if err != nil {
+			problem.Respond(c, err)
+			return
+		}
+
+		if !merchant.HasScope(scope) {
+			problem.Respond(c, fmt.Errorf("%w: API key has no %s scope", cstmerr.ErrorForbidden, scope))
+			return
+		}
+
+		c.Set("merchant_key_id", merchant.KeyID)
+		c.Set("merchant_name", merchant.Name)
+		c.Next()
+	}
+}
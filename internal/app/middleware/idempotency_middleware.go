@@ -0,0 +1,169 @@
+// Package middleware предоставляет функции для обработки запросов на взаимодействие с программой лояльности
+// Включает в себя функции для обработки ключей идемпотентности
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// IdempotencyKeyHeader - заголовок, в котором клиент передает ключ идемпотентности.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength - максимальная длина ключа идемпотентности.
+const maxIdempotencyKeyLength = 255
+
+// responseRecorder дублирует тело ответа в буфер, чтобы его можно было сохранить для повторной выдачи.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware обеспечивает идемпотентность запроса по заголовку Idempotency-Key.
+// Запрос без заголовка обрабатывается как обычно. Ключ хранится для каждого пользователя отдельно
+// вместе с отпечатком запроса и ответом:
+//   - повтор с тем же ключом и тем же телом получает сохраненный ответ;
+//   - повтор с тем же ключом и другим телом получает 422;
+//   - повтор, пока исходный запрос еще обрабатывается, получает 409.
+//
+// Ответ с кодом 5xx не сохраняется, и запрос с тем же ключом можно повторить.
+// При панике обработчика ключ также освобождается, после чего паника передается дальше.
+// Должен подключаться после AuthMiddleware.
+//
+// Параметры:
+//   - endpoint: название операции, в рамках которой уникален ключ.
+func IdempotencyMiddleware(endpoint string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problem.RespondInvalidRequest(c, "Idempotency-Key is too long")
+			return
+		}
+
+		userID, err := strconv.ParseInt(c.GetString("user_id"), 10, 64)
+		if err != nil {
+			problem.Respond(c, cstmerr.ErrorUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.RespondInvalidRequest(c, "failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Request, body)
+
+		record, claimed, err := database.ClaimIdempotencyKey(c.Request.Context(), userID, endpoint, key, fingerprint, config.IdempotencyKeyTTL)
+		if err != nil {
+			problem.Respond(c, err)
+			return
+		}
+		if !claimed {
+			replayIdempotentResponse(c, record, fingerprint)
+			c.Abort()
+			return
+		}
+
+		// Результат сохраняется, даже если клиент уже отключился, иначе ключ останется занятым до истечения срока
+		ctx := context.WithoutCancel(c.Request.Context())
+		release := func() {
+			if err := database.ReleaseIdempotencyKey(ctx, userID, endpoint, key); err != nil {
+				config.LoggerFromContext(ctx).Error("Failed to release idempotency key", zap.Error(err))
+			}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+		nextReleasingOnPanic(c, release)
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			release()
+			return
+		}
+		err = database.CompleteIdempotencyKey(ctx, userID, endpoint, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		if err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to save idempotent response", zap.Error(err))
+		}
+	}
+}
+
+// nextReleasingOnPanic передает запрос следующим обработчикам. Если обработчик паникует,
+// вызывает release и передает панику дальше, чтобы ее обработал Recovery.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - release: освобождение ключа идемпотентности.
+func nextReleasingOnPanic(c *gin.Context, release func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			release()
+			panic(r)
+		}
+	}()
+	c.Next()
+}
+
+// replayIdempotentResponse отвечает на повторный запрос с уже использованным ключом идемпотентности.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - record: сохраненная запись ключа.
+//   - fingerprint: отпечаток текущего запроса.
+func replayIdempotentResponse(c *gin.Context, record database.IdempotencyRecord, fingerprint string) {
+	switch {
+	case record.RequestHash != fingerprint:
+		problem.Respond(c, cstmerr.ErrorIdempotencyKeyReused)
+	case !record.Completed:
+		problem.Respond(c, cstmerr.ErrorIdempotencyKeyInProgress)
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(record.StatusCode, record.ContentType, record.ResponseBody)
+	}
+}
+
+// requestFingerprint вычисляет отпечаток запроса по методу, пути и телу запроса.
+//
+// Параметры:
+//   - r: HTTP-запрос.
+//   - body: тело запроса.
+//
+// Возвращает:
+//   - string: SHA-256 отпечаток в шестнадцатеричном виде.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
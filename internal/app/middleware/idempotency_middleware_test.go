@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	"github.com/FollowLille/loyalty/internal/database"
+)
+
+func TestIdempotencyMiddleware_WithoutKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/withdraw", IdempotencyMiddleware("withdraw"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/withdraw", strings.NewReader(`{"order":"2377225624","sum":751}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/withdraw", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, strings.Repeat("k", maxIdempotencyKeyLength+1))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", nil)
+	other := httptest.NewRequest(http.MethodPost, "/api/user/orders", nil)
+
+	assert.Equal(t, requestFingerprint(req, []byte("a")), requestFingerprint(req, []byte("a")))
+	assert.NotEqual(t, requestFingerprint(req, []byte("a")), requestFingerprint(req, []byte("b")))
+	assert.NotEqual(t, requestFingerprint(req, []byte("a")), requestFingerprint(other, []byte("a")))
+}
+
+func TestReplayIdempotentResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		record     database.IdempotencyRecord
+		wantStatus int
+		wantBody   string
+		wantReplay bool
+	}{
+		{
+			name:       "completed",
+			record:     database.IdempotencyRecord{RequestHash: "hash", Completed: true, StatusCode: http.StatusOK, ContentType: "application/json", ResponseBody: []byte(`{"message":"ok"}`)},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"message":"ok"}`,
+			wantReplay: true,
+		},
+		{
+			name:       "in_progress",
+			record:     database.IdempotencyRecord{RequestHash: "hash"},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "different_request",
+			record:     database.IdempotencyRecord{RequestHash: "other", Completed: true, StatusCode: http.StatusOK},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/user/orders", nil)
+			replayIdempotentResponse(c, tt.record, "hash")
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+			assert.Equal(t, tt.wantReplay, w.Header().Get("Idempotent-Replayed") == "true")
+			if !tt.wantReplay {
+				assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestNextReleasingOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	released := 0
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.POST("/panic", func(c *gin.Context) {
+		nextReleasingOnPanic(c, func() { released++ })
+	}, func(c *gin.Context) {
+		panic("handler failed")
+	})
+	router.POST("/ok", func(c *gin.Context) {
+		nextReleasingOnPanic(c, func() { released++ })
+	}, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ok", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, released)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, released)
+}
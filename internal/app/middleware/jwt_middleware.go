@@ -3,14 +3,15 @@
 package middleware
 
 import (
-	"net/http"
+	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	"github.com/FollowLille/loyalty/internal/auth"
-	"github.com/FollowLille/loyalty/internal/database"
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/services"
 )
 
 // AuthMiddleware проверяет JWT-токен перед обработкой запросами
@@ -18,32 +19,24 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if len(authHeader) == 0 {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			problem.Respond(c, fmt.Errorf("%w: authorization header required", cstmerr.ErrorUnauthorized))
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		if len(tokenString) == 0 {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			problem.Respond(c, fmt.Errorf("%w: authorization header required", cstmerr.ErrorUnauthorized))
 			return
 		}
-		userName, err := auth.ValidateToken(tokenString)
+		userID, roles, err := services.AuthenticateToken(c.Request.Context(), tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
-			return
-		}
-		userID, err := database.GetUserIDByName(userName)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			problem.Respond(c, err)
 			return
 		}
 		userIDStr := strconv.FormatInt(userID, 10)
 		c.Set("user_id", userIDStr)
+		c.Set("roles", roles)
 		c.Next()
 	}
 }
@@ -0,0 +1,34 @@
+// Package middleware предоставляет функции для обработки запросов на взаимодействие с программой лояльности
+// Включает в себя функцию учета запросов в метриках Prometheus
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/metrics"
+)
+
+// unmatchedRoute - метка маршрута для запросов, не совпавших ни с одним маршрутом.
+// Путь таких запросов в метку не попадает, чтобы произвольные URL не раздували число временных рядов.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware учитывает количество и длительность запросов по методу, шаблону маршрута и коду ответа.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := c.Writer.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, status, time.Since(start))
+	}
+}
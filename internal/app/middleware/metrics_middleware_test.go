@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/metrics"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/orders/:number", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/orders/12345678903", "/unknown/path"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	assert.Contains(t, body, `gophermart_http_requests_total{method="GET",route="/orders/:number",status="204"}`)
+	assert.Contains(t, body, `gophermart_http_requests_total{method="GET",route="unmatched",status="404"}`)
+	assert.False(t, strings.Contains(body, "12345678903"), "route label must not contain the raw path")
+}
@@ -0,0 +1,78 @@
+// Package middleware предоставляет функции для обработки запросов на взаимодействие с программой лояльности
+// Включает в себя функции для проверки запросов по спецификации OpenAPI
+package middleware
+
+import (
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+)
+
+// OpenAPIValidator проверяет параметры и тело запроса по спецификации OpenAPI.
+// Операция ищется по шаблону маршрута gin, поэтому маршруты, которых нет в спецификации, пропускаются без проверки.
+// Аутентификация спецификацией не проверяется, за нее отвечает AuthMiddleware.
+//
+// Параметры:
+//   - doc: спецификация OpenAPI.
+func OpenAPIValidator(doc *openapi3.T) gin.HandlerFunc {
+	// Клиенту достаточно причины ошибки, схема и значение целиком в ответ не попадают
+	openapi3.SchemaErrorDetailsDisabled = true
+	options := &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	return func(c *gin.Context) {
+		path := OpenAPIPath(c.FullPath())
+		pathItem := doc.Paths.Find(path)
+		if pathItem == nil {
+			c.Next()
+			return
+		}
+		operation := pathItem.GetOperation(c.Request.Method)
+		if operation == nil {
+			c.Next()
+			return
+		}
+
+		pathParams := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route: &routers.Route{
+				Spec:      doc,
+				Path:      path,
+				PathItem:  pathItem,
+				Method:    c.Request.Method,
+				Operation: operation,
+			},
+			Options: options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			problem.RespondInvalidRequest(c, err.Error())
+			return
+		}
+		c.Next()
+	}
+}
+
+// OpenAPIPath переводит шаблон маршрута gin в шаблон пути OpenAPI: /orders/:number -> /orders/{number}.
+//
+// Параметры:
+//   - route: шаблон маршрута gin.
+//
+// Возвращает:
+//   - string: шаблон пути OpenAPI.
+func OpenAPIPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
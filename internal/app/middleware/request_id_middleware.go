@@ -0,0 +1,26 @@
+// Package middleware предоставляет функции для обработки запросов на взаимодействие с программой лояльности
+// Включает в себя функцию присвоения запросу идентификатора для связывания записей лога
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// RequestIDMiddleware присваивает запросу идентификатор из заголовка X-Request-ID или генерирует новый,
+// если заголовок не передан или некорректен. Идентификатор возвращается в заголовке ответа,
+// а в контексте запроса сохраняется логгер, добавляющий его к каждой записи.
+// Должен подключаться первым, чтобы идентификатор был доступен остальным обработчикам.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(config.RequestIDHeader)
+		if !config.ValidRequestID(requestID) {
+			requestID = config.NewRequestID()
+		}
+
+		c.Request = c.Request.WithContext(config.WithRequestID(c.Request.Context(), requestID))
+		c.Header(config.RequestIDHeader, requestID)
+		c.Next()
+	}
+}
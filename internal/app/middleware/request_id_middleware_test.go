@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "propagated", header: "req-42", wantSame: true},
+		{name: "generated", header: ""},
+		{name: "too_long", header: strings.Repeat("a", 128+1)},
+		{name: "with_spaces", header: "req 42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext string
+			router := gin.New()
+			router.Use(RequestIDMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				fromContext = config.RequestIDFromContext(c.Request.Context())
+				assert.NotNil(t, config.LoggerFromContext(c.Request.Context()))
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(config.RequestIDHeader, tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(config.RequestIDHeader)
+			assert.NotEmpty(t, got)
+			assert.Equal(t, got, fromContext)
+			if tt.wantSame {
+				assert.Equal(t, tt.header, got)
+			} else {
+				assert.NotEqual(t, tt.header, got)
+				assert.Len(t, got, 32)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package middleware предоставляет функции для обработки запросов на взаимодействие с программой лояльности
+// Включает в себя функции для проверки ролей пользователя
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/FollowLille/loyalty/internal/app/problem"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// RequireRole пропускает запрос дальше, только если у пользователя есть указанная роль.
+// Роли берутся из JWT-токена, поэтому должен использоваться после AuthMiddleware.
+//
+// Параметры:
+//   - role: требуемая роль.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("roles")
+		if !ok {
+			problem.Respond(c, cstmerr.ErrorUnauthorized)
+			return
+		}
+
+		roles, _ := value.([]string)
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+
+		problem.Respond(c, fmt.Errorf("%w: role %s required", cstmerr.ErrorForbidden, role))
+	}
+}
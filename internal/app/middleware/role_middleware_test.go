@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		roles      interface{}
+		setRoles   bool
+		wantStatus int
+	}{
+		{
+			name:       "has_role",
+			roles:      []string{"admin"},
+			setRoles:   true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing_role",
+			roles:      []string{"support"},
+			setRoles:   true,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "no_roles_in_token",
+			roles:      []string(nil),
+			setRoles:   true,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "not_authenticated",
+			setRoles:   false,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.setRoles {
+					c.Set("roles", tt.roles)
+				}
+				c.Next()
+			})
+			router.GET("/admin", RequireRole("admin"), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code, "RequireRole() failed for test case: %v", tt.name)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Package openapi содержит спецификацию OpenAPI 3 пользовательского HTTP API системы лояльности.
+// Спецификация встраивается в бинарный файл, отдается клиентам и используется для проверки запросов.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Spec - спецификация OpenAPI в формате JSON.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Load разбирает встроенную спецификацию и проверяет ее корректность.
+//
+// Возвращает:
+//   - *openapi3.T: разобранная спецификация.
+//   - error: ошибка, если спецификация некорректна.
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse openapi spec: %w", err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	return doc, nil
+}
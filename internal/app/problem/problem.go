@@ -0,0 +1,158 @@
+// Package problem формирует ответы об ошибках в формате application/problem+json (RFC 7807).
+// Все доменные ошибки сопоставляются с HTTP-статусом и стабильным кодом ошибки в одном месте.
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// ContentType - тип содержимого ответа об ошибке.
+const ContentType = "application/problem+json"
+
+// typeBase - префикс URI типа ошибки. Код ошибки дописывается в конец.
+const typeBase = "https://loyalty.example/problems/"
+
+// Problem описывает тело ответа об ошибке по RFC 7807.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"` // стабильный код ошибки, на который могут опираться клиенты
+}
+
+// mapping описывает соответствие доменной ошибки HTTP-статусу и коду ошибки.
+type mapping struct {
+	err    error
+	status int
+	code   string
+	title  string
+}
+
+// mappings перечисляет все доменные ошибки. Порядок важен: используется первое совпадение по errors.Is.
+var mappings = []mapping{
+	{cstmerr.ErrorInvalidRequest, http.StatusBadRequest, "invalid_request", "Invalid request"},
+	{cstmerr.ErrorUnauthorized, http.StatusUnauthorized, "unauthorized", "Unauthorized"},
+	{cstmerr.ErrorForbidden, http.StatusForbidden, "forbidden", "Insufficient permissions"},
+	{cstmerr.ErrorIdempotencyKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency key reused"},
+	{cstmerr.ErrorIdempotencyKeyInProgress, http.StatusConflict, "idempotency_key_in_progress", "Request is still in progress"},
+	{cstmerr.ErrorInvalidCursor, http.StatusBadRequest, "invalid_cursor", "Invalid cursor"},
+	{cstmerr.ErrorInvalidFilter, http.StatusBadRequest, "invalid_filter", "Invalid filter"},
+
+	{cstmerr.ErrorUserAlreadyExists, http.StatusConflict, "user_already_exists", "Username already exists"},
+	{cstmerr.ErrorUserDoesNotExist, http.StatusNotFound, "user_not_found", "User not found"},
+	{cstmerr.ErrorInvalidCredentials, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password"},
+	{cstmerr.ErrorInvalidPassword, http.StatusUnauthorized, "invalid_password", "Invalid password"},
+	{cstmerr.ErrorWeakPassword, http.StatusBadRequest, "weak_password", "Password does not meet requirements"},
+	{cstmerr.ErrorInvalidResetToken, http.StatusBadRequest, "invalid_reset_token", "Invalid or expired reset token"},
+	{cstmerr.ErrorAccountLocked, http.StatusLocked, "account_locked", "Account temporarily locked"},
+	{cstmerr.ErrorTooManyLoginAttempts, http.StatusTooManyRequests, "too_many_login_attempts", "Too many login attempts"},
+	{cstmerr.ErrorInvalidReferralCode, http.StatusBadRequest, "invalid_referral_code", "Invalid referral code"},
+	{cstmerr.ErrorReferralLimitReached, http.StatusConflict, "referral_limit_reached", "Referral code has reached its limit"},
+
+	{cstmerr.ErrorInvalidOrderNumber, http.StatusUnprocessableEntity, "invalid_order_number", "Invalid order number"},
+	{cstmerr.ErrorOrderUploadedByAnotherUser, http.StatusConflict, "order_uploaded_by_another_user", "Order already uploaded by another user"},
+	{cstmerr.ErrOrderNotFound, http.StatusNotFound, "order_not_found", "Order not found"},
+	{cstmerr.ErrorOrderIsWithdrawal, http.StatusConflict, "order_is_withdrawal", "Order is a withdrawal and cannot be changed"},
+	{cstmerr.ErrorOrderAlreadyExists, http.StatusConflict, "order_already_exists", "Order already exists"},
+
+	{cstmerr.ErrorInsufficientBalance, http.StatusPaymentRequired, "insufficient_balance", "Insufficient balance"},
+	{cstmerr.ErrorInvalidAmount, http.StatusBadRequest, "invalid_amount", "Invalid amount"},
+	{cstmerr.ErrorInvalidReasonCode, http.StatusBadRequest, "invalid_reason_code", "Invalid reason code"},
+	{cstmerr.ErrorReasonRequired, http.StatusBadRequest, "reason_required", "Reason is required"},
+	{cstmerr.ErrorWithdrawalNotFound, http.StatusNotFound, "withdrawal_not_found", "Withdrawal not found"},
+	{cstmerr.ErrorRefundExceedsAmount, http.StatusConflict, "refund_exceeds_withdrawal", "Refund exceeds withdrawn amount"},
+	{cstmerr.ErrorInvalidTransfer, http.StatusBadRequest, "invalid_transfer", "Invalid transfer"},
+	{cstmerr.ErrorTransferLimitExceeded, http.StatusUnprocessableEntity, "transfer_limit_exceeded", "Transfer limit exceeded"},
+	{cstmerr.ErrorPromoCodeNotFound, http.StatusNotFound, "promo_code_not_found", "Promo code not found"},
+	{cstmerr.ErrorPromoCodeInactive, http.StatusUnprocessableEntity, "promo_code_inactive", "Promo code is not active"},
+	{cstmerr.ErrorPromoLimitReached, http.StatusConflict, "promo_limit_reached", "Promo code redemption limit reached"},
+	{cstmerr.ErrorCampaignBudgetExhausted, http.StatusUnprocessableEntity, "campaign_budget_exhausted", "Campaign budget exhausted"},
+
+	{cstmerr.ErrorInvalidAPIKey, http.StatusUnauthorized, "invalid_api_key", "Invalid API key"},
+	{cstmerr.ErrorInvalidScope, http.StatusBadRequest, "invalid_scope", "Invalid scope"},
+	{cstmerr.ErrorAPIKeyNotFound, http.StatusNotFound, "api_key_not_found", "Merchant key not found"},
+	{cstmerr.ErrorMerchantNotFound, http.StatusUnprocessableEntity, "merchant_not_found", "Merchant not found"},
+
+	{cstmerr.ErrorInvalidWebhook, http.StatusBadRequest, "invalid_webhook", "Invalid webhook subscription"},
+	{cstmerr.ErrorWebhookNotFound, http.StatusNotFound, "webhook_not_found", "Webhook subscription not found"},
+	{cstmerr.ErrorWebhookDeliveryNotFound, http.StatusNotFound, "webhook_delivery_not_found", "Webhook delivery not found"},
+
+	{cstmerr.ErrorInvalidCampaign, http.StatusBadRequest, "invalid_campaign", "Invalid campaign"},
+	{cstmerr.ErrorCampaignNotFound, http.StatusNotFound, "campaign_not_found", "Campaign not found"},
+	{cstmerr.ErrorCampaignCodeTaken, http.StatusConflict, "campaign_code_taken", "Promo code is used by another active campaign"},
+}
+
+// New формирует описание ошибки для доменной ошибки.
+// Неизвестные ошибки считаются внутренними, их текст клиенту не передается.
+//
+// Параметры:
+//   - err: ошибка.
+//
+// Возвращает:
+//   - Problem: описание ошибки.
+func New(err error) Problem {
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return Problem{
+				Type:   typeBase + m.code,
+				Title:  m.title,
+				Status: m.status,
+				Detail: err.Error(),
+				Code:   m.code,
+			}
+		}
+	}
+	return Problem{
+		Type:   typeBase + "internal_error",
+		Title:  "Internal server error",
+		Status: http.StatusInternalServerError,
+		Code:   "internal_error",
+	}
+}
+
+// Respond отвечает клиенту ошибкой в формате application/problem+json и прерывает обработку запроса.
+// Для отказа во входе из-за перебора паролей выставляет заголовок Retry-After.
+// Внутренние ошибки логируются.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - err: ошибка.
+func Respond(c *gin.Context, err error) {
+	p := New(err)
+	p.Instance = c.Request.URL.Path
+
+	var blockErr *cstmerr.LoginBlockedError
+	if errors.As(err, &blockErr) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blockErr.RetryAfter.Seconds()))))
+	}
+
+	if p.Status >= http.StatusInternalServerError {
+		config.LoggerFromContext(c.Request.Context()).Error("Request failed", zap.String("path", c.Request.URL.Path), zap.Error(err))
+	} else {
+		config.LoggerFromContext(c.Request.Context()).Warn("Request rejected", zap.String("path", c.Request.URL.Path), zap.String("code", p.Code), zap.Error(err))
+	}
+
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// RespondInvalidRequest отвечает клиенту ошибкой некорректного запроса с указанной причиной.
+//
+// Параметры:
+//   - c: контекст HTTP-запроса.
+//   - detail: причина, по которой запрос некорректен.
+func RespondInvalidRequest(c *gin.Context, detail string) {
+	Respond(c, fmt.Errorf("%w: %s", cstmerr.ErrorInvalidRequest, detail))
+}
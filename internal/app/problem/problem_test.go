@@ -0,0 +1,97 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "sentinel",
+			err:        cstmerr.ErrorInsufficientBalance,
+			wantStatus: http.StatusPaymentRequired,
+			wantCode:   "insufficient_balance",
+			wantDetail: "insufficient balance",
+		},
+		{
+			name:       "wrapped",
+			err:        fmt.Errorf("%w: 123", cstmerr.ErrorInvalidOrderNumber),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "invalid_order_number",
+			wantDetail: "invalid order number: 123",
+		},
+		{
+			name:       "withdrawal_order_exists",
+			err:        fmt.Errorf("failed to register withdrawal: %w", cstmerr.ErrorOrderAlreadyExists),
+			wantStatus: http.StatusConflict,
+			wantCode:   "order_already_exists",
+			wantDetail: "failed to register withdrawal: order already exists",
+		},
+		{
+			name:       "login_blocked",
+			err:        &cstmerr.LoginBlockedError{Err: cstmerr.ErrorAccountLocked, RetryAfter: time.Minute},
+			wantStatus: http.StatusLocked,
+			wantCode:   "account_locked",
+		},
+		{
+			name:       "unknown",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(tt.err)
+			assert.Equal(t, tt.wantStatus, p.Status)
+			assert.Equal(t, tt.wantCode, p.Code)
+			assert.Equal(t, typeBase+tt.wantCode, p.Type)
+			if tt.wantDetail != "" {
+				assert.Equal(t, tt.wantDetail, p.Detail)
+			}
+		})
+	}
+}
+
+func TestNew_InternalErrorHidesDetail(t *testing.T) {
+	p := New(errors.New("pq: password authentication failed"))
+	assert.Empty(t, p.Detail)
+}
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/user/login", nil)
+
+	Respond(c, &cstmerr.LoginBlockedError{Err: cstmerr.ErrorTooManyLoginAttempts, RetryAfter: 1500 * time.Millisecond})
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.True(t, c.IsAborted())
+
+	var body Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "too_many_login_attempts", body.Code)
+	assert.Equal(t, "/api/user/login", body.Instance)
+}
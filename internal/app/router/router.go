@@ -0,0 +1,103 @@
+// Package router собирает HTTP-маршруты системы лояльности и подключает к ним middleware.
+package router
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	"github.com/FollowLille/loyalty/internal/app/handlers"
+	"github.com/FollowLille/loyalty/internal/app/middleware"
+	"github.com/FollowLille/loyalty/internal/app/openapi"
+	"github.com/FollowLille/loyalty/internal/auth"
+	"github.com/FollowLille/loyalty/internal/compress"
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/services"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// New создает HTTP-роутер со всеми маршрутами системы лояльности.
+// Запросы к пользовательскому API проверяются по спецификации OpenAPI.
+// Для каждого запроса начинается спан трассировки, продолжающий трассу из заголовка traceparent.
+// IP-адрес клиента берется из заголовков прокси только для соединений от config.TrustedProxies.
+//
+// Возвращает:
+//   - *gin.Engine: роутер.
+//   - error: ошибка, если спецификация OpenAPI или список доверенных прокси некорректны.
+func New() (*gin.Engine, error) {
+	doc, err := openapi.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+	validator := middleware.OpenAPIValidator(doc)
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+	router.Use(otelgin.Middleware(tracing.ServiceName), middleware.RequestIDMiddleware(), middleware.MetricsMiddleware(), gin.Recovery(), config.RequestLogger(), config.ResponseLogger())
+	router.Use(compress.GzipMiddleware(), compress.GzipResponseMiddleware())
+
+	router.GET("/healthz", handlers.Healthz)
+	router.GET("/readyz", handlers.Readyz)
+	router.GET("/openapi.json", handlers.OpenAPISpec)
+	router.GET("/swagger", handlers.SwaggerUI)
+
+	public := router.Group("/api/user")
+	public.Use(validator)
+	{
+		public.POST("/register", handlers.Register)
+		public.POST("/login", handlers.Login)
+		public.POST("/password/reset", handlers.RequestPasswordReset)
+		public.POST("/password/reset/confirm", handlers.ConfirmPasswordReset)
+	}
+
+	protected := router.Group("/api/user")
+	protected.Use(middleware.AuthMiddleware(), validator)
+	{
+		protected.POST("/orders", middleware.IdempotencyMiddleware("orders"), handlers.UploadOrder)
+		protected.GET("/orders", handlers.GetOrders)
+		protected.GET("/orders/events", handlers.OrderEvents)
+		protected.GET("/balance", handlers.GetBalance)
+		protected.GET("/tier", handlers.GetTier)
+		protected.GET("/referrals", handlers.GetReferrals)
+		protected.POST("/balance/withdraw", middleware.IdempotencyMiddleware("withdraw"), handlers.GetWithdrawRequest)
+		protected.POST("/balance/transfer", middleware.IdempotencyMiddleware("transfer"), handlers.TransferPoints)
+		protected.POST("/promo", middleware.IdempotencyMiddleware("promo"), handlers.RedeemPromo)
+		protected.GET("/withdrawals", handlers.GetWithdrawals)
+		protected.GET("/statement", handlers.GetStatement)
+		protected.PUT("/password", handlers.ChangePassword)
+	}
+
+	admin := router.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireRole(auth.RoleAdmin))
+	{
+		admin.GET("/users/:login", handlers.AdminGetUser)
+		admin.POST("/users/:login/adjustments", handlers.AdminAdjustBalance)
+		admin.POST("/orders/:number/reprocess", handlers.AdminReprocessOrder)
+		admin.POST("/orders/:number/invalidate", handlers.AdminInvalidateOrder)
+		admin.POST("/withdrawals/:order/reversals", handlers.AdminReverseWithdrawal)
+		admin.GET("/merchant-keys", handlers.AdminListMerchantKeys)
+		admin.POST("/merchant-keys", handlers.AdminCreateMerchantKey)
+		admin.DELETE("/merchant-keys/:id", handlers.AdminRevokeMerchantKey)
+		admin.GET("/webhooks", handlers.AdminListWebhooks)
+		admin.POST("/webhooks", handlers.AdminCreateWebhook)
+		admin.DELETE("/webhooks/:id", handlers.AdminRevokeWebhook)
+		admin.GET("/webhooks/:id/deliveries", handlers.AdminListWebhookDeliveries)
+		admin.POST("/webhook-deliveries/:id/retry", handlers.AdminRetryWebhookDelivery)
+		admin.GET("/campaigns", handlers.AdminListCampaigns)
+		admin.POST("/campaigns", handlers.AdminCreateCampaign)
+		admin.GET("/campaigns/:id", handlers.AdminGetCampaign)
+		admin.PUT("/campaigns/:id", handlers.AdminUpdateCampaign)
+		admin.DELETE("/campaigns/:id", handlers.AdminArchiveCampaign)
+	}
+
+	merchant := router.Group("/api/merchant")
+	{
+		merchant.POST("/orders", middleware.APIKeyMiddleware(services.ScopeOrdersWrite), handlers.MerchantUploadOrder)
+		merchant.POST("/withdrawals/:order/reversals", middleware.APIKeyMiddleware(services.ScopeWithdrawalsRefund), handlers.MerchantReverseWithdrawal)
+	}
+
+	return router, nil
+}
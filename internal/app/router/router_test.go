@@ -0,0 +1,87 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/app/middleware"
+	"github.com/FollowLille/loyalty/internal/app/openapi"
+	"github.com/FollowLille/loyalty/internal/app/problem"
+)
+
+// userAPIPrefix - префикс маршрутов, которые должны быть описаны в спецификации OpenAPI.
+const userAPIPrefix = "/api/user"
+
+// TestRoutesMatchOpenAPISpec падает, если маршрут пользовательского API есть в роутере, но не описан
+// в спецификации, или описан в спецификации, но не зарегистрирован в роутере.
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine, err := New()
+	require.NoError(t, err)
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+
+	var routes []string
+	for _, route := range engine.Routes() {
+		if strings.HasPrefix(route.Path, userAPIPrefix) {
+			routes = append(routes, route.Method+" "+middleware.OpenAPIPath(route.Path))
+		}
+	}
+
+	var operations []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			operations = append(operations, method+" "+path)
+		}
+	}
+
+	assert.ElementsMatch(t, operations, routes, "routes and openapi spec diverge")
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine, err := New()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "missing_fields", contentType: "application/json", body: `{}`},
+		{name: "wrong_type", contentType: "application/json", body: `{"login": 1, "password": "secret"}`},
+		{name: "wrong_content_type", contentType: "text/plain", body: `login=user`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/user/register", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestOpenAPISpecServed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine, err := New()
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(openapi.Spec), w.Body.String())
+}
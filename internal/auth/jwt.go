@@ -10,9 +10,29 @@ import (
 	"github.com/FollowLille/loyalty/internal/config"
 )
 
+// RoleAdmin - роль администратора системы лояльности.
+const RoleAdmin = "admin"
+
+// TokenClaims описывает данные, которые хранятся в JWT-токене.
+type TokenClaims struct {
+	Username     string   // имя пользователя
+	TokenVersion int64    // версия токенов пользователя, увеличивается при смене пароля или ролей
+	Roles        []string // роли пользователя
+}
+
+// HasRole проверяет, есть ли у владельца токена указанная роль.
+func (c TokenClaims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 // GenerateToken создает JWT-токен для указанного пользователя.
-// JWT-токен содержит имя пользователя и время его действия.
-// Время действия токена устанавливается на 24 часа.
+// JWT-токен содержит имя пользователя и время его действия.
+// Время действия токена устанавливается на 24 часа.
 // Токен сгенерирован и возвращается в виде строки.
 // Параметры:
 //   - username: имя пользователя.
@@ -21,11 +41,25 @@ import (
 //   - string: JWT-токен для указанного пользователя.
 //   - error: ошибка, если произошла ошибка при генерации токена.
 func GenerateToken(username string) (string, error) {
-	if username == "" {
+	return IssueToken(TokenClaims{Username: username})
+}
+
+// IssueToken создает JWT-токен с указанными данными.
+// Время действия токена устанавливается на 24 часа.
+// Параметры:
+//   - claims: данные токена.
+//
+// Возвращает:
+//   - string: JWT-токен.
+//   - error: ошибка, если произошла ошибка при генерации токена.
+func IssueToken(claims TokenClaims) (string, error) {
+	if claims.Username == "" {
 		return "", fmt.Errorf("empty username")
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
+		"username": claims.Username,
+		"ver":      claims.TokenVersion,
+		"roles":    claims.Roles,
 		"exp":      time.Now().Add(time.Hour * 24).Unix(),
 	})
 	secretKey := []byte(config.SuperSecretKey)
@@ -33,7 +67,7 @@ func GenerateToken(username string) (string, error) {
 }
 
 // ValidateToken проверяет JWT-токен на валидность.
-// JWT-токен должен содержать имя пользователя и время его действия.
+// JWT-токен должен содержать имя пользователя и время его действия.
 // Если токен валиден, функция возвращает имя пользователя.
 // Если токен невалиден, функция возвращает ошибку.
 // Параметры:
@@ -43,6 +77,22 @@ func GenerateToken(username string) (string, error) {
 //   - string: имя пользователя, если токен валиден.
 //   - error: ошибка, если произошла ошибка при проверке токена.
 func ValidateToken(tokenStr string) (string, error) {
+	claims, err := ParseToken(tokenStr)
+	if err != nil {
+		return "", err
+	}
+	return claims.Username, nil
+}
+
+// ParseToken проверяет JWT-токен на валидность и возвращает его данные.
+// Токены, выпущенные без версии, считаются токенами нулевой версии и без ролей.
+// Параметры:
+//   - tokenStr: JWT-токен для проверки.
+//
+// Возвращает:
+//   - TokenClaims: данные токена, если токен валиден.
+//   - error: ошибка, если произошла ошибка при проверке токена.
+func ParseToken(tokenStr string) (TokenClaims, error) {
 	// Разбор и валидация токена
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		// Проверяем, что используется HMAC с алгоритмом HS256
@@ -53,29 +103,45 @@ func ValidateToken(tokenStr string) (string, error) {
 		return []byte(config.SuperSecretKey), nil
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to parse token: %w", err)
+		return TokenClaims{}, fmt.Errorf("failed to parse token: %w", err)
 	}
 
 	// Проверка валидности токена и извлечение claims
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return "", fmt.Errorf("invalid token")
+		return TokenClaims{}, fmt.Errorf("invalid token")
 	}
 
 	// Проверяем expiration (exp)
 	expirationTime, ok := claims["exp"].(float64)
 	if !ok {
-		return "", fmt.Errorf("invalid expiration time format")
+		return TokenClaims{}, fmt.Errorf("invalid expiration time format")
 	}
 	if time.Now().Unix() > int64(expirationTime) {
-		return "", fmt.Errorf("token expired")
+		return TokenClaims{}, fmt.Errorf("token expired")
 	}
 
 	// Извлекаем username
 	username, ok := claims["username"].(string)
 	if !ok || username == "" {
-		return "", fmt.Errorf("invalid or missing username in token")
+		return TokenClaims{}, fmt.Errorf("invalid or missing username in token")
+	}
+
+	// Извлекаем версию токена, если она есть
+	var version int64
+	if ver, ok := claims["ver"].(float64); ok {
+		version = int64(ver)
+	}
+
+	// Извлекаем роли, если они есть
+	var roles []string
+	if rawRoles, ok := claims["roles"].([]interface{}); ok {
+		for _, rawRole := range rawRoles {
+			if role, ok := rawRole.(string); ok {
+				roles = append(roles, role)
+			}
+		}
 	}
 
-	return username, nil
+	return TokenClaims{Username: username, TokenVersion: version, Roles: roles}, nil
 }
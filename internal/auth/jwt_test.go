@@ -136,3 +136,108 @@ func TestValidateToken(t *testing.T) {
 		})
 	}
 }
+
+func TestParseToken(t *testing.T) {
+	type args struct {
+		tokenStr string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    TokenClaims
+		wantErr bool
+	}{
+		{
+			name: "token_with_version",
+			args: args{
+				tokenStr: func() string {
+					config.SuperSecretKey = "test_secret"
+					token, _ := IssueToken(TokenClaims{Username: "test_user", TokenVersion: 3})
+					return token
+				}(),
+			},
+			want:    TokenClaims{Username: "test_user", TokenVersion: 3},
+			wantErr: false,
+		},
+		{
+			name: "token_with_roles",
+			args: args{
+				tokenStr: func() string {
+					config.SuperSecretKey = "test_secret"
+					token, _ := IssueToken(TokenClaims{Username: "admin_user", TokenVersion: 1, Roles: []string{RoleAdmin}})
+					return token
+				}(),
+			},
+			want:    TokenClaims{Username: "admin_user", TokenVersion: 1, Roles: []string{RoleAdmin}},
+			wantErr: false,
+		},
+		{
+			name: "token_without_version",
+			args: args{
+				tokenStr: func() string {
+					token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+						"username": "legacy_user",
+						"exp":      time.Now().Add(time.Hour).Unix(),
+					})
+					tokenStr, _ := token.SignedString([]byte("test_secret"))
+					return tokenStr
+				}(),
+			},
+			want:    TokenClaims{Username: "legacy_user", TokenVersion: 0},
+			wantErr: false,
+		},
+		{
+			name: "malformed_token",
+			args: args{
+				tokenStr: "malformed.token.string",
+			},
+			want:    TokenClaims{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SuperSecretKey = "test_secret" // Установим тестовый ключ
+			claims, err := ParseToken(tt.args.tokenStr)
+			if tt.wantErr {
+				assert.Error(t, err, "ParseToken() should return error for test case: %v", tt.name)
+			} else {
+				assert.NoError(t, err, "ParseToken() failed for test case: %v", tt.name)
+			}
+			assert.Equal(t, tt.want, claims, "Expected and actual claims do not match for test case: %v", tt.name)
+		})
+	}
+}
+
+func TestTokenClaims_HasRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims TokenClaims
+		role   string
+		want   bool
+	}{
+		{
+			name:   "has_role",
+			claims: TokenClaims{Username: "admin_user", Roles: []string{"support", RoleAdmin}},
+			role:   RoleAdmin,
+			want:   true,
+		},
+		{
+			name:   "missing_role",
+			claims: TokenClaims{Username: "test_user", Roles: []string{"support"}},
+			role:   RoleAdmin,
+			want:   false,
+		},
+		{
+			name:   "no_roles",
+			claims: TokenClaims{Username: "test_user"},
+			role:   RoleAdmin,
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.claims.HasRole(tt.role), "HasRole() failed for test case: %v", tt.name)
+		})
+	}
+}
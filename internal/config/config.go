@@ -15,3 +15,109 @@ var DatabaseRetryDelays = []time.Duration{
 var SuperSecretKey string = "You'llNeverGuessIt"
 
 var AccrualAPIURL string = "http://localhost:8081"
+
+// Настройки защиты от перебора паролей.
+// Для логина и для IP-адреса счётчики неудачных попыток ведутся раздельно:
+// первые попытки проходят без задержки, затем задержка удваивается с каждой неудачей,
+// а по достижении максимума логин (или IP-адрес) блокируется на LoginLockoutDuration.
+var (
+	LoginFreeAttempts    = 3                // неудачные попытки по логину без задержки
+	LoginMaxAttempts     = 5                // неудачные попытки по логину до блокировки учетной записи
+	LoginIPFreeAttempts  = 10               // неудачные попытки с одного IP без задержки
+	LoginIPMaxAttempts   = 20               // неудачные попытки с одного IP до блокировки адреса
+	LoginBaseDelay       = 1 * time.Second  // начальная задержка между неудачными попытками
+	LoginLockoutDuration = 15 * time.Minute // длительность блокировки
+)
+
+// TrustedProxies хранит адреса и подсети прокси, которым доверяются заголовки X-Forwarded-For и X-Real-IP.
+// Если список пуст, IP-адрес клиента берется из адреса соединения, и подменить его заголовком нельзя.
+var TrustedProxies []string
+
+// PasswordPolicy описывает требования к сложности пароля.
+type PasswordPolicy struct {
+	MinLength      int  // минимальная длина пароля
+	RequireUpper   bool // обязательна заглавная буква
+	RequireLower   bool // обязательна строчная буква
+	RequireDigit   bool // обязательна цифра
+	RequireSpecial bool // обязателен символ, не являющийся буквой или цифрой
+}
+
+// Password хранит действующие требования к сложности пароля.
+var Password = PasswordPolicy{MinLength: 8}
+
+// PasswordResetTTL хранит время жизни токена для сброса пароля.
+var PasswordResetTTL = 30 * time.Minute
+
+// Настройки постраничной выдачи списков.
+var (
+	PageDefaultLimit = 100  // размер страницы, если клиент его не указал
+	PageMaxLimit     = 1000 // максимальный размер страницы
+)
+
+// IdempotencyKeyTTL хранит срок хранения ключа идемпотентности и сохраненного ответа.
+var IdempotencyKeyTTL = 24 * time.Hour
+
+// Настройки сгорания баллов.
+var (
+	PointsExpiryMonths       = 0                   // срок жизни начисленных баллов в месяцах, 0 - баллы не сгорают
+	PointsExpiringSoonWindow = 30 * 24 * time.Hour // период, за который баллы показываются как сгорающие
+	PointsExpiryInterval     = time.Hour           // периодичность запуска задачи сгорания баллов
+)
+
+// Настройки потока событий пользователя.
+var (
+	UserEventsTTL           = 24 * time.Hour   // срок хранения событий для возобновления потока по Last-Event-ID
+	UserEventsReplayLimit   = 1000             // максимальное число пропущенных событий, отдаваемых при возобновлении
+	EventsHeartbeatInterval = 15 * time.Second // периодичность отправки heartbeat в открытый поток
+)
+
+// Настройки доставки вебхуков.
+var (
+	WebhookDispatchInterval = 5 * time.Second  // периодичность опроса очереди доставок
+	WebhookBatchSize        = 50               // максимальное число доставок за один проход
+	WebhookTimeout          = 10 * time.Second // таймаут запроса к получателю
+	WebhookLease            = 10 * time.Minute // время, на которое доставки закрепляются за экземпляром, больше WebhookBatchSize * WebhookTimeout
+	WebhookMaxAttempts      = 8                // число попыток, после которого доставка переводится в dead
+	WebhookRetryBaseDelay   = 30 * time.Second // задержка перед второй попыткой, далее удваивается
+	WebhookRetryMaxDelay    = time.Hour        // максимальная задержка между попытками
+)
+
+// Ограничения переводов баллов между пользователями, 0 - без ограничения.
+var (
+	TransferMaxAmount  = 10000.0 // максимальная сумма одного перевода
+	TransferDailyLimit = 30000.0 // максимальная сумма переводов одного отправителя за календарные сутки (UTC)
+)
+
+// LoyaltyTier описывает уровень программы лояльности.
+type LoyaltyTier struct {
+	Name       string  // название уровня
+	MinPoints  float64 // баллы, начисленные за последние 12 месяцев, необходимые для уровня
+	Multiplier float64 // множитель начислений системы расчета баллов
+}
+
+// Tiers хранит уровни программы лояльности по возрастанию порога.
+// Первый уровень с нулевым порогом присваивается всем пользователям.
+var Tiers = []LoyaltyTier{
+	{Name: "Basic", MinPoints: 0, Multiplier: 1},
+	{Name: "Silver", MinPoints: 1000, Multiplier: 1.1},
+	{Name: "Gold", MinPoints: 5000, Multiplier: 1.2},
+	{Name: "Platinum", MinPoints: 15000, Multiplier: 1.5},
+}
+
+// TierEvaluationHour хранит час (UTC), в который ежесуточно пересчитываются уровни пользователей.
+var TierEvaluationHour = 3
+
+// Настройки реферальной программы. Бонусы начисляются, когда первый заказ приглашенного пользователя обработан.
+var (
+	ReferrerBonus = 500.0 // бонус пригласившему пользователю
+	RefereeBonus  = 250.0 // бонус приглашенному пользователю
+	ReferralLimit = 50    // максимальное число приглашенных одним пользователем, 0 - без ограничения
+)
+
+// Настройки плавного завершения работы.
+// После сигнала завершения /readyz сразу отвечает 503, а сервер еще ShutdownDelay принимает запросы,
+// чтобы балансировщик успел исключить экземпляр, после чего до ShutdownTimeout дожидается текущих запросов.
+var (
+	ShutdownDelay   = 5 * time.Second  // время между переходом в неготовность и остановкой приема запросов
+	ShutdownTimeout = 30 * time.Second // максимальное время ожидания завершения текущих запросов
+)
@@ -4,7 +4,12 @@ package config
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +19,35 @@ import (
 
 var Logger = zap.NewNop()
 
+// RequestIDHeader - заголовок, в котором передается идентификатор запроса.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину идентификатора запроса, переданного клиентом.
+const maxRequestIDLength = 128
+
+// redactedValue подставляется в лог вместо скрытых тел запросов и заголовков.
+const redactedValue = "[REDACTED]"
+
+// sensitiveBodyPaths - маршруты, тела запросов которых содержат пароли или токены и не попадают в лог.
+var sensitiveBodyPaths = map[string]struct{}{
+	"/api/user/register":               {},
+	"/api/user/login":                  {},
+	"/api/user/password":               {},
+	"/api/user/password/reset":         {},
+	"/api/user/password/reset/confirm": {},
+}
+
+// sensitiveHeaders - заголовки с учетными данными, которые не попадают в лог.
+var sensitiveHeaders = map[string]struct{}{
+	"Authorization": {},
+	"X-Api-Key":     {},
+	"Cookie":        {},
+}
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
 // InitLogger инициализирует логгер.
 // Если произошла ошибка при инициализации, программа завершается с кодом ошибки.
 //
@@ -39,6 +73,78 @@ func InitLogger(level string) error {
 	return nil
 }
 
+// NewRequestID генерирует случайный идентификатор запроса.
+//
+// Возвращает:
+//   - string: идентификатор из 32 шестнадцатеричных символов.
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// Идентификатор нужен только для связывания записей лога, при сбое генератора достаточно времени
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// ValidRequestID проверяет, что идентификатор запроса, переданный клиентом, не пустой, не слишком длинный
+// и состоит только из видимых ASCII-символов, чтобы его можно было безопасно писать в лог и заголовки.
+//
+// Параметры:
+//   - requestID: идентификатор запроса.
+//
+// Возвращает:
+//   - bool: true, если идентификатор можно использовать.
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < '!' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID сохраняет в контексте идентификатор запроса и логгер, добавляющий его к каждой записи.
+//
+// Параметры:
+//   - ctx: родительский контекст.
+//   - requestID: идентификатор запроса.
+//
+// Возвращает:
+//   - context.Context: контекст с идентификатором запроса и логгером.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	return context.WithValue(ctx, loggerKey{}, Logger.With(zap.String("request_id", requestID)))
+}
+
+// RequestIDFromContext возвращает идентификатор запроса, сохраненный WithRequestID.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//
+// Возвращает:
+//   - string: идентификатор запроса, пустая строка, если он не сохранен.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// LoggerFromContext возвращает логгер запроса, сохраненный WithRequestID, или общий логгер.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//
+// Возвращает:
+//   - *zap.Logger: логгер.
+func LoggerFromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return Logger
+}
+
 // RequestLogger инициализирует обработчик для логирования запросов.
 // В лог попадают все входящие запросы и ответы.
 //
@@ -49,39 +155,56 @@ func InitLogger(level string) error {
 //   - метод запроса
 //   - путь запроса
 //   - время выполнения запроса
-//   - тело запроса
-//   - хэдеры запроса
+//   - тело запроса, кроме маршрутов с паролями и токенами
+//   - хэдеры запроса, кроме заголовков с учетными данными
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
+		logger := LoggerFromContext(c.Request.Context())
 
 		bodyBytes, err := io.ReadAll(c.Request.Body)
 		if err != nil {
-			Logger.Error("Failed to read request body", zap.Error(err))
+			logger.Error("Failed to read request body", zap.Error(err))
 			c.Next()
 			return
 		}
 
-		headers := c.Request.Header
-		headerMap := make(map[string]string)
-		for k, v := range headers {
-			headerMap[k] = v[0]
-		}
-
-		Logger.Info("Got incomming request",
+		loggedBody := redactBody(c.Request.URL.Path, bodyBytes)
+		logger.Info("Got incomming request",
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
 			zap.Duration("duration", time.Since(start)),
-			zap.ByteString("body", bodyBytes),
-			zap.Any("headers", headerMap),
+			zap.ByteString("body", loggedBody),
+			zap.Any("headers", redactHeaders(c.Request.Header)),
 		)
 
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		c.Set("requestBody", bodyBytes)
+		c.Set("requestBody", loggedBody)
 		c.Next()
 	}
 }
 
+// redactBody возвращает тело запроса для записи в лог, скрывая его на маршрутах с паролями и токенами.
+func redactBody(path string, body []byte) []byte {
+	if _, ok := sensitiveBodyPaths[path]; ok && len(body) > 0 {
+		return []byte(redactedValue)
+	}
+	return body
+}
+
+// redactHeaders возвращает первые значения заголовков запроса, скрывая заголовки с учетными данными.
+func redactHeaders(headers http.Header) map[string]string {
+	headerMap := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if _, ok := sensitiveHeaders[http.CanonicalHeaderKey(k)]; ok {
+			headerMap[k] = redactedValue
+			continue
+		}
+		headerMap[k] = v[0]
+	}
+	return headerMap
+}
+
 // ResponseLogger инициализирует обработчик для логирования ответов.
 // В лог попадает только ответ.
 //
@@ -96,17 +219,18 @@ func ResponseLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
+		logger := LoggerFromContext(c.Request.Context())
 		statusCode := c.Writer.Status()
 		responseSize := c.Writer.Size()
 
 		if requestBody, exists := c.Get("requestBody"); exists {
-			Logger.Info("Sent response",
+			logger.Info("Sent response",
 				zap.Int("status_code", statusCode),
 				zap.Int("response_size", responseSize),
 				zap.ByteString("request_body", requestBody.([]byte)),
 			)
 		} else {
-			Logger.Warn("Request body not found in context")
+			logger.Warn("Request body not found in context")
 		}
 	}
 }
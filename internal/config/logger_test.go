@@ -0,0 +1,60 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want string
+	}{
+		{
+			name: "login",
+			path: "/api/user/login",
+			body: `{"login":"user","password":"secret"}`,
+			want: redactedValue,
+		},
+		{
+			name: "reset_confirm",
+			path: "/api/user/password/reset/confirm",
+			body: `{"token":"abc","password":"secret"}`,
+			want: redactedValue,
+		},
+		{
+			name: "other_route",
+			path: "/api/user/balance/withdraw",
+			body: `{"order":"2377225624","sum":10}`,
+			want: `{"order":"2377225624","sum":10}`,
+		},
+		{
+			name: "empty_body",
+			path: "/api/user/login",
+			body: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(redactBody(tt.path, []byte(tt.body))))
+		})
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer token")
+	headers.Set("X-API-Key", "gm_secret")
+	headers.Set("Content-Type", "application/json")
+
+	got := redactHeaders(headers)
+
+	assert.Equal(t, redactedValue, got["Authorization"])
+	assert.Equal(t, redactedValue, got["X-Api-Key"])
+	assert.Equal(t, "application/json", got["Content-Type"])
+}
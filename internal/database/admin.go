@@ -0,0 +1,346 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для административных операций и журнала действий администраторов
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+type User struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+}
+
+type BalanceAdjustment struct {
+	ID         int64
+	UserID     int64
+	Amount     float64
+	ReasonCode string
+	Comment    string
+	AdminID    int64
+	CreatedAt  time.Time
+}
+
+// AuditRecord описывает запись журнала действий администратора.
+type AuditRecord struct {
+	AdminID      int64
+	Action       string
+	TargetUserID *int64
+	TargetOrder  *int64
+	Details      map[string]interface{}
+}
+
+// GetUserByName возвращает пользователя по имени.
+// Если пользователь не найден, возвращает ошибку cstmerr.ErrorUserDoesNotExist.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - name: имя пользователя.
+//
+// Возвращает:
+//   - User: пользователь.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GetUserByName(ctx context.Context, name string) (User, error) {
+	query := `SELECT id, name, created_at FROM loyalty.users WHERE name = $1`
+	row, err := QueryRowWithRetry(ctx, DB, query, name)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to get user", zap.Error(err))
+		return User{}, err
+	}
+
+	var user User
+	err = row.Scan(&user.ID, &user.Name, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, cstmerr.ErrorUserDoesNotExist
+	} else if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan user", zap.Error(err))
+		return User{}, err
+	}
+	return user, nil
+}
+
+// CreateBalanceAdjustment сохраняет ручную корректировку баланса пользователя и запись в журнале действий администратора.
+// Списание, после которого баланс пользователя стал бы отрицательным, отклоняется с ошибкой cstmerr.ErrorInsufficientBalance.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adjustment: корректировка баланса, поля ID и CreatedAt заполняются базой данных.
+//
+// Возвращает:
+//   - BalanceAdjustment: сохранённая корректировка.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func CreateBalanceAdjustment(ctx context.Context, adjustment BalanceAdjustment) (BalanceAdjustment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return BalanceAdjustment{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	if adjustment.Amount < 0 {
+		if err = lockBalanceForDebit(ctx, tx, adjustment.UserID, -adjustment.Amount); err != nil {
+			return BalanceAdjustment{}, err
+		}
+	}
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.balance_adjustments (user_id, amount, reason_code, comment, admin_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		adjustment.UserID, adjustment.Amount, adjustment.ReasonCode, adjustment.Comment, adjustment.AdminID)
+	if err != nil {
+		return BalanceAdjustment{}, fmt.Errorf("failed to insert balance adjustment: %w", err)
+	}
+	if err = row.Scan(&adjustment.ID, &adjustment.CreatedAt); err != nil {
+		return BalanceAdjustment{}, fmt.Errorf("failed to scan balance adjustment: %w", err)
+	}
+
+	if adjustment.Amount > 0 {
+		err = addLot(ctx, tx, adjustment.UserID, nil, LotSourceAdjustment, adjustment.Amount)
+	} else {
+		err = consumeLots(ctx, tx, adjustment.UserID, -adjustment.Amount)
+	}
+	if err != nil {
+		return BalanceAdjustment{}, err
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID:      adjustment.AdminID,
+		Action:       "balance_adjustment",
+		TargetUserID: &adjustment.UserID,
+		Details: map[string]interface{}{
+			"adjustment_id": adjustment.ID,
+			"amount":        adjustment.Amount,
+			"reason_code":   adjustment.ReasonCode,
+			"comment":       adjustment.Comment,
+		},
+	})
+	if err != nil {
+		return BalanceAdjustment{}, err
+	}
+
+	if err = recordBalanceEvent(ctx, tx, adjustment.UserID); err != nil {
+		return BalanceAdjustment{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return BalanceAdjustment{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Balance adjusted",
+		zap.Int64("user_id", adjustment.UserID),
+		zap.Int64("admin_id", adjustment.AdminID),
+		zap.Float64("amount", adjustment.Amount),
+		zap.String("reason_code", adjustment.ReasonCode))
+	return adjustment, nil
+}
+
+// FetchUserAdjustments возвращает список ручных корректировок баланса пользователя.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - []BalanceAdjustment: список корректировок, от новых к старым.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchUserAdjustments(ctx context.Context, userID int64) ([]BalanceAdjustment, error) {
+	query := `
+		SELECT id, user_id, amount, reason_code, comment, admin_id, created_at
+		FROM loyalty.balance_adjustments
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, query, userID)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user adjustments", zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch user adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	var adjustments []BalanceAdjustment
+	for rows.Next() {
+		var a BalanceAdjustment
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Amount, &a.ReasonCode, &a.Comment, &a.AdminID, &a.CreatedAt); err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to scan adjustment", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan adjustment: %w", err)
+		}
+		adjustments = append(adjustments, a)
+	}
+	if rows.Err() != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user adjustments", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("failed to fetch user adjustments: %w", rows.Err())
+	}
+
+	return adjustments, nil
+}
+
+// SetOrderStatusByAdmin принудительно меняет статус заказа и сохраняет запись в журнале действий администратора.
+// Если заказ не найден, возвращает ошибку cstmerr.ErrOrderNotFound.
+// Заказы, созданные списанием баллов, не меняются: возвращается ошибка cstmerr.ErrorOrderIsWithdrawal.
+// Если заказ перестает быть обработанным, начисление за него отменяется; когда оно уже потрачено
+// и баланс пользователя стал бы отрицательным, возвращается ошибка cstmerr.ErrorInsufficientBalance.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - orderNumber: номер заказа.
+//   - status: новый статус заказа.
+//   - action: название действия для журнала.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func SetOrderStatusByAdmin(ctx context.Context, adminID int64, orderNumber, status, action string) error {
+	orderInt, err := strconv.ParseInt(orderNumber, 10, 64)
+	if err != nil {
+		return cstmerr.ErrOrderNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT sd.status_name, COALESCE(b.withdrawn, 0), COALESCE(b.accrual, 0), uo.user_id
+		FROM loyalty.orders o
+		JOIN loyalty.status_dictionary sd ON sd.id = o.status
+		LEFT JOIN loyalty.bonuses b ON b.order_id = o.id
+		LEFT JOIN loyalty.user_orders uo ON uo.order_id = o.id
+		WHERE o.id = $1
+		LIMIT 1
+		FOR UPDATE OF o`, orderInt)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	var previousStatus string
+	var withdrawn, accrual float64
+	var userID *int64
+	if err = row.Scan(&previousStatus, &withdrawn, &accrual, &userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrOrderNotFound
+			return err
+		}
+		return fmt.Errorf("failed to scan order: %w", err)
+	}
+	if withdrawn > 0 {
+		err = cstmerr.ErrorOrderIsWithdrawal
+		return err
+	}
+
+	// Блокируем пользователя до смены статуса, чтобы параллельные списания не потратили отменяемое начисление
+	dropsAccrual := previousStatus == "PROCESSED" && status != "PROCESSED"
+	if dropsAccrual && userID != nil {
+		if err = lockUser(ctx, tx, *userID); err != nil {
+			return err
+		}
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		UPDATE loyalty.orders
+		SET status = (SELECT id FROM loyalty.status_dictionary WHERE status_name = $1 LIMIT 1)
+		WHERE id = $2`, status, orderInt)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	// Начисление за заказ, который больше не считается обработанным, не должно сгорать или расходоваться
+	if dropsAccrual {
+		if err = deleteOrderLot(ctx, tx, orderInt); err != nil {
+			return err
+		}
+		if userID != nil {
+			if err = checkBalanceCovers(ctx, tx, *userID, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID:      adminID,
+		Action:       action,
+		TargetUserID: userID,
+		TargetOrder:  &orderInt,
+		Details: map[string]interface{}{
+			"previous_status": previousStatus,
+			"new_status":      status,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if userID != nil && previousStatus != status {
+		payload := OrderEventPayload{Number: orderNumber, Status: status, Accrual: accrual}
+		if err = recordUserEvent(ctx, tx, *userID, UserEventOrder, payload); err != nil {
+			return err
+		}
+		if status == "PROCESSED" || previousStatus == "PROCESSED" {
+			if err = recordBalanceEvent(ctx, tx, *userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Order status changed by admin",
+		zap.String("order_number", orderNumber),
+		zap.String("previous_status", previousStatus),
+		zap.String("status", status),
+		zap.Int64("admin_id", adminID))
+	return nil
+}
+
+// insertAuditRecord добавляет запись в журнал действий администратора в рамках транзакции,
+// в которой выполняется само действие.
+func insertAuditRecord(ctx context.Context, tx ExecContexter, record AuditRecord) error {
+	details, err := json.Marshal(record.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.admin_audit_log (admin_id, action, target_user_id, target_order, details)
+		VALUES ($1, $2, $3, $4, $5)`,
+		record.AdminID, record.Action, record.TargetUserID, record.TargetOrder, string(details))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit record: %w", err)
+	}
+	return nil
+}
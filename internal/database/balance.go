@@ -15,16 +15,17 @@ import (
 // В случае успеха, возвращает текущий баланс пользователя и общую сумму его выводов.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //
 // Возвращает:
 //   - float64: текущий баланс пользователя.
 //   - float64: общую сумму его выводов.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func FetchUserBalance(userID int64) (float64, float64, error) {
+func FetchUserBalance(ctx context.Context, userID int64) (float64, float64, error) {
 	query := `
 		SELECT
-			COALESCE(ub.total_accruals - ub.total_withdrawn, 0) as current_balance,
+			COALESCE(ub.current_balance, 0) as current_balance,
 			COALESCE(ub.total_withdrawn, 0) as total_withdrawn
 		FROM loyalty.user_bonuses ub
 		WHERE ub.user_id = $1;
@@ -33,15 +34,51 @@ func FetchUserBalance(userID int64) (float64, float64, error) {
 	var currentBalance float64
 	var totalWithdrawn float64
 
-	row, err := QueryRowWithRetry(context.Background(), DB, query, userID)
+	row, err := QueryRowWithRetry(ctx, DB, query, userID)
 	if err != nil {
-		config.Logger.Error("Failed to fetch user balance", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to fetch user balance", zap.Error(err))
 		return 0, 0, err
 	}
 
 	if err = row.Scan(&currentBalance, &totalWithdrawn); err != nil {
-		config.Logger.Error("Failed to scan result", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
 		return 0, 0, err
 	}
 	return currentBalance, totalWithdrawn, nil
 }
+
+// FetchUserPendingBalance возвращает количество незакрытых заказов пользователя (NEW и PROCESSING)
+// и сумму предварительных начислений по ним, если система начислений уже их сообщила.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - int64: количество незакрытых заказов.
+//   - float64: сумма предварительных начислений.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchUserPendingBalance(ctx context.Context, userID int64) (int64, float64, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(b.accrual), 0)
+		FROM loyalty.user_orders uo
+		JOIN loyalty.orders o ON o.id = uo.order_id
+		JOIN loyalty.status_dictionary sd ON sd.id = o.status
+		LEFT JOIN loyalty.bonuses b ON b.order_id = o.id
+		WHERE uo.user_id = $1 AND NOT sd.is_closed AND COALESCE(b.withdrawn, 0) = 0;
+	`
+
+	row, err := QueryRowWithRetry(ctx, DB, query, userID)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user pending balance", zap.Error(err))
+		return 0, 0, err
+	}
+
+	var orders int64
+	var accrual float64
+	if err = row.Scan(&orders, &accrual); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
+		return 0, 0, err
+	}
+	return orders, accrual, nil
+}
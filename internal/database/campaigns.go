@@ -0,0 +1,547 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для работы с маркетинговыми кампаниями, промокодами и бонусами по кампаниям
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// Типы кампаний.
+const (
+	CampaignTypeCode       = "code"       // фиксированный бонус за ввод промокода
+	CampaignTypeMultiplier = "multiplier" // множитель начислений за заказы, загруженные в период кампании
+)
+
+// campaignBonusEffective отбирает действующие бонусы по кампаниям (псевдоним cb): бонусы по промокоду
+// и бонусы за заказы, которые по-прежнему считаются обработанными.
+const campaignBonusEffective = `(cb.order_id IS NULL OR EXISTS (
+	SELECT 1 FROM loyalty.orders co
+	JOIN loyalty.status_dictionary csd ON csd.id = co.status
+	WHERE co.id = cb.order_id AND csd.status_name = 'PROCESSED'))`
+
+// campaignColumns перечисляет поля кампании вместе с итогами по выданным бонусам в порядке scanCampaign.
+const campaignColumns = `
+	c.id, c.name, c.type, c.code, c.bonus, c.multiplier, c.starts_at, c.ends_at, c.budget, c.per_user_limit,
+	c.created_by, c.created_at, c.updated_at, c.archived_at,
+	COALESCE((SELECT SUM(cb.amount) FROM loyalty.campaign_bonuses cb WHERE cb.campaign_id = c.id AND ` + campaignBonusEffective + `), 0),
+	(SELECT COUNT(*) FROM loyalty.campaign_bonuses cb WHERE cb.campaign_id = c.id AND ` + campaignBonusEffective + `)`
+
+type Campaign struct {
+	ID           int64
+	Name         string
+	Type         string
+	Code         *string // промокод, только для кампаний типа code
+	Bonus        float64 // бонус за промокод
+	Multiplier   float64 // множитель начислений
+	StartsAt     time.Time
+	EndsAt       time.Time // конец действия (не включительно)
+	Budget       *float64  // сумма бонусов, которую можно выдать по кампании, nil - без ограничения
+	PerUserLimit int       // число бонусов одному пользователю, 0 - без ограничения
+	CreatedBy    int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ArchivedAt   *time.Time
+	Spent        float64 // сумма выданных бонусов
+	Redemptions  int64   // число выданных бонусов
+}
+
+// CampaignBonus описывает бонус, выданный пользователю по кампании.
+type CampaignBonus struct {
+	ID           int64
+	CampaignID   int64
+	CampaignName string
+	UserID       int64
+	OrderID      *int64
+	Amount       float64
+	CreatedAt    time.Time
+}
+
+// scanCampaign читает кампанию из строки с полями campaignColumns.
+func scanCampaign(row interface{ Scan(...interface{}) error }) (Campaign, error) {
+	var c Campaign
+	var budget sql.NullFloat64
+	err := row.Scan(&c.ID, &c.Name, &c.Type, &c.Code, &c.Bonus, &c.Multiplier, &c.StartsAt, &c.EndsAt, &budget,
+		&c.PerUserLimit, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.ArchivedAt, &c.Spent, &c.Redemptions)
+	if budget.Valid {
+		c.Budget = &budget.Float64
+	}
+	return c, err
+}
+
+// campaignAuditDetails возвращает параметры кампании для журнала действий администратора.
+func campaignAuditDetails(c Campaign) map[string]interface{} {
+	return map[string]interface{}{
+		"campaign_id":    c.ID,
+		"name":           c.Name,
+		"type":           c.Type,
+		"code":           c.Code,
+		"bonus":          c.Bonus,
+		"multiplier":     c.Multiplier,
+		"starts_at":      c.StartsAt,
+		"ends_at":        c.EndsAt,
+		"budget":         c.Budget,
+		"per_user_limit": c.PerUserLimit,
+	}
+}
+
+// checkCampaignCode проверяет в рамках транзакции, что промокод не занят другой активной кампанией.
+func checkCampaignCode(ctx context.Context, tx *sql.Tx, code *string, campaignID int64) error {
+	if code == nil {
+		return nil
+	}
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT EXISTS (
+			SELECT 1 FROM loyalty.campaigns
+			WHERE code = $1 AND archived_at IS NULL AND id <> $2)`, *code, campaignID)
+	if err != nil {
+		return fmt.Errorf("failed to check promo code: %w", err)
+	}
+	var taken bool
+	if err = row.Scan(&taken); err != nil {
+		return fmt.Errorf("failed to check promo code: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("%w: %s", cstmerr.ErrorCampaignCodeTaken, *code)
+	}
+	return nil
+}
+
+// CreateCampaign сохраняет кампанию и запись в журнале действий администратора.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - campaign: кампания, поля ID, CreatedAt и UpdatedAt заполняются базой данных.
+//
+// Возвращает:
+//   - Campaign: сохраненная кампания.
+//   - error: cstmerr.ErrorCampaignCodeTaken, если промокод занят активной кампанией, или ошибка выполнения запроса.
+func CreateCampaign(ctx context.Context, campaign Campaign) (Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return Campaign{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	if err = checkCampaignCode(ctx, tx, campaign.Code, 0); err != nil {
+		return Campaign{}, err
+	}
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.campaigns (name, type, code, bonus, multiplier, starts_at, ends_at, budget, per_user_limit, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`,
+		campaign.Name, campaign.Type, campaign.Code, campaign.Bonus, campaign.Multiplier,
+		campaign.StartsAt, campaign.EndsAt, campaign.Budget, campaign.PerUserLimit, campaign.CreatedBy)
+	if err != nil {
+		return Campaign{}, fmt.Errorf("failed to create campaign: %w", err)
+	}
+	if err = row.Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt); err != nil {
+		return Campaign{}, fmt.Errorf("failed to scan campaign: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: campaign.CreatedBy,
+		Action:  "campaign_create",
+		Details: campaignAuditDetails(campaign),
+	})
+	if err != nil {
+		return Campaign{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Campaign{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Campaign created", zap.Int64("campaign_id", campaign.ID), zap.String("type", campaign.Type))
+	return campaign, nil
+}
+
+// UpdateCampaign изменяет параметры кампании и сохраняет запись в журнале действий администратора.
+// Тип кампании не меняется, архивную кампанию изменить нельзя. Уже выданные бонусы не пересчитываются.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - campaign: новые параметры кампании с идентификатором изменяемой кампании.
+//
+// Возвращает:
+//   - Campaign: измененная кампания.
+//   - error: cstmerr.ErrorCampaignNotFound, если активная кампания не найдена, cstmerr.ErrorInvalidCampaign
+//     при попытке сменить тип, cstmerr.ErrorCampaignCodeTaken или ошибка выполнения запроса.
+func UpdateCampaign(ctx context.Context, adminID int64, campaign Campaign) (Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return Campaign{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT type FROM loyalty.campaigns WHERE id = $1 AND archived_at IS NULL FOR UPDATE`, campaign.ID)
+	if err != nil {
+		return Campaign{}, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	var campaignType string
+	if err = row.Scan(&campaignType); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorCampaignNotFound
+			return Campaign{}, err
+		}
+		return Campaign{}, fmt.Errorf("failed to scan campaign: %w", err)
+	}
+	if campaignType != campaign.Type {
+		err = fmt.Errorf("%w: campaign type cannot be changed", cstmerr.ErrorInvalidCampaign)
+		return Campaign{}, err
+	}
+	if err = checkCampaignCode(ctx, tx, campaign.Code, campaign.ID); err != nil {
+		return Campaign{}, err
+	}
+
+	row, err = QueryRowWithRetry(ctx, tx, `
+		UPDATE loyalty.campaigns c
+		SET name = $2, code = $3, bonus = $4, multiplier = $5, starts_at = $6, ends_at = $7,
+			budget = $8, per_user_limit = $9, updated_at = NOW()
+		WHERE c.id = $1
+		RETURNING `+campaignColumns,
+		campaign.ID, campaign.Name, campaign.Code, campaign.Bonus, campaign.Multiplier,
+		campaign.StartsAt, campaign.EndsAt, campaign.Budget, campaign.PerUserLimit)
+	if err != nil {
+		return Campaign{}, fmt.Errorf("failed to update campaign: %w", err)
+	}
+	updated, err := scanCampaign(row)
+	if err != nil {
+		return Campaign{}, fmt.Errorf("failed to scan campaign: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: adminID,
+		Action:  "campaign_update",
+		Details: campaignAuditDetails(updated),
+	})
+	if err != nil {
+		return Campaign{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Campaign{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Campaign updated", zap.Int64("campaign_id", campaign.ID), zap.Int64("admin_id", adminID))
+	return updated, nil
+}
+
+// ArchiveCampaign завершает кампанию: промокод перестает действовать, новые начисления не умножаются.
+// Выданные бонусы остаются у пользователей.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - campaignID: идентификатор кампании.
+//
+// Возвращает:
+//   - error: cstmerr.ErrorCampaignNotFound, если активная кампания не найдена, или ошибка выполнения запроса.
+func ArchiveCampaign(ctx context.Context, adminID, campaignID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		UPDATE loyalty.campaigns
+		SET archived_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND archived_at IS NULL
+		RETURNING name`, campaignID)
+	if err != nil {
+		return fmt.Errorf("failed to archive campaign: %w", err)
+	}
+	var name string
+	if err = row.Scan(&name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorCampaignNotFound
+			return err
+		}
+		return fmt.Errorf("failed to scan campaign: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: adminID,
+		Action:  "campaign_archive",
+		Details: map[string]interface{}{
+			"campaign_id": campaignID,
+			"name":        name,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Campaign archived", zap.Int64("campaign_id", campaignID), zap.Int64("admin_id", adminID))
+	return nil
+}
+
+// GetCampaign возвращает кампанию вместе с итогами по выданным бонусам.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - campaignID: идентификатор кампании.
+//
+// Возвращает:
+//   - Campaign: кампания.
+//   - error: cstmerr.ErrorCampaignNotFound, если кампания не найдена, или ошибка выполнения запроса.
+func GetCampaign(ctx context.Context, campaignID int64) (Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	row, err := QueryRowWithRetry(ctx, DB, `SELECT `+campaignColumns+` FROM loyalty.campaigns c WHERE c.id = $1`, campaignID)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to get campaign", zap.Error(err))
+		return Campaign{}, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	campaign, err := scanCampaign(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Campaign{}, cstmerr.ErrorCampaignNotFound
+		}
+		return Campaign{}, fmt.Errorf("failed to scan campaign: %w", err)
+	}
+	return campaign, nil
+}
+
+// ListCampaigns возвращает все кампании, включая архивные, вместе с итогами по выданным бонусам.
+//
+// Возвращает:
+//   - []Campaign: кампании, новые первыми.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ListCampaigns(ctx context.Context) ([]Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, `
+		SELECT `+campaignColumns+`
+		FROM loyalty.campaigns c
+		ORDER BY c.created_at DESC, c.id DESC`)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to list campaigns", zap.Error(err))
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	var campaigns []Campaign
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", rows.Err())
+	}
+	return campaigns, nil
+}
+
+// RedeemPromoCode начисляет пользователю бонус по промокоду. Кампания блокируется на время транзакции,
+// поэтому параллельные активации не превысят бюджет кампании и лимит активаций на пользователя.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - code: промокод в верхнем регистре.
+//
+// Возвращает:
+//   - CampaignBonus: начисленный бонус.
+//   - error: cstmerr.ErrorPromoCodeNotFound, cstmerr.ErrorPromoCodeInactive, cstmerr.ErrorPromoLimitReached,
+//     cstmerr.ErrorCampaignBudgetExhausted или ошибка выполнения запроса.
+func RedeemPromoCode(ctx context.Context, userID int64, code string) (CampaignBonus, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return CampaignBonus{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT c.id, c.name, c.bonus, c.budget, c.per_user_limit, NOW() >= c.starts_at AND NOW() < c.ends_at
+		FROM loyalty.campaigns c
+		WHERE c.code = $1 AND c.type = 'code' AND c.archived_at IS NULL
+		FOR UPDATE`, code)
+	if err != nil {
+		return CampaignBonus{}, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	bonus := CampaignBonus{UserID: userID}
+	var budget sql.NullFloat64
+	var perUserLimit int
+	var active bool
+	if err = row.Scan(&bonus.CampaignID, &bonus.CampaignName, &bonus.Amount, &budget, &perUserLimit, &active); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorPromoCodeNotFound
+			return CampaignBonus{}, err
+		}
+		return CampaignBonus{}, fmt.Errorf("failed to scan campaign: %w", err)
+	}
+	if !active {
+		err = cstmerr.ErrorPromoCodeInactive
+		return CampaignBonus{}, err
+	}
+
+	row, err = QueryRowWithRetry(ctx, tx, `
+		SELECT
+			COALESCE((SELECT SUM(cb.amount) FROM loyalty.campaign_bonuses cb WHERE cb.campaign_id = $1 AND `+campaignBonusEffective+`), 0),
+			(SELECT COUNT(*) FROM loyalty.campaign_bonuses cb WHERE cb.campaign_id = $1 AND cb.user_id = $2 AND `+campaignBonusEffective+`)`,
+		bonus.CampaignID, userID)
+	if err != nil {
+		return CampaignBonus{}, fmt.Errorf("failed to fetch campaign usage: %w", err)
+	}
+	var spent float64
+	var redeemed int
+	if err = row.Scan(&spent, &redeemed); err != nil {
+		return CampaignBonus{}, fmt.Errorf("failed to scan campaign usage: %w", err)
+	}
+	if perUserLimit > 0 && redeemed >= perUserLimit {
+		err = cstmerr.ErrorPromoLimitReached
+		return CampaignBonus{}, err
+	}
+	if budget.Valid && spent+bonus.Amount > budget.Float64 {
+		err = cstmerr.ErrorCampaignBudgetExhausted
+		return CampaignBonus{}, err
+	}
+
+	row, err = QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.campaign_bonuses (campaign_id, user_id, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`, bonus.CampaignID, userID, bonus.Amount)
+	if err != nil {
+		return CampaignBonus{}, fmt.Errorf("failed to insert campaign bonus: %w", err)
+	}
+	if err = row.Scan(&bonus.ID, &bonus.CreatedAt); err != nil {
+		return CampaignBonus{}, fmt.Errorf("failed to scan campaign bonus: %w", err)
+	}
+
+	if err = addLot(ctx, tx, userID, nil, LotSourceCampaign, bonus.Amount); err != nil {
+		return CampaignBonus{}, err
+	}
+	if err = recordBalanceEvent(ctx, tx, userID); err != nil {
+		return CampaignBonus{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return CampaignBonus{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Promo code redeemed",
+		zap.Int64("campaign_id", bonus.CampaignID),
+		zap.Int64("user_id", userID),
+		zap.Float64("amount", bonus.Amount))
+	return bonus, nil
+}
+
+// applyCampaignBonuses начисляет бонусы по кампаниям-множителям, в период которых был загружен заказ,
+// в рамках транзакции начисления за заказ. Бонус ограничивается остатком бюджета кампании,
+// кампании, исчерпавшие бюджет или лимит бонусов пользователю, пропускаются.
+// Повторный вызов для того же заказа бонусы не дублирует.
+func applyCampaignBonuses(ctx context.Context, tx *sql.Tx, userID, orderID int64, accrual float64) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.id, c.multiplier, c.budget, c.per_user_limit,
+			COALESCE((SELECT SUM(cb.amount) FROM loyalty.campaign_bonuses cb WHERE cb.campaign_id = c.id AND `+campaignBonusEffective+`), 0),
+			(SELECT COUNT(*) FROM loyalty.campaign_bonuses cb WHERE cb.campaign_id = c.id AND cb.user_id = $2 AND `+campaignBonusEffective+`)
+		FROM loyalty.campaigns c
+		JOIN loyalty.orders o ON o.id = $1
+		WHERE c.type = 'multiplier' AND c.archived_at IS NULL
+			AND o.created_at >= c.starts_at AND o.created_at < c.ends_at
+			AND NOT EXISTS (SELECT 1 FROM loyalty.campaign_bonuses cb WHERE cb.campaign_id = c.id AND cb.order_id = $1)
+		ORDER BY c.id
+		FOR UPDATE OF c`, orderID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch campaigns: %w", err)
+	}
+	type grant struct {
+		campaignID int64
+		amount     float64
+	}
+	var grants []grant
+	for rows.Next() {
+		var campaignID int64
+		var multiplier, spent float64
+		var budget sql.NullFloat64
+		var perUserLimit, redeemed int
+		if err = rows.Scan(&campaignID, &multiplier, &budget, &perUserLimit, &spent, &redeemed); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		if perUserLimit > 0 && redeemed >= perUserLimit {
+			continue
+		}
+		amount := accrual * (multiplier - 1)
+		if budget.Valid && spent+amount > budget.Float64 {
+			amount = budget.Float64 - spent
+		}
+		amount = math.Round(amount*100) / 100
+		if amount > 0 {
+			grants = append(grants, grant{campaignID: campaignID, amount: amount})
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to fetch campaigns: %w", err)
+	}
+
+	for _, g := range grants {
+		err = ExecQueryWithRetry(ctx, tx, `
+			INSERT INTO loyalty.campaign_bonuses (campaign_id, user_id, order_id, amount)
+			VALUES ($1, $2, $3, $4)`, g.campaignID, userID, orderID, g.amount)
+		if err != nil {
+			return fmt.Errorf("failed to insert campaign bonus: %w", err)
+		}
+		if err = addLot(ctx, tx, userID, &orderID, LotSourceCampaign, g.amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
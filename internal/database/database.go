@@ -88,12 +88,109 @@ func PrepareDB() error {
 		return err
 	}
 
+	if err = CreateLoginFailuresTable(); err != nil {
+		config.Logger.Fatal("Failed to create login failures table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateLoginHistoryTable(); err != nil {
+		config.Logger.Fatal("Failed to create login history table", zap.Error(err))
+		return err
+	}
+
+	if err = CreatePasswordResetTokensTable(); err != nil {
+		config.Logger.Fatal("Failed to create password reset tokens table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateUserRolesTable(); err != nil {
+		config.Logger.Fatal("Failed to create user roles table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateBalanceAdjustmentsTable(); err != nil {
+		config.Logger.Fatal("Failed to create balance adjustments table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateAdminAuditLogTable(); err != nil {
+		config.Logger.Fatal("Failed to create admin audit log table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateMerchantAPIKeysTable(); err != nil {
+		config.Logger.Fatal("Failed to create merchant API keys table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateIdempotencyKeysTable(); err != nil {
+		config.Logger.Fatal("Failed to create idempotency keys table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateWithdrawalReversalsTable(); err != nil {
+		config.Logger.Fatal("Failed to create withdrawal reversals table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateAccrualLotsTable(); err != nil {
+		config.Logger.Fatal("Failed to create accrual lots table", zap.Error(err))
+		return err
+	}
+
+	if err = CreatePointExpirationsTable(); err != nil {
+		config.Logger.Fatal("Failed to create point expirations table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateUserEventsTable(); err != nil {
+		config.Logger.Fatal("Failed to create user events table", zap.Error(err))
+		return err
+	}
+
+	if err = CreatePointTransfersTable(); err != nil {
+		config.Logger.Fatal("Failed to create point transfers table", zap.Error(err))
+		return err
+	}
+
+	if err = CreateTierTables(); err != nil {
+		config.Logger.Fatal("Failed to create tier tables", zap.Error(err))
+		return err
+	}
+
+	if err = CreateCampaignTables(); err != nil {
+		config.Logger.Fatal("Failed to create campaign tables", zap.Error(err))
+		return err
+	}
+
+	if err = CreateReferralTables(); err != nil {
+		config.Logger.Fatal("Failed to create referral tables", zap.Error(err))
+		return err
+	}
+
+	if err = CreateWebhookTables(); err != nil {
+		config.Logger.Fatal("Failed to create webhook tables", zap.Error(err))
+		return err
+	}
+
 	// Создание VIEW для подсчета бонусов
 	if err = CreateUserBonusesView(); err != nil {
 		config.Logger.Fatal("Failed to create user bonuses view", zap.Error(err))
 		return err
 	}
 
+	// Партии баллов заполняются по уже существующим начислениям и списаниям только один раз
+	if err = BackfillAccrualLots(); err != nil {
+		config.Logger.Fatal("Failed to backfill accrual lots", zap.Error(err))
+		return err
+	}
+
+	// Версия записывается последней, чтобы проверка готовности не прошла до завершения подготовки схемы
+	if err = RecordSchemaVersion(); err != nil {
+		config.Logger.Fatal("Failed to record schema version", zap.Error(err))
+		return err
+	}
+
 	return nil
 }
 
@@ -125,6 +222,7 @@ func CreateUserTable() error {
     			name VARCHAR(255) NOT NULL, 
     			password_hash VARCHAR(255) NOT NULL, 
     			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);
+			ALTER TABLE loyalty.users ADD COLUMN IF NOT EXISTS token_version BIGINT NOT NULL DEFAULT 0;
     			`
 	_, err := DB.Exec(query)
 	if err != nil {
@@ -211,6 +309,10 @@ func CreateBonusesTable() error {
 				withdrawn FLOAT8 NOT NULL DEFAULT 0,
 				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     			CONSTRAINT unique_order_id UNIQUE (order_id));
+			-- Индекс для постраничной выборки списаний по (created_at, order_id)
+			CREATE INDEX IF NOT EXISTS idx_bonuses_withdrawals ON loyalty.bonuses (created_at, order_id) WHERE withdrawn > 0;
+			-- Мерчант, в счет заказа которого списаны баллы; только он может вернуть списание
+			ALTER TABLE loyalty.bonuses ADD COLUMN IF NOT EXISTS merchant_name VARCHAR(255);
 			    `
 	_, err := DB.Exec(query)
 	if err != nil {
@@ -234,6 +336,13 @@ func CreateUsersOrdersTable() error {
 				user_id BIGINT NOT NULL,
 				FOREIGN KEY (order_id) REFERENCES loyalty.orders(id) ON DELETE CASCADE,
 				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			-- Индексы для постраничной выборки заказов пользователя по (created_at, id)
+			CREATE INDEX IF NOT EXISTS idx_user_orders_user_id ON loyalty.user_orders (user_id, order_id);
+			-- Заказ принадлежит одному пользователю; повторные связи, оставленные прежними списаниями, удаляются
+			DELETE FROM loyalty.user_orders a USING loyalty.user_orders b
+			WHERE a.order_id = b.order_id AND a.ctid > b.ctid;
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_user_orders_order_id ON loyalty.user_orders (order_id);
+			CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON loyalty.orders (created_at, id);
 			`
 
 	_, err := DB.Exec(query)
@@ -269,6 +378,579 @@ func CreateOrdersBonusesTable() error {
 	return nil
 }
 
+// CreateLoginFailuresTable создает таблицу для хранения счётчиков неудачных попыток входа.
+// Ключом служит субъект проверки: логин пользователя или IP-адрес клиента.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateLoginFailuresTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.login_failures (
+				subject VARCHAR(255) PRIMARY KEY NOT NULL,
+				failed_count INT NOT NULL DEFAULT 0,
+				last_failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				locked_until TIMESTAMP);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create login failures table", zap.Error(err))
+		return fmt.Errorf("failed to create login failures table: %w", err)
+	}
+	config.Logger.Info("Login failures table is ready")
+	return nil
+}
+
+// CreateLoginHistoryTable создает таблицу для хранения истории попыток входа.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateLoginHistoryTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.login_history (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				user_name VARCHAR(255) NOT NULL,
+				ip VARCHAR(64) NOT NULL,
+				user_agent TEXT NOT NULL DEFAULT '',
+				success BOOLEAN NOT NULL,
+				reason VARCHAR(64) NOT NULL DEFAULT '',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);
+			CREATE INDEX IF NOT EXISTS idx_login_history_user_name ON loyalty.login_history (user_name, created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create login history table", zap.Error(err))
+		return fmt.Errorf("failed to create login history table: %w", err)
+	}
+	config.Logger.Info("Login history table is ready")
+	return nil
+}
+
+// CreatePasswordResetTokensTable создает таблицу для хранения токенов сброса пароля.
+// Сами токены не хранятся, хранится только их SHA-256 хэш.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreatePasswordResetTokensTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.password_reset_tokens (
+				token_hash VARCHAR(64) PRIMARY KEY NOT NULL,
+				user_id BIGINT NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				used_at TIMESTAMP,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create password reset tokens table", zap.Error(err))
+		return fmt.Errorf("failed to create password reset tokens table: %w", err)
+	}
+	config.Logger.Info("Password reset tokens table is ready")
+	return nil
+}
+
+// CreateUserRolesTable создает таблицу для хранения ролей пользователей.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateUserRolesTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.user_roles (
+				user_id BIGINT NOT NULL,
+				role VARCHAR(32) NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, role),
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create user roles table", zap.Error(err))
+		return fmt.Errorf("failed to create user roles table: %w", err)
+	}
+	config.Logger.Info("User roles table is ready")
+	return nil
+}
+
+// CreateBalanceAdjustmentsTable создает таблицу для хранения ручных корректировок баланса.
+// Положительная сумма означает начисление, отрицательная - списание.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateBalanceAdjustmentsTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.balance_adjustments (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				user_id BIGINT NOT NULL,
+				amount FLOAT8 NOT NULL,
+				reason_code VARCHAR(32) NOT NULL,
+				comment TEXT NOT NULL DEFAULT '',
+				admin_id BIGINT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_balance_adjustments_user_id ON loyalty.balance_adjustments (user_id);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create balance adjustments table", zap.Error(err))
+		return fmt.Errorf("failed to create balance adjustments table: %w", err)
+	}
+	config.Logger.Info("Balance adjustments table is ready")
+	return nil
+}
+
+// CreateAdminAuditLogTable создает таблицу для хранения журнала действий администраторов.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateAdminAuditLogTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.admin_audit_log (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				admin_id BIGINT NOT NULL,
+				action VARCHAR(64) NOT NULL,
+				target_user_id BIGINT,
+				target_order BIGINT,
+				details JSONB NOT NULL DEFAULT '{}',
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);
+			CREATE INDEX IF NOT EXISTS idx_admin_audit_log_admin_id ON loyalty.admin_audit_log (admin_id, created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create admin audit log table", zap.Error(err))
+		return fmt.Errorf("failed to create admin audit log table: %w", err)
+	}
+	config.Logger.Info("Admin audit log table is ready")
+	return nil
+}
+
+// CreateMerchantAPIKeysTable создает таблицу для хранения API-ключей мерчантов.
+// Сами ключи не хранятся, хранится только их SHA-256 хэш и короткий префикс для отображения.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateMerchantAPIKeysTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.merchant_api_keys (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				merchant_name VARCHAR(255) NOT NULL,
+				key_prefix VARCHAR(16) NOT NULL,
+				key_hash VARCHAR(64) NOT NULL,
+				scopes TEXT[] NOT NULL DEFAULT '{}',
+				created_by BIGINT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMP,
+				revoked_at TIMESTAMP,
+				CONSTRAINT unique_key_hash UNIQUE (key_hash));
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create merchant API keys table", zap.Error(err))
+		return fmt.Errorf("failed to create merchant API keys table: %w", err)
+	}
+	config.Logger.Info("Merchant API keys table is ready")
+	return nil
+}
+
+// CreateIdempotencyKeysTable создает таблицу для хранения ключей идемпотентности запросов пользователей.
+// Вместе с ключом хранится отпечаток тела запроса и сохраненный ответ для повторной выдачи.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateIdempotencyKeysTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.idempotency_keys (
+				user_id BIGINT NOT NULL,
+				endpoint VARCHAR(255) NOT NULL,
+				key VARCHAR(255) NOT NULL,
+				request_hash VARCHAR(64) NOT NULL,
+				completed BOOLEAN NOT NULL DEFAULT false,
+				status_code INT,
+				content_type VARCHAR(255),
+				response_body BYTEA,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, endpoint, key),
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON loyalty.idempotency_keys (created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create idempotency keys table", zap.Error(err))
+		return fmt.Errorf("failed to create idempotency keys table: %w", err)
+	}
+	config.Logger.Info("Idempotency keys table is ready")
+	return nil
+}
+
+// CreateWithdrawalReversalsTable создает таблицу для хранения возвратов списанных баллов.
+// Возврат привязан к заказу, по которому было списание, и может быть частичным.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateWithdrawalReversalsTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.withdrawal_reversals (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				order_id BIGINT NOT NULL,
+				user_id BIGINT NOT NULL,
+				amount FLOAT8 NOT NULL CHECK (amount > 0),
+				reason TEXT NOT NULL,
+				source VARCHAR(16) NOT NULL,
+				actor_id BIGINT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (order_id) REFERENCES loyalty.orders(id) ON DELETE CASCADE,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_withdrawal_reversals_order_id ON loyalty.withdrawal_reversals (order_id);
+			CREATE INDEX IF NOT EXISTS idx_withdrawal_reversals_user_id ON loyalty.withdrawal_reversals (user_id);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create withdrawal reversals table", zap.Error(err))
+		return fmt.Errorf("failed to create withdrawal reversals table: %w", err)
+	}
+	config.Logger.Info("Withdrawal reversals table is ready")
+	return nil
+}
+
+// CreateAccrualLotsTable создает таблицу партий баллов.
+// Каждое поступление баллов (начисление за заказ, ручная корректировка, возврат списания) образует партию,
+// а списания уменьшают остаток партий начиная с самых старых начислений.
+// Сгорают только партии начислений за заказы.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateAccrualLotsTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.accrual_lots (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				user_id BIGINT NOT NULL,
+				order_id BIGINT,
+				source VARCHAR(16) NOT NULL,
+				amount FLOAT8 NOT NULL,
+				remaining FLOAT8 NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_accrual_lots_order_id ON loyalty.accrual_lots (order_id) WHERE source = 'accrual';
+			CREATE INDEX IF NOT EXISTS idx_accrual_lots_user_id ON loyalty.accrual_lots (user_id, created_at) WHERE remaining > 0;
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create accrual lots table", zap.Error(err))
+		return fmt.Errorf("failed to create accrual lots table: %w", err)
+	}
+	config.Logger.Info("Accrual lots table is ready")
+	return nil
+}
+
+// CreatePointExpirationsTable создает таблицу для хранения записей о сгорании баллов.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreatePointExpirationsTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.point_expirations (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				user_id BIGINT NOT NULL,
+				lot_id BIGINT,
+				amount FLOAT8 NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE,
+				FOREIGN KEY (lot_id) REFERENCES loyalty.accrual_lots(id) ON DELETE SET NULL);
+			CREATE INDEX IF NOT EXISTS idx_point_expirations_user_id ON loyalty.point_expirations (user_id);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create point expirations table", zap.Error(err))
+		return fmt.Errorf("failed to create point expirations table: %w", err)
+	}
+	config.Logger.Info("Point expirations table is ready")
+	return nil
+}
+
+// CreateUserEventsTable создает таблицу для хранения событий пользователя: изменений заказов и баланса.
+// События хранятся ограниченное время, чтобы клиент мог возобновить поток по Last-Event-ID.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreateUserEventsTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.user_events (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				user_id BIGINT NOT NULL,
+				type VARCHAR(32) NOT NULL,
+				payload JSONB NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_user_events_user_id ON loyalty.user_events (user_id, id);
+			CREATE INDEX IF NOT EXISTS idx_user_events_created_at ON loyalty.user_events (created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create user events table", zap.Error(err))
+		return fmt.Errorf("failed to create user events table: %w", err)
+	}
+	config.Logger.Info("User events table is ready")
+	return nil
+}
+
+// CreatePointTransfersTable создает таблицу для хранения переводов баллов между пользователями.
+// Если произошла ошибка при создании таблицы, программа завершается с кодом ошибки.
+// В случае успеха, выводится сообщение об успешном создании таблицы.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблицы.
+func CreatePointTransfersTable() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.point_transfers (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				sender_id BIGINT NOT NULL,
+				recipient_id BIGINT NOT NULL,
+				amount FLOAT8 NOT NULL CHECK (amount > 0),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (sender_id) REFERENCES loyalty.users(id) ON DELETE CASCADE,
+				FOREIGN KEY (recipient_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_point_transfers_sender_id ON loyalty.point_transfers (sender_id, created_at);
+			CREATE INDEX IF NOT EXISTS idx_point_transfers_recipient_id ON loyalty.point_transfers (recipient_id, created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create point transfers table", zap.Error(err))
+		return fmt.Errorf("failed to create point transfers table: %w", err)
+	}
+	config.Logger.Info("Point transfers table is ready")
+	return nil
+}
+
+// CreateTierTables создает таблицы уровней программы лояльности, текущих уровней пользователей
+// и бонусов по множителю уровня. Бонус хранится отдельно от начисления системы расчета баллов,
+// не больше одного бонуса на заказ.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблиц.
+func CreateTierTables() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.tiers (
+				name TEXT PRIMARY KEY NOT NULL,
+				min_points FLOAT8 NOT NULL UNIQUE CHECK (min_points >= 0),
+				multiplier FLOAT8 NOT NULL CHECK (multiplier >= 1));
+			CREATE TABLE IF NOT EXISTS loyalty.user_tiers (
+				user_id BIGINT PRIMARY KEY NOT NULL,
+				tier TEXT NOT NULL,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE TABLE IF NOT EXISTS loyalty.tier_bonuses (
+				order_id BIGINT PRIMARY KEY NOT NULL,
+				user_id BIGINT NOT NULL,
+				tier TEXT NOT NULL,
+				multiplier FLOAT8 NOT NULL,
+				amount FLOAT8 NOT NULL CHECK (amount > 0),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (order_id) REFERENCES loyalty.orders(id) ON DELETE CASCADE,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_tier_bonuses_user_id ON loyalty.tier_bonuses (user_id, created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create tier tables", zap.Error(err))
+		return fmt.Errorf("failed to create tier tables: %w", err)
+	}
+	config.Logger.Info("Tier tables are ready")
+	return nil
+}
+
+// CreateCampaignTables создает таблицы маркетинговых кампаний и выданных по ним бонусов.
+// Бонус по промокоду не связан с заказом, бонус по множителю связан с заказом, за который начислен,
+// и выдается по одной кампании за заказ не больше одного раза.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблиц.
+func CreateCampaignTables() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.campaigns (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				name TEXT NOT NULL,
+				type TEXT NOT NULL CHECK (type IN ('code', 'multiplier')),
+				code TEXT,
+				bonus FLOAT8 NOT NULL DEFAULT 0,
+				multiplier FLOAT8 NOT NULL DEFAULT 1,
+				starts_at TIMESTAMP NOT NULL,
+				ends_at TIMESTAMP NOT NULL,
+				budget FLOAT8,
+				per_user_limit INT NOT NULL DEFAULT 0,
+				created_by BIGINT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				archived_at TIMESTAMP,
+				CHECK (starts_at < ends_at),
+				CHECK ((type = 'code') = (code IS NOT NULL)));
+			-- Код активной кампании уникален, код архивной кампании можно использовать повторно
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_campaigns_code ON loyalty.campaigns (code) WHERE archived_at IS NULL;
+			CREATE TABLE IF NOT EXISTS loyalty.campaign_bonuses (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				campaign_id BIGINT NOT NULL,
+				user_id BIGINT NOT NULL,
+				order_id BIGINT,
+				amount FLOAT8 NOT NULL CHECK (amount > 0),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (campaign_id) REFERENCES loyalty.campaigns(id) ON DELETE CASCADE,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE,
+				FOREIGN KEY (order_id) REFERENCES loyalty.orders(id) ON DELETE CASCADE,
+				CONSTRAINT unique_campaign_order UNIQUE (campaign_id, order_id));
+			CREATE INDEX IF NOT EXISTS idx_campaign_bonuses_campaign_user ON loyalty.campaign_bonuses (campaign_id, user_id);
+			CREATE INDEX IF NOT EXISTS idx_campaign_bonuses_user_id ON loyalty.campaign_bonuses (user_id, created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create campaign tables", zap.Error(err))
+		return fmt.Errorf("failed to create campaign tables: %w", err)
+	}
+	config.Logger.Info("Campaign tables are ready")
+	return nil
+}
+
+// CreateReferralTables создает таблицы реферальных кодов, приглашений и реферальных бонусов.
+// У пользователя не больше одного кода, приглашенный пользователь может быть приглашен только один раз.
+// Суммы бонусов фиксируются в приглашении при регистрации.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблиц.
+func CreateReferralTables() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.referral_codes (
+				user_id BIGINT PRIMARY KEY NOT NULL,
+				code VARCHAR(16) NOT NULL UNIQUE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE TABLE IF NOT EXISTS loyalty.referrals (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				referrer_id BIGINT NOT NULL,
+				referee_id BIGINT NOT NULL UNIQUE,
+				referrer_bonus FLOAT8 NOT NULL CHECK (referrer_bonus >= 0),
+				referee_bonus FLOAT8 NOT NULL CHECK (referee_bonus >= 0),
+				order_id BIGINT,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				rewarded_at TIMESTAMP,
+				CHECK (referrer_id <> referee_id),
+				FOREIGN KEY (referrer_id) REFERENCES loyalty.users(id) ON DELETE CASCADE,
+				FOREIGN KEY (referee_id) REFERENCES loyalty.users(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_referrals_referrer_id ON loyalty.referrals (referrer_id, created_at);
+			CREATE TABLE IF NOT EXISTS loyalty.referral_bonuses (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				referral_id BIGINT NOT NULL,
+				user_id BIGINT NOT NULL,
+				amount FLOAT8 NOT NULL CHECK (amount > 0),
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (referral_id) REFERENCES loyalty.referrals(id) ON DELETE CASCADE,
+				FOREIGN KEY (user_id) REFERENCES loyalty.users(id) ON DELETE CASCADE,
+				CONSTRAINT unique_referral_user UNIQUE (referral_id, user_id));
+			CREATE INDEX IF NOT EXISTS idx_referral_bonuses_user_id ON loyalty.referral_bonuses (user_id, created_at);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create referral tables", zap.Error(err))
+		return fmt.Errorf("failed to create referral tables: %w", err)
+	}
+	config.Logger.Info("Referral tables are ready")
+	return nil
+}
+
+// CreateWebhookTables создает таблицы подписок на вебхуки, исходящих событий (outbox) и журнала доставок.
+// Событие записывается в outbox в транзакции изменения, и в той же транзакции для каждой подходящей
+// подписки создается доставка, которую затем выполняет диспетчер.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при создании таблиц.
+func CreateWebhookTables() error {
+	query := `
+			CREATE TABLE IF NOT EXISTS loyalty.webhook_subscriptions (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				url TEXT NOT NULL,
+				secret VARCHAR(128) NOT NULL,
+				event_types TEXT[] NOT NULL,
+				created_by BIGINT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				revoked_at TIMESTAMPTZ);
+			CREATE TABLE IF NOT EXISTS loyalty.webhook_outbox (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				event_type VARCHAR(64) NOT NULL,
+				payload JSONB NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW());
+			CREATE TABLE IF NOT EXISTS loyalty.webhook_deliveries (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				outbox_id BIGINT NOT NULL,
+				subscription_id BIGINT NOT NULL,
+				status VARCHAR(16) NOT NULL DEFAULT 'pending',
+				attempts INT NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				last_status_code INT,
+				last_error TEXT,
+				delivered_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				FOREIGN KEY (outbox_id) REFERENCES loyalty.webhook_outbox(id) ON DELETE CASCADE,
+				FOREIGN KEY (subscription_id) REFERENCES loyalty.webhook_subscriptions(id) ON DELETE CASCADE,
+				CONSTRAINT unique_webhook_delivery UNIQUE (outbox_id, subscription_id));
+			CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON loyalty.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+			CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON loyalty.webhook_deliveries (subscription_id, id);
+			CREATE TABLE IF NOT EXISTS loyalty.webhook_delivery_attempts (
+				id BIGSERIAL PRIMARY KEY NOT NULL,
+				delivery_id BIGINT NOT NULL,
+				attempt INT NOT NULL,
+				status_code INT,
+				error TEXT,
+				duration_ms BIGINT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				FOREIGN KEY (delivery_id) REFERENCES loyalty.webhook_deliveries(id) ON DELETE CASCADE);
+			CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON loyalty.webhook_delivery_attempts (delivery_id, id);
+			`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		config.Logger.Error("Failed to create webhook tables", zap.Error(err))
+		return fmt.Errorf("failed to create webhook tables: %w", err)
+	}
+	config.Logger.Info("Webhook tables are ready")
+	return nil
+}
+
 // CreateUserBonusesView создает представление для хранения информации о текущем состоянии бонусов пользователей
 // Если произошла ошибка при создании представления, программа завершается с кодом ошибки.
 // В случае успеха, выводится сообщение об успешном создании представления.
@@ -282,7 +964,25 @@ func CreateUserBonusesView() error {
 			u.id AS user_id,
 			u.name AS user_name,
 			COALESCE(SUM(CASE WHEN sd.status_name = 'PROCESSED' THEN b.accrual ELSE 0 END), 0) AS total_accruals,  -- Сумма начислений только для закрытых заказов
-			COALESCE(SUM(CASE WHEN sd.status_name != 'INVALID' THEN b.withdrawn ELSE 0 END), 0) AS total_withdrawn -- Сумма списаний для всех заказов
+			COALESCE(SUM(CASE WHEN sd.status_name != 'INVALID' THEN b.withdrawn ELSE 0 END), 0)
+				- COALESCE((SELECT SUM(wr.amount) FROM loyalty.withdrawal_reversals wr WHERE wr.user_id = u.id), 0) AS total_withdrawn, -- Сумма списаний для всех заказов за вычетом возвратов
+			COALESCE((SELECT SUM(ba.amount) FROM loyalty.balance_adjustments ba WHERE ba.user_id = u.id), 0) AS total_adjustments, -- Сумма ручных корректировок
+			COALESCE((SELECT SUM(pe.amount) FROM loyalty.point_expirations pe WHERE pe.user_id = u.id), 0) AS total_expired, -- Сумма сгоревших баллов
+			COALESCE(SUM(CASE WHEN sd.status_name = 'PROCESSED' THEN b.accrual ELSE 0 END), 0)
+				+ COALESCE((SELECT SUM(ba.amount) FROM loyalty.balance_adjustments ba WHERE ba.user_id = u.id), 0)
+				- COALESCE(SUM(CASE WHEN sd.status_name != 'INVALID' THEN b.withdrawn ELSE 0 END), 0)
+				+ COALESCE((SELECT SUM(wr.amount) FROM loyalty.withdrawal_reversals wr WHERE wr.user_id = u.id), 0)
+				- COALESCE((SELECT SUM(pe.amount) FROM loyalty.point_expirations pe WHERE pe.user_id = u.id), 0)
+				+ COALESCE((SELECT SUM(pt.amount) FROM loyalty.point_transfers pt WHERE pt.recipient_id = u.id), 0)
+				- COALESCE((SELECT SUM(pt.amount) FROM loyalty.point_transfers pt WHERE pt.sender_id = u.id), 0)
+				+ COALESCE(SUM(CASE WHEN sd.status_name = 'PROCESSED' THEN tb.amount ELSE 0 END), 0)
+				+ COALESCE((SELECT SUM(cb.amount) FROM loyalty.campaign_bonuses cb WHERE cb.user_id = u.id AND ` + campaignBonusEffective + `), 0)
+				+ COALESCE((SELECT SUM(rb.amount) FROM loyalty.referral_bonuses rb WHERE rb.user_id = u.id), 0) AS current_balance, -- Текущий баланс
+			COALESCE((SELECT SUM(pt.amount) FROM loyalty.point_transfers pt WHERE pt.recipient_id = u.id), 0) AS total_transfers_in, -- Сумма полученных переводов
+			COALESCE((SELECT SUM(pt.amount) FROM loyalty.point_transfers pt WHERE pt.sender_id = u.id), 0) AS total_transfers_out, -- Сумма отправленных переводов
+			COALESCE(SUM(CASE WHEN sd.status_name = 'PROCESSED' THEN tb.amount ELSE 0 END), 0) AS total_tier_bonuses, -- Сумма бонусов по множителю уровня для закрытых заказов
+			COALESCE((SELECT SUM(cb.amount) FROM loyalty.campaign_bonuses cb WHERE cb.user_id = u.id AND ` + campaignBonusEffective + `), 0) AS total_campaign_bonuses, -- Сумма бонусов по кампаниям
+			COALESCE((SELECT SUM(rb.amount) FROM loyalty.referral_bonuses rb WHERE rb.user_id = u.id), 0) AS total_referral_bonuses -- Сумма реферальных бонусов
 		FROM
 			loyalty.users u
 		LEFT JOIN
@@ -293,6 +993,8 @@ func CreateUserBonusesView() error {
 			loyalty.bonuses b ON b.order_id = o.id
 		LEFT JOIN
 			loyalty.status_dictionary sd ON sd.id = o.status
+		LEFT JOIN
+			loyalty.tier_bonuses tb ON tb.order_id = o.id
 		GROUP BY
 			u.id;
 	`
@@ -311,12 +1013,13 @@ func CreateUserBonusesView() error {
 // В случае успеха, возвращается идентификатор пользователя.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - orderNumber: номер заказа.
 //
 // Возвращает:
 //   - int64: идентификатор пользователя, создавшего заказ.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func GetOrderOwner(orderNumber string) (*int64, error) {
+func GetOrderOwner(ctx context.Context, orderNumber string) (*int64, error) {
 	query := `
 		SELECT uo.user_id
 		FROM loyalty.orders o
@@ -326,22 +1029,22 @@ func GetOrderOwner(orderNumber string) (*int64, error) {
 
 	orderInt, err := strconv.Atoi(orderNumber)
 	if err != nil {
-		config.Logger.Error("Failed to convert order number to int", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to convert order number to int", zap.Error(err))
 		return nil, err
 	}
 	var userID *int64
-	row, err := QueryRowWithRetry(context.Background(), DB, query, orderInt)
+	row, err := QueryRowWithRetry(ctx, DB, query, orderInt)
 	if err != nil {
-		config.Logger.Error("Failed to get order owner", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to get order owner", zap.Error(err))
 		return nil, err
 	}
 
 	err = row.Scan(&userID)
 	if errors.Is(err, sql.ErrNoRows) {
-		config.Logger.Info("Order not found")
+		config.LoggerFromContext(ctx).Info("Order not found")
 		return nil, nil
 	} else if err != nil {
-		config.Logger.Error("Failed to scan row", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to scan row", zap.Error(err))
 		return nil, err
 	}
 	return userID, nil
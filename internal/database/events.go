@@ -0,0 +1,159 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для записи и чтения событий пользователя
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// UserEventsChannel - канал Postgres NOTIFY, в который публикуются события пользователей.
+const UserEventsChannel = "user_events"
+
+// Типы событий пользователя.
+const (
+	UserEventOrder   = "order"   // изменился статус или начисление заказа
+	UserEventBalance = "balance" // изменился баланс
+)
+
+// UserEvent описывает событие пользователя.
+// В таком же виде событие передается в payload уведомления Postgres NOTIFY.
+type UserEvent struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// OrderEventPayload описывает данные события об изменении заказа.
+type OrderEventPayload struct {
+	Number  string  `json:"number"`
+	Status  string  `json:"status"`
+	Accrual float64 `json:"accrual"`
+}
+
+// recordUserEvent сохраняет событие пользователя и публикует его в канал UserEventsChannel.
+// Должна вызываться в транзакции, которая вносит изменение: уведомление доставляется только после фиксации транзакции.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса.
+//   - tx: транзакция.
+//   - userID: идентификатор пользователя.
+//   - eventType: тип события.
+//   - payload: данные события.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func recordUserEvent(ctx context.Context, tx ExecContexter, userID int64, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO loyalty.user_events (user_id, type, payload)
+			VALUES ($1, $2, $3::jsonb)
+			RETURNING id, user_id, type, payload, created_at
+		)
+		SELECT pg_notify($4, row_to_json(inserted)::text) FROM inserted`
+	if err = ExecQueryWithRetry(ctx, tx, query, userID, eventType, string(data), UserEventsChannel); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to record user event", zap.Error(err), zap.String("type", eventType))
+		return fmt.Errorf("failed to record user event: %w", err)
+	}
+	return nil
+}
+
+// recordBalanceEvent сохраняет событие с текущим балансом пользователя с учетом изменений транзакции.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса.
+//   - tx: транзакция.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func recordBalanceEvent(ctx context.Context, tx ExecContexter, userID int64) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO loyalty.user_events (user_id, type, payload)
+			SELECT ub.user_id, $2, json_build_object('current', ub.current_balance, 'withdrawn', ub.total_withdrawn)
+			FROM loyalty.user_bonuses ub
+			WHERE ub.user_id = $1
+			RETURNING id, user_id, type, payload, created_at
+		)
+		SELECT pg_notify($3, row_to_json(inserted)::text) FROM inserted`
+	if err := ExecQueryWithRetry(ctx, tx, query, userID, UserEventBalance, UserEventsChannel); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to record balance event", zap.Error(err), zap.Int64("user_id", userID))
+		return fmt.Errorf("failed to record balance event: %w", err)
+	}
+	return nil
+}
+
+// FetchUserEvents возвращает события пользователя с идентификатором больше afterID в порядке возникновения.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - afterID: идентификатор последнего полученного клиентом события.
+//   - limit: максимальное количество событий.
+//
+// Возвращает:
+//   - []UserEvent: события пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchUserEvents(ctx context.Context, userID, afterID int64, limit int) ([]UserEvent, error) {
+	query := `
+		SELECT id, user_id, type, payload, created_at
+		FROM loyalty.user_events
+		WHERE user_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, query, userID, afterID, limit)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user events", zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch user events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []UserEvent
+	for rows.Next() {
+		var event UserEvent
+		var payload []byte
+		if err = rows.Scan(&event.ID, &event.UserID, &event.Type, &payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user event: %w", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch user events: %w", err)
+	}
+	return events, nil
+}
+
+// DeleteExpiredUserEvents удаляет события пользователей старше срока хранения.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - ttl: срок хранения событий.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func DeleteExpiredUserEvents(ctx context.Context, ttl time.Duration) error {
+	query := `DELETE FROM loyalty.user_events WHERE created_at < NOW() - make_interval(secs => $1)`
+	if err := ExecQueryWithRetry(ctx, DB, query, ttl.Seconds()); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to delete expired user events", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции проверки доступности базы данных и версии ее схемы
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// SchemaVersion - версия схемы, которую создает PrepareDB. Увеличивается при каждом изменении схемы.
+const SchemaVersion = 4
+
+// RecordSchemaVersion создает таблицу версии схемы и сохраняет в ней SchemaVersion.
+// Версия не понижается, если схему уже подготовил более новый экземпляр сервиса.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при сохранении версии.
+func RecordSchemaVersion() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS loyalty.schema_version (
+			id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+			version INT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);
+		`
+	if _, err := DB.Exec(query); err != nil {
+		return fmt.Errorf("failed to create schema version table: %w", err)
+	}
+
+	_, err := DB.Exec(`
+		INSERT INTO loyalty.schema_version AS sv (version) VALUES ($1)
+		ON CONFLICT (id) DO UPDATE SET version = EXCLUDED.version, applied_at = NOW()
+		WHERE sv.version < EXCLUDED.version`, SchemaVersion)
+	if err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	config.Logger.Info("Schema version is recorded")
+	return nil
+}
+
+// Ping проверяет доступность базы данных.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//
+// Возвращает:
+//   - error: ошибка, если соединение не установлено или база данных недоступна.
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database is not initialized")
+	}
+	return DB.PingContext(ctx)
+}
+
+// CheckSchemaVersion проверяет, что версия схемы в базе данных совпадает с SchemaVersion.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//
+// Возвращает:
+//   - error: ошибка, если версия схемы отличается от ожидаемой или ее не удалось получить.
+func CheckSchemaVersion(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database is not initialized")
+	}
+
+	var version int
+	err := DB.QueryRowContext(ctx, `SELECT version FROM loyalty.schema_version`).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("schema version is not recorded")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if version != SchemaVersion {
+		return fmt.Errorf("schema version is %d, expected %d", version, SchemaVersion)
+	}
+	return nil
+}
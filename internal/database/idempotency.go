@@ -0,0 +1,148 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для работы с ключами идемпотентности запросов
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// IdempotencyRecord описывает сохраненный ключ идемпотентности.
+type IdempotencyRecord struct {
+	RequestHash  string // отпечаток запроса, с которым ключ был использован впервые
+	Completed    bool   // признак того, что ответ на запрос сохранен
+	StatusCode   int    // код сохраненного ответа
+	ContentType  string // Content-Type сохраненного ответа
+	ResponseBody []byte // тело сохраненного ответа
+}
+
+// ClaimIdempotencyKey резервирует ключ идемпотентности за запросом.
+// Если ключ свободен или срок его хранения истек, ключ закрепляется за запросом и возвращается claimed = true.
+// Иначе возвращается ранее сохраненная запись ключа.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - endpoint: операция, к которой относится ключ.
+//   - key: ключ идемпотентности.
+//   - requestHash: отпечаток запроса.
+//   - ttl: срок хранения ключа.
+//
+// Возвращает:
+//   - IdempotencyRecord: ранее сохраненная запись ключа, если ключ уже занят.
+//   - bool: true, если ключ закреплен за текущим запросом.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ClaimIdempotencyKey(ctx context.Context, userID int64, endpoint, key, requestHash string, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Вставка проходит для нового ключа и для ключа с истекшим сроком хранения
+	row, err := QueryRowWithRetry(ctx, DB, `
+		INSERT INTO loyalty.idempotency_keys AS ik (user_id, endpoint, key, request_hash)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, endpoint, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			completed = false,
+			status_code = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			created_at = CURRENT_TIMESTAMP
+		WHERE ik.created_at < CURRENT_TIMESTAMP - make_interval(secs => $5)
+		RETURNING ik.key`, userID, endpoint, key, requestHash, ttl.Seconds())
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to claim idempotency key", zap.Error(err))
+		return IdempotencyRecord{}, false, err
+	}
+	var claimedKey string
+	err = row.Scan(&claimedKey)
+	if err == nil {
+		return IdempotencyRecord{}, true, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		config.LoggerFromContext(ctx).Error("Failed to scan claimed idempotency key", zap.Error(err))
+		return IdempotencyRecord{}, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	row, err = QueryRowWithRetry(ctx, DB, `
+		SELECT request_hash, completed, COALESCE(status_code, 0), COALESCE(content_type, ''), COALESCE(response_body, ''::bytea)
+		FROM loyalty.idempotency_keys
+		WHERE user_id = $1 AND endpoint = $2 AND key = $3`, userID, endpoint, key)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to get idempotency key", zap.Error(err))
+		return IdempotencyRecord{}, false, err
+	}
+	var record IdempotencyRecord
+	if err = row.Scan(&record.RequestHash, &record.Completed, &record.StatusCode, &record.ContentType, &record.ResponseBody); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan idempotency key", zap.Error(err))
+		return IdempotencyRecord{}, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return record, false, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос, за которым закреплен ключ идемпотентности.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - endpoint: операция, к которой относится ключ.
+//   - key: ключ идемпотентности.
+//   - statusCode: код ответа.
+//   - contentType: Content-Type ответа.
+//   - body: тело ответа.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func CompleteIdempotencyKey(ctx context.Context, userID int64, endpoint, key string, statusCode int, contentType string, body []byte) error {
+	query := `
+		UPDATE loyalty.idempotency_keys
+		SET completed = true, status_code = $4, content_type = $5, response_body = $6
+		WHERE user_id = $1 AND endpoint = $2 AND key = $3`
+	err := ExecQueryWithRetry(ctx, DB, query, userID, endpoint, key, statusCode, contentType, body)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to complete idempotency key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ идемпотентности, чтобы запрос можно было повторить с тем же ключом.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - endpoint: операция, к которой относится ключ.
+//   - key: ключ идемпотентности.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ReleaseIdempotencyKey(ctx context.Context, userID int64, endpoint, key string) error {
+	query := `DELETE FROM loyalty.idempotency_keys WHERE user_id = $1 AND endpoint = $2 AND key = $3`
+	if err := ExecQueryWithRetry(ctx, DB, query, userID, endpoint, key); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to release idempotency key", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет ключи идемпотентности с истекшим сроком хранения.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - ttl: срок хранения ключа.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func DeleteExpiredIdempotencyKeys(ctx context.Context, ttl time.Duration) error {
+	query := `DELETE FROM loyalty.idempotency_keys WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`
+	if err := ExecQueryWithRetry(ctx, DB, query, ttl.Seconds()); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to delete expired idempotency keys", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для учета попыток входа пользователя
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// LoginFailures описывает состояние счётчика неудачных попыток входа для одного субъекта.
+type LoginFailures struct {
+	FailedCount      int           // количество неудачных попыток подряд
+	SinceLastFailure time.Duration // время, прошедшее с последней неудачной попытки
+	LockedFor        time.Duration // оставшееся время блокировки, 0 если блокировки нет
+}
+
+// GetLoginFailures возвращает состояние счётчика неудачных попыток входа для субъекта.
+// Если попыток не было, возвращает пустое состояние.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - subject: субъект проверки (логин или IP-адрес).
+//
+// Возвращает:
+//   - LoginFailures: состояние счётчика.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GetLoginFailures(ctx context.Context, subject string) (LoginFailures, error) {
+	query := `
+		SELECT
+			failed_count,
+			EXTRACT(EPOCH FROM (NOW() - last_failed_at)),
+			GREATEST(COALESCE(EXTRACT(EPOCH FROM (locked_until - NOW())), 0), 0)
+		FROM loyalty.login_failures
+		WHERE subject = $1;
+	`
+
+	row, err := QueryRowWithRetry(ctx, DB, query, subject)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to get login failures", zap.Error(err))
+		return LoginFailures{}, err
+	}
+
+	var failures LoginFailures
+	var sinceLast, lockedFor float64
+	err = row.Scan(&failures.FailedCount, &sinceLast, &lockedFor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return LoginFailures{}, nil
+	} else if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan login failures", zap.Error(err))
+		return LoginFailures{}, err
+	}
+
+	failures.SinceLastFailure = secondsToDuration(sinceLast)
+	failures.LockedFor = secondsToDuration(lockedFor)
+	return failures, nil
+}
+
+// RegisterLoginFailure увеличивает счётчик неудачных попыток входа для субъекта.
+// Если с последней неудачи прошло больше lockout, счётчик начинается заново.
+// При достижении maxAttempts субъект блокируется на время lockout.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - subject: субъект проверки (логин или IP-адрес).
+//   - maxAttempts: количество неудачных попыток до блокировки.
+//   - lockout: длительность блокировки.
+//
+// Возвращает:
+//   - LoginFailures: состояние счётчика после обновления.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func RegisterLoginFailure(ctx context.Context, subject string, maxAttempts int, lockout time.Duration) (LoginFailures, error) {
+	query := `
+		INSERT INTO loyalty.login_failures AS lf (subject, failed_count, last_failed_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (subject) DO UPDATE SET
+			failed_count = CASE
+				WHEN lf.last_failed_at < NOW() - make_interval(secs => $3) THEN 1
+				ELSE lf.failed_count + 1
+			END,
+			last_failed_at = NOW(),
+			locked_until = CASE
+				WHEN (CASE
+					WHEN lf.last_failed_at < NOW() - make_interval(secs => $3) THEN 1
+					ELSE lf.failed_count + 1
+				END) >= $2 THEN NOW() + make_interval(secs => $3)
+				ELSE NULL
+			END
+		RETURNING failed_count, GREATEST(COALESCE(EXTRACT(EPOCH FROM (locked_until - NOW())), 0), 0);
+	`
+
+	row, err := QueryRowWithRetry(ctx, DB, query, subject, maxAttempts, lockout.Seconds())
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to register login failure", zap.Error(err))
+		return LoginFailures{}, err
+	}
+
+	var failures LoginFailures
+	var lockedFor float64
+	if err = row.Scan(&failures.FailedCount, &lockedFor); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan login failures", zap.Error(err))
+		return LoginFailures{}, err
+	}
+	failures.LockedFor = secondsToDuration(lockedFor)
+	return failures, nil
+}
+
+// ResetLoginFailures сбрасывает счётчик неудачных попыток входа для субъекта.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - subject: субъект проверки (логин или IP-адрес).
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ResetLoginFailures(ctx context.Context, subject string) error {
+	query := `DELETE FROM loyalty.login_failures WHERE subject = $1`
+	if err := ExecQueryWithRetry(ctx, DB, query, subject); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to reset login failures", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RecordLoginAttempt сохраняет попытку входа в историю.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userName: логин, под которым выполнялся вход.
+//   - ip: IP-адрес клиента.
+//   - userAgent: User-Agent клиента.
+//   - success: признак успешного входа.
+//   - reason: причина отказа, пустая строка для успешного входа.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func RecordLoginAttempt(ctx context.Context, userName, ip, userAgent string, success bool, reason string) error {
+	query := `
+		INSERT INTO loyalty.login_history (user_name, ip, user_agent, success, reason)
+		VALUES ($1, $2, $3, $4, $5)`
+	if err := ExecQueryWithRetry(ctx, DB, query, userName, ip, userAgent, success, reason); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to record login attempt", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// secondsToDuration переводит количество секунд, полученное из базы данных, в time.Duration.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,346 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для учета партий баллов и их сгорания
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// Источники партий баллов.
+const (
+	LotSourceAccrual    = "accrual"    // начисление за заказ, сгорает
+	LotSourceTierBonus  = "tier_bonus" // бонус по множителю уровня за заказ, сгорает вместе с начислением
+	LotSourceAdjustment = "adjustment" // ручное начисление администратором
+	LotSourceReversal   = "reversal"   // возврат списанных баллов
+	LotSourceTransfer   = "transfer"   // перевод от другого пользователя
+	LotSourceCampaign   = "campaign"   // бонус по маркетинговой кампании
+	LotSourceReferral   = "referral"   // бонус реферальной программы
+)
+
+// balanceTolerance - допустимая погрешность сравнения сумм баллов, хранящихся в FLOAT8.
+const balanceTolerance = 1e-6
+
+// ExpiringPoints описывает баллы, которые сгорят в указанную дату.
+type ExpiringPoints struct {
+	Amount    float64
+	ExpiresAt time.Time
+}
+
+// BackfillAccrualLots заполняет таблицу партий баллов по уже существующим начислениям, корректировкам и возвратам.
+// Остаток партий рассчитывается так, как если бы все прошлые списания расходовали самые старые начисления.
+// Выполняется только если таблица партий пуста.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func BackfillAccrualLots() error {
+	query := `
+		INSERT INTO loyalty.accrual_lots (user_id, order_id, source, amount, remaining, created_at)
+		SELECT user_id, order_id, source, amount, GREATEST(0, LEAST(amount, cum - debits)), created_at
+		FROM (
+			SELECT
+				c.*,
+				SUM(c.amount) OVER (PARTITION BY c.user_id ORDER BY c.source <> 'accrual', c.created_at, c.order_id) AS cum,
+				COALESCE(d.debits, 0) AS debits
+			FROM (
+				SELECT uo.user_id, b.order_id, 'accrual' AS source, b.accrual AS amount, COALESCE(b.created_at, NOW()) AS created_at
+				FROM loyalty.bonuses b
+				JOIN loyalty.orders o ON o.id = b.order_id
+				JOIN loyalty.user_orders uo ON uo.order_id = b.order_id
+				JOIN loyalty.status_dictionary sd ON sd.id = o.status
+				WHERE sd.status_name = 'PROCESSED' AND b.accrual > 0
+				UNION ALL
+				SELECT user_id, NULL, 'adjustment', amount, created_at
+				FROM loyalty.balance_adjustments
+				WHERE amount > 0
+				UNION ALL
+				SELECT user_id, order_id, 'reversal', amount, created_at
+				FROM loyalty.withdrawal_reversals
+			) c
+			LEFT JOIN (
+				SELECT user_id, SUM(debit) AS debits
+				FROM (
+					SELECT uo.user_id, b.withdrawn AS debit
+					FROM loyalty.bonuses b
+					JOIN loyalty.orders o ON o.id = b.order_id
+					JOIN loyalty.user_orders uo ON uo.order_id = b.order_id
+					JOIN loyalty.status_dictionary sd ON sd.id = o.status
+					WHERE sd.status_name != 'INVALID' AND b.withdrawn > 0
+					UNION ALL
+					SELECT user_id, -amount
+					FROM loyalty.balance_adjustments
+					WHERE amount < 0
+				) all_debits
+				GROUP BY user_id
+			) d ON d.user_id = c.user_id
+		) lots
+		WHERE NOT EXISTS (SELECT 1 FROM loyalty.accrual_lots);
+	`
+
+	if _, err := DB.Exec(query); err != nil {
+		config.Logger.Error("Failed to backfill accrual lots", zap.Error(err))
+		return fmt.Errorf("failed to backfill accrual lots: %w", err)
+	}
+	config.Logger.Info("Accrual lots are ready")
+	return nil
+}
+
+// addLot добавляет партию баллов в рамках транзакции, в которой баллы поступают на счет.
+// Повторное начисление за тот же заказ партию не дублирует.
+func addLot(ctx context.Context, tx ExecContexter, userID int64, orderID *int64, source string, amount float64) error {
+	err := ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.accrual_lots (user_id, order_id, source, amount, remaining)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (order_id) WHERE source = 'accrual' DO NOTHING`,
+		userID, orderID, source, amount)
+	if err != nil {
+		return fmt.Errorf("failed to add accrual lot: %w", err)
+	}
+	return nil
+}
+
+// lockBalanceForDebit блокирует пользователя в рамках транзакции и проверяет, что его текущий баланс покрывает списание.
+// Вызывается всеми операциями, уменьшающими баланс, до записи самой операции, поэтому параллельные списания,
+// переводы и корректировки одного пользователя выполняются по очереди и не уводят баланс в минус.
+func lockBalanceForDebit(ctx context.Context, tx *sql.Tx, userID int64, amount float64) error {
+	if err := lockUser(ctx, tx, userID); err != nil {
+		return err
+	}
+	return checkBalanceCovers(ctx, tx, userID, amount)
+}
+
+// lockUser блокирует строку пользователя до конца транзакции.
+func lockUser(ctx context.Context, tx *sql.Tx, userID int64) error {
+	err := ExecQueryWithRetry(ctx, tx, `SELECT id FROM loyalty.users WHERE id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return nil
+}
+
+// checkBalanceCovers проверяет, что текущий баланс пользователя с учетом изменений транзакции не меньше amount.
+// Возвращает cstmerr.ErrorInsufficientBalance, если баланса не хватает.
+func checkBalanceCovers(ctx context.Context, tx *sql.Tx, userID int64, amount float64) error {
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT COALESCE((SELECT current_balance FROM loyalty.user_bonuses WHERE user_id = $1), 0)`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user balance: %w", err)
+	}
+	var currentBalance float64
+	if err = row.Scan(&currentBalance); err != nil {
+		return fmt.Errorf("failed to scan user balance: %w", err)
+	}
+	if currentBalance+balanceTolerance < amount {
+		return cstmerr.ErrorInsufficientBalance
+	}
+	return nil
+}
+
+// consumeLots уменьшает остаток партий баллов пользователя на сумму списания в рамках транзакции списания.
+// Первыми расходуются самые старые начисления за заказы, затем несгораемые партии.
+// Если остатка партий не хватает, возвращает cstmerr.ErrorInsufficientBalance и ничего не списывает.
+func consumeLots(ctx context.Context, tx *sql.Tx, userID int64, amount float64) error {
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT COALESCE(SUM(remaining), 0)
+		FROM (SELECT remaining FROM loyalty.accrual_lots WHERE user_id = $1 AND remaining > 0 FOR UPDATE) l`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock accrual lots: %w", err)
+	}
+	var available float64
+	if err = row.Scan(&available); err != nil {
+		return fmt.Errorf("failed to scan accrual lots: %w", err)
+	}
+	if available+balanceTolerance < amount {
+		config.LoggerFromContext(ctx).Warn("Accrual lots do not cover debit",
+			zap.Int64("user_id", userID), zap.Float64("available", available), zap.Float64("amount", amount))
+		return cstmerr.ErrorInsufficientBalance
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		WITH ordered AS (
+			SELECT id, remaining,
+				SUM(remaining) OVER (ORDER BY source NOT IN ('accrual', 'tier_bonus'), created_at, id) - remaining AS consumed_before
+			FROM loyalty.accrual_lots
+			WHERE user_id = $1 AND remaining > 0
+		)
+		UPDATE loyalty.accrual_lots l
+		SET remaining = GREATEST(0, l.remaining - LEAST(o.remaining, $2 - o.consumed_before))
+		FROM ordered o
+		WHERE l.id = o.id AND o.consumed_before < $2`, userID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to consume accrual lots: %w", err)
+	}
+	return nil
+}
+
+// deleteOrderLot удаляет партии начисления и бонусов за заказ, если заказ перестал быть обработанным.
+func deleteOrderLot(ctx context.Context, tx ExecContexter, orderID int64) error {
+	err := ExecQueryWithRetry(ctx, tx, `
+		DELETE FROM loyalty.accrual_lots WHERE order_id = $1 AND source IN ('accrual', 'tier_bonus', 'campaign')`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to delete accrual lot: %w", err)
+	}
+	return nil
+}
+
+// restoreOrderBonusLots восстанавливает партии бонусов по уровню и кампаниям для заказа, повторно
+// переведенного в PROCESSED. Сами бонусы сохраняются при отмене обработки заказа и повторно не начисляются,
+// а их партии удаляются вместе с партией начисления.
+func restoreOrderBonusLots(ctx context.Context, tx ExecContexter, orderID int64) error {
+	err := ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.accrual_lots (user_id, order_id, source, amount, remaining)
+		SELECT user_id, order_id, 'tier_bonus', amount, amount
+		FROM loyalty.tier_bonuses
+		WHERE order_id = $1
+			AND NOT EXISTS (SELECT 1 FROM loyalty.accrual_lots WHERE order_id = $1 AND source = 'tier_bonus')
+		UNION ALL
+		SELECT user_id, order_id, 'campaign', amount, amount
+		FROM loyalty.campaign_bonuses
+		WHERE order_id = $1
+			AND NOT EXISTS (SELECT 1 FROM loyalty.accrual_lots WHERE order_id = $1 AND source = 'campaign')`,
+		orderID)
+	if err != nil {
+		return fmt.Errorf("failed to restore bonus lots: %w", err)
+	}
+	return nil
+}
+
+// ExpirePoints списывает остаток партий начислений, созданных больше months месяцев назад,
+// и сохраняет записи о сгорании. Сгорающая сумма не может превысить текущий баланс пользователя.
+// Партии, заблокированные параллельным списанием, пропускаются до следующего запуска.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - months: срок жизни начисленных баллов в месяцах.
+//
+// Возвращает:
+//   - int64: количество записей о сгорании.
+//   - float64: общая сумма сгоревших баллов.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ExpirePoints(ctx context.Context, months int) (int64, float64, error) {
+	query := `
+		WITH locked AS (
+			SELECT id, user_id, remaining
+			FROM loyalty.accrual_lots
+			WHERE source IN ('accrual', 'tier_bonus') AND remaining > 0 AND created_at <= NOW() - make_interval(months => $1)
+			FOR UPDATE SKIP LOCKED
+		), capped AS (
+			SELECT l.id, l.user_id,
+				GREATEST(0, LEAST(l.remaining,
+					ub.current_balance - (SUM(l.remaining) OVER (PARTITION BY l.user_id ORDER BY l.id) - l.remaining))) AS amount
+			FROM locked l
+			JOIN loyalty.user_bonuses ub ON ub.user_id = l.user_id
+		), updated AS (
+			UPDATE loyalty.accrual_lots a SET remaining = 0 FROM capped c WHERE a.id = c.id
+		), inserted AS (
+			INSERT INTO loyalty.point_expirations (user_id, lot_id, amount)
+			SELECT user_id, id, amount FROM capped WHERE amount > 0
+			RETURNING user_id, amount
+		)
+		SELECT user_id, COUNT(*), SUM(amount) FROM inserted GROUP BY user_id;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return 0, 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, query, months)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to expire points", zap.Error(err))
+		return 0, 0, fmt.Errorf("failed to expire points: %w", err)
+	}
+	var userIDs []int64
+	var count int64
+	var total float64
+	for rows.Next() {
+		var userID, userCount int64
+		var userTotal float64
+		if err = rows.Scan(&userID, &userCount, &userTotal); err != nil {
+			rows.Close()
+			config.LoggerFromContext(ctx).Error("Failed to scan expired points", zap.Error(err))
+			return 0, 0, fmt.Errorf("failed to expire points: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+		count += userCount
+		total += userTotal
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to expire points: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if err = recordBalanceEvent(ctx, tx, userID); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return count, total, nil
+}
+
+// FetchExpiringPoints возвращает баллы пользователя, которые сгорят в ближайшее время, сгруппированные по дате сгорания.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - months: срок жизни начисленных баллов в месяцах.
+//   - window: период, за который показываются сгорающие баллы.
+//
+// Возвращает:
+//   - []ExpiringPoints: сгорающие баллы по датам, от ближайших к дальним.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchExpiringPoints(ctx context.Context, userID int64, months int, window time.Duration) ([]ExpiringPoints, error) {
+	query := `
+		SELECT SUM(remaining), date_trunc('day', created_at + make_interval(months => $2)) AS expires_at
+		FROM loyalty.accrual_lots
+		WHERE user_id = $1 AND source IN ('accrual', 'tier_bonus') AND remaining > 0
+			AND created_at + make_interval(months => $2) <= NOW() + make_interval(secs => $3)
+		GROUP BY expires_at
+		ORDER BY expires_at;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, query, userID, months, window.Seconds())
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch expiring points", zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch expiring points: %w", err)
+	}
+	defer rows.Close()
+
+	var points []ExpiringPoints
+	for rows.Next() {
+		var p ExpiringPoints
+		if err := rows.Scan(&p.Amount, &p.ExpiresAt); err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to scan expiring points", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan expiring points: %w", err)
+		}
+		points = append(points, p)
+	}
+	if rows.Err() != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch expiring points", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("failed to fetch expiring points: %w", rows.Err())
+	}
+	return points, nil
+}
@@ -0,0 +1,237 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для работы с API-ключами мерчантов
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+type MerchantAPIKey struct {
+	ID           int64
+	MerchantName string
+	KeyPrefix    string
+	KeyHash      string
+	Scopes       []string
+	CreatedBy    int64
+	CreatedAt    time.Time
+	LastUsedAt   *time.Time
+	RevokedAt    *time.Time
+}
+
+// CreateMerchantAPIKey сохраняет API-ключ мерчанта и запись в журнале действий администратора.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - key: API-ключ, поля ID и CreatedAt заполняются базой данных.
+//
+// Возвращает:
+//   - MerchantAPIKey: сохранённый ключ.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func CreateMerchantAPIKey(ctx context.Context, key MerchantAPIKey) (MerchantAPIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return MerchantAPIKey{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.merchant_api_keys (merchant_name, key_prefix, key_hash, scopes, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		key.MerchantName, key.KeyPrefix, key.KeyHash, pq.Array(key.Scopes), key.CreatedBy)
+	if err != nil {
+		return MerchantAPIKey{}, fmt.Errorf("failed to insert API key: %w", err)
+	}
+	if err = row.Scan(&key.ID, &key.CreatedAt); err != nil {
+		return MerchantAPIKey{}, fmt.Errorf("failed to scan API key: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: key.CreatedBy,
+		Action:  "merchant_key_create",
+		Details: map[string]interface{}{
+			"key_id":        key.ID,
+			"merchant_name": key.MerchantName,
+			"key_prefix":    key.KeyPrefix,
+			"scopes":        key.Scopes,
+		},
+	})
+	if err != nil {
+		return MerchantAPIKey{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return MerchantAPIKey{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Merchant API key created", zap.Int64("key_id", key.ID), zap.String("merchant", key.MerchantName))
+	return key, nil
+}
+
+// GetActiveMerchantAPIKey возвращает неотозванный API-ключ по его хэшу и отмечает время его использования.
+// Если ключ не найден или отозван, возвращает ошибку cstmerr.ErrorInvalidAPIKey.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - keyHash: SHA-256 хэш ключа.
+//
+// Возвращает:
+//   - MerchantAPIKey: API-ключ.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GetActiveMerchantAPIKey(ctx context.Context, keyHash string) (MerchantAPIKey, error) {
+	query := `
+		UPDATE loyalty.merchant_api_keys
+		SET last_used_at = NOW()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING id, merchant_name, key_prefix, scopes, created_by, created_at, last_used_at`
+
+	row, err := QueryRowWithRetry(ctx, DB, query, keyHash)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to get API key", zap.Error(err))
+		return MerchantAPIKey{}, err
+	}
+
+	key := MerchantAPIKey{KeyHash: keyHash}
+	err = row.Scan(&key.ID, &key.MerchantName, &key.KeyPrefix, pq.Array(&key.Scopes), &key.CreatedBy, &key.CreatedAt, &key.LastUsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return MerchantAPIKey{}, cstmerr.ErrorInvalidAPIKey
+	} else if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan API key", zap.Error(err))
+		return MerchantAPIKey{}, err
+	}
+	return key, nil
+}
+
+// merchantExists проверяет, что у мерчанта есть неотозванный API-ключ.
+func merchantExists(ctx context.Context, q QueryRowContexter, merchantName string) (bool, error) {
+	row, err := QueryRowWithRetry(ctx, q, `
+		SELECT EXISTS (SELECT 1 FROM loyalty.merchant_api_keys WHERE merchant_name = $1 AND revoked_at IS NULL)`, merchantName)
+	if err != nil {
+		return false, fmt.Errorf("failed to check merchant: %w", err)
+	}
+	var exists bool
+	if err = row.Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to scan merchant: %w", err)
+	}
+	return exists, nil
+}
+
+// ListMerchantAPIKeys возвращает список всех API-ключей мерчантов, включая отозванные.
+//
+// Возвращает:
+//   - []MerchantAPIKey: список ключей, от новых к старым.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ListMerchantAPIKeys(ctx context.Context) ([]MerchantAPIKey, error) {
+	query := `
+		SELECT id, merchant_name, key_prefix, scopes, created_by, created_at, last_used_at, revoked_at
+		FROM loyalty.merchant_api_keys
+		ORDER BY created_at DESC, id DESC;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, query)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to list API keys", zap.Error(err))
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []MerchantAPIKey
+	for rows.Next() {
+		var key MerchantAPIKey
+		if err := rows.Scan(&key.ID, &key.MerchantName, &key.KeyPrefix, pq.Array(&key.Scopes), &key.CreatedBy, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to scan API key", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if rows.Err() != nil {
+		config.LoggerFromContext(ctx).Error("Failed to list API keys", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("failed to list API keys: %w", rows.Err())
+	}
+
+	return keys, nil
+}
+
+// RevokeMerchantAPIKey отзывает API-ключ мерчанта и сохраняет запись в журнале действий администратора.
+// Если ключ не найден или уже отозван, возвращает ошибку cstmerr.ErrorAPIKeyNotFound.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - keyID: идентификатор ключа.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func RevokeMerchantAPIKey(ctx context.Context, adminID, keyID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		UPDATE loyalty.merchant_api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING merchant_name`, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	var merchantName string
+	if err = row.Scan(&merchantName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorAPIKeyNotFound
+			return err
+		}
+		return fmt.Errorf("failed to scan API key: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: adminID,
+		Action:  "merchant_key_revoke",
+		Details: map[string]interface{}{
+			"key_id":        keyID,
+			"merchant_name": merchantName,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Merchant API key revoked", zap.Int64("key_id", keyID), zap.Int64("admin_id", adminID))
+	return nil
+}
@@ -4,12 +4,18 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 
 	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
 )
 
 type Order struct {
@@ -24,23 +30,24 @@ type Order struct {
 // В случае успеха, возвращает идентификатор созданного заказа.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //   - orderNumber: номер заказа.
 //
 // Возвращает:
 //   - error: ошибка, если произошла ошибка при создании заказа.
-func CreateOrder(userID int64, orderNumber string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func CreateOrder(ctx context.Context, userID int64, orderNumber string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
-		config.Logger.Error("Failed to start transaction", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				config.Logger.Error("Failed to rollback transaction", zap.Error(rbErr))
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
 			}
 		}
 	}()
@@ -70,69 +77,157 @@ func CreateOrder(userID int64, orderNumber string) error {
 	return nil
 }
 
-// GetUserOrders возвращает информацию о заказах пользователя
+// OrdersFilter описывает фильтры и параметры постраничной выборки заказов пользователя.
+// Постраничная выборка строится по ключу (created_at, id): в выборку попадают заказы,
+// идущие в заданном порядке строго после заказа-курсора.
+type OrdersFilter struct {
+	Statuses    []string   // статусы заказов, пустой список - любые статусы
+	From        *time.Time // заказы, загруженные не раньше указанного времени
+	To          *time.Time // заказы, загруженные раньше указанного времени
+	AfterTime   *time.Time // время загрузки заказа-курсора
+	AfterNumber int64      // номер заказа-курсора
+	Ascending   bool       // сортировка от старых к новым
+	Limit       int        // максимальное количество заказов, 0 - без ограничения
+}
+
+// GetUserOrders возвращает информацию о всех заказах пользователя
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //
 // Возвращает:
 //   - []Order: информация о заказах пользователя.
 //   - error: ошибка, если произошла ошибка при получении информации о заказах пользователя.
-func GetUserOrders(userID int64) ([]Order, error) {
-	query := `
-		SELECT o.id, sd.status_name, COALESCE(b.accrual,0) , o.created_at
-		FROM loyalty.orders o
-		JOIN loyalty.user_orders uo ON o.id = uo.order_id
-		JOIN loyalty.status_dictionary sd ON o.status = sd.id
-		LEFT JOIN loyalty.bonuses b ON b.order_id = o.id
-		WHERE uo.user_id = $1
-		ORDER BY o.created_at DESC;
-	`
+func GetUserOrders(ctx context.Context, userID int64) ([]Order, error) {
+	return FetchUserOrders(ctx, userID, OrdersFilter{})
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// FetchUserOrders возвращает информацию о заказах пользователя с учетом фильтров и постраничной выборки
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - filter: фильтры и параметры постраничной выборки.
+//
+// Возвращает:
+//   - []Order: информация о заказах пользователя.
+//   - error: ошибка, если произошла ошибка при получении информации о заказах пользователя.
+func FetchUserOrders(ctx context.Context, userID int64, filter OrdersFilter) ([]Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	rows, err := QueryRowsWithRetry(ctx, DB, query, userID)
+
+	var orders []Order
+	err := StreamUserOrders(ctx, userID, filter, func(order Order) error {
+		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// StreamUserOrders читает заказы пользователя по фильтру и передает их в fn по одному,
+// не загружая всю выборку в память. Если fn возвращает ошибку, чтение прекращается.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса.
+//   - userID: идентификатор пользователя.
+//   - filter: фильтры и параметры постраничной выборки.
+//   - fn: обработчик заказа.
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или ошибка fn.
+func StreamUserOrders(ctx context.Context, userID int64, filter OrdersFilter, fn func(Order) error) error {
+	query, args := buildUserOrdersQuery(userID, filter)
+
+	rows, err := QueryRowsWithRetry(ctx, DB, query, args...)
 	if err != nil {
-		config.Logger.Error("Failed to fetch user orders", zap.Error(err))
-		return nil, fmt.Errorf("failed to fetch user orders: %w", err)
+		config.LoggerFromContext(ctx).Error("Failed to fetch user orders", zap.Error(err))
+		return fmt.Errorf("failed to fetch user orders: %w", err)
 	}
 	defer rows.Close()
 
-	var orders []Order
 	for rows.Next() {
 		var order Order
 		if err := rows.Scan(&order.Number, &order.Status, &order.Accrual, &order.UploadedAt); err != nil {
-			config.Logger.Error("Failed to scan order", zap.Error(err))
-			return nil, fmt.Errorf("failed to scan order: %w", err)
+			config.LoggerFromContext(ctx).Error("Failed to scan order", zap.Error(err))
+			return fmt.Errorf("failed to scan order: %w", err)
+		}
+		if err := fn(order); err != nil {
+			return err
 		}
-		orders = append(orders, order)
 	}
 	if rows.Err() != nil {
-		config.Logger.Error("Failed to fetch user orders", zap.Error(rows.Err()))
-		return nil, fmt.Errorf("failed to fetch user orders: %w", rows.Err())
+		config.LoggerFromContext(ctx).Error("Failed to fetch user orders", zap.Error(rows.Err()))
+		return fmt.Errorf("failed to fetch user orders: %w", rows.Err())
 	}
 
-	return orders, nil
+	return nil
+}
+
+// buildUserOrdersQuery собирает SQL-запрос и его аргументы для выборки заказов пользователя по фильтру.
+func buildUserOrdersQuery(userID int64, filter OrdersFilter) (string, []interface{}) {
+	args := []interface{}{userID}
+	conditions := []string{"uo.user_id = $1"}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "sd.status_name = ANY("+addArg(pq.Array(filter.Statuses))+")")
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "o.created_at >= "+addArg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "o.created_at < "+addArg(*filter.To))
+	}
+
+	order, comparison := "DESC", "<"
+	if filter.Ascending {
+		order, comparison = "ASC", ">"
+	}
+	if filter.AfterTime != nil {
+		conditions = append(conditions, fmt.Sprintf("(o.created_at, o.id) %s (%s, %s)",
+			comparison, addArg(*filter.AfterTime), addArg(filter.AfterNumber)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT o.id, sd.status_name, COALESCE(b.accrual,0) , o.created_at
+		FROM loyalty.orders o
+		JOIN loyalty.user_orders uo ON o.id = uo.order_id
+		JOIN loyalty.status_dictionary sd ON o.status = sd.id
+		LEFT JOIN loyalty.bonuses b ON b.order_id = o.id
+		WHERE %s
+		ORDER BY o.created_at %s, o.id %s`, strings.Join(conditions, " AND "), order, order)
+	if filter.Limit > 0 {
+		query += " LIMIT " + addArg(filter.Limit)
+	}
+	return query, args
 }
 
 // GetOrdersByStatus возвращает информацию о заказах с указанным статусом
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - status: статус.
 //
 // Возвращает:
 //   - []Order: информация о заказах с указанным статусом.
 //   - error: ошибка, если произошла ошибка при получении информации о заказах с указанным статусом.
-func GetOrdersByStatus() ([]Order, error) {
+func GetOrdersByStatus(ctx context.Context) ([]Order, error) {
 	var orders []Order
 	query := `
 			SELECT o.id, sd.status_name
 			FROM loyalty.orders o
 			JOIN loyalty.status_dictionary sd ON o.status = sd.id
 			WHERE status_name IN ('NEW', 'PROCESSING');`
-	rows, err := QueryRowsWithRetry(context.Background(), DB, query)
+	rows, err := QueryRowsWithRetry(ctx, DB, query)
 	if err != nil {
-		config.Logger.Error("Failed to get orders by status", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to get orders by status", zap.Error(err))
 		return nil, fmt.Errorf("failed to get orders by status: %w", err)
 	}
 	defer rows.Close()
@@ -140,13 +235,13 @@ func GetOrdersByStatus() ([]Order, error) {
 	for rows.Next() {
 		var order Order
 		if err := rows.Scan(&order.Number, &order.Status); err != nil {
-			config.Logger.Error("Failed to scan order", zap.Error(err))
+			config.LoggerFromContext(ctx).Error("Failed to scan order", zap.Error(err))
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
 		orders = append(orders, order)
 	}
 	if rows.Err() != nil {
-		config.Logger.Error("Failed to get orders by status", zap.Error(rows.Err()))
+		config.LoggerFromContext(ctx).Error("Failed to get orders by status", zap.Error(rows.Err()))
 		return nil, fmt.Errorf("failed to get orders by status: %w", rows.Err())
 	}
 
@@ -154,33 +249,63 @@ func GetOrdersByStatus() ([]Order, error) {
 }
 
 // UpdateOrder обновляет статус заказа
+// Если статус или начисление изменились, в той же транзакции записывает событие пользователя.
 // Если произошла ошибка при выполнении запроса, программа завершается с кодом ошибки.
 // В случае успеха, возвращается nil.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - orderNumber: номер заказа.
 //   - status: статус.
 //   - accrual: сумма начисленных бонусов.
 //
 // Возвращает:
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func UpdateOrder(orderNumber, status string, accrual float64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func UpdateOrder(ctx context.Context, orderNumber, status string, accrual float64) error {
+	orderID, err := strconv.ParseInt(orderNumber, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse order number: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	tx, err := DB.BeginTx(ctx, nil)
 
 	if err != nil {
-		config.Logger.Error("Failed to start transaction", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				config.Logger.Error("Failed to rollback transaction", zap.Error(rbErr))
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
 			}
 		}
 	}()
 
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT sd.status_name, COALESCE(b.accrual, 0), uo.user_id
+		FROM loyalty.orders o
+		JOIN loyalty.status_dictionary sd ON sd.id = o.status
+		LEFT JOIN loyalty.bonuses b ON b.order_id = o.id
+		LEFT JOIN loyalty.user_orders uo ON uo.order_id = o.id
+		WHERE o.id = $1
+		LIMIT 1
+		FOR UPDATE OF o`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	var previousStatus string
+	var previousAccrual float64
+	var userID *int64
+	if err = row.Scan(&previousStatus, &previousAccrual, &userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrOrderNotFound
+			return err
+		}
+		return fmt.Errorf("failed to scan order: %w", err)
+	}
+
 	query := `
 		UPDATE loyalty.orders 
 		SET status = (
@@ -193,7 +318,7 @@ func UpdateOrder(orderNumber, status string, accrual float64) error {
 	err = ExecQueryWithRetry(ctx, tx, query, status, orderNumber)
 
 	if err != nil {
-		config.Logger.Error("Failed to update order", zap.Error(err), zap.String("query", query))
+		config.LoggerFromContext(ctx).Error("Failed to update order", zap.Error(err), zap.String("query", query))
 		return fmt.Errorf("failed to update order: %w", err)
 	}
 
@@ -204,12 +329,66 @@ func UpdateOrder(orderNumber, status string, accrual float64) error {
 		DO UPDATE SET accrual = EXCLUDED.accrual`
 	err = ExecQueryWithRetry(ctx, tx, query, orderNumber, accrual)
 	if err != nil {
-		config.Logger.Error("Failed to update order", zap.Error(err), zap.String("query", query))
+		config.LoggerFromContext(ctx).Error("Failed to update order", zap.Error(err), zap.String("query", query))
 		return fmt.Errorf("failed to update order: %w", err)
 	}
 
+	if userID == nil {
+		err = fmt.Errorf("order %d has no owner", orderID)
+		return err
+	}
+
+	// Начисление за обработанный заказ образует партию баллов, которая сгорает по истечении срока.
+	// Бонус по множителю рассчитывается по уровню до этого начисления, затем уровень пересчитывается.
+	// Бонусы по кампаниям начисляются за заказы, загруженные в период кампании
+	if status == "PROCESSED" && accrual > 0 {
+		if err = addLot(ctx, tx, *userID, &orderID, LotSourceAccrual, accrual); err != nil {
+			return err
+		}
+		if err = restoreOrderBonusLots(ctx, tx, orderID); err != nil {
+			return err
+		}
+		if err = applyTierBonus(ctx, tx, *userID, orderID, accrual); err != nil {
+			return err
+		}
+		if err = applyCampaignBonuses(ctx, tx, *userID, orderID, accrual); err != nil {
+			return err
+		}
+		if err = upgradeTier(ctx, tx, *userID); err != nil {
+			return err
+		}
+	}
+
+	// Реферальные бонусы начисляются за первый заказ приглашенного пользователя, дошедший до PROCESSED
+	if status == "PROCESSED" && previousStatus != "PROCESSED" {
+		if err = rewardReferral(ctx, tx, *userID, orderID); err != nil {
+			return err
+		}
+	}
+
+	// Агент опрашивает заказы повторно, событие публикуется только при фактическом изменении
+	if previousStatus != status || previousAccrual != accrual {
+		payload := OrderEventPayload{Number: orderNumber, Status: status, Accrual: accrual}
+		if err = recordUserEvent(ctx, tx, *userID, UserEventOrder, payload); err != nil {
+			return err
+		}
+		if status == "PROCESSED" || previousStatus == "PROCESSED" {
+			if err = recordBalanceEvent(ctx, tx, *userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Внешним системам сообщается только о переходе заказа в PROCESSED
+	if status == "PROCESSED" && previousStatus != "PROCESSED" {
+		payload := OrderEventPayload{Number: orderNumber, Status: status, Accrual: accrual}
+		if err = recordWebhookEvent(ctx, tx, WebhookEventOrderProcessed, *userID, payload); err != nil {
+			return err
+		}
+	}
+
 	if err = tx.Commit(); err != nil {
-		config.Logger.Error("Failed to commit transaction", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to commit transaction", zap.Error(err))
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
@@ -0,0 +1,124 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для работы с токенами сброса пароля
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// CreatePasswordResetToken сохраняет хэш токена сброса пароля для пользователя.
+// Ранее выданные и ещё не использованные токены пользователя аннулируются.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - tokenHash: SHA-256 хэш токена.
+//   - ttl: время жизни токена.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func CreatePasswordResetToken(ctx context.Context, userID int64, tokenHash string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		UPDATE loyalty.password_reset_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke previous reset tokens: %w", err)
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.password_reset_tokens (token_hash, user_id, expires_at)
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))`, tokenHash, userID, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ResetPasswordByToken погашает токен сброса пароля и устанавливает пользователю новый пароль.
+// Версия токенов пользователя увеличивается, поэтому все ранее выданные токены становятся недействительными.
+// Если токен не найден, уже использован или истёк, возвращает ошибку cstmerr.ErrorInvalidResetToken.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - tokenHash: SHA-256 хэш токена.
+//   - passwordHash: новый хэш пароля.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ResetPasswordByToken(ctx context.Context, tokenHash, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		UPDATE loyalty.password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to redeem reset token: %w", err)
+	}
+	var userID int64
+	if err = row.Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorInvalidResetToken
+			return err
+		}
+		return fmt.Errorf("failed to scan user ID: %w", err)
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		UPDATE loyalty.users
+		SET password_hash = $1, token_version = token_version + 1
+		WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("User password reset", zap.Int64("user_id", userID))
+	return nil
+}
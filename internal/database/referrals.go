@@ -0,0 +1,237 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции реферальной программы: реферальные коды, приглашения и начисление реферальных бонусов
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// ReferralInvite описывает приглашение, с которым регистрируется пользователь.
+type ReferralInvite struct {
+	Code          string  // реферальный код пригласившего пользователя
+	ReferrerBonus float64 // бонус пригласившему пользователю
+	RefereeBonus  float64 // бонус приглашенному пользователю
+	Limit         int     // максимальное число приглашенных одним пользователем, 0 - без ограничения
+	ClientIP      string  // IP-адрес, с которого регистрируется приглашенный
+	UserAgent     string  // User-Agent клиента, с которого регистрируется приглашенный
+}
+
+// Referral описывает пользователя, приглашенного по реферальному коду, и бонус пригласившего за него.
+type Referral struct {
+	ID           int64
+	RefereeLogin string
+	Bonus        float64 // бонус пригласившему, зафиксированный при регистрации
+	RegisteredAt time.Time
+	RewardedAt   *time.Time // nil, пока первый заказ приглашенного не обработан
+}
+
+// insertReferralCode сохраняет реферальный код пользователя в рамках транзакции.
+// Если у пользователя уже есть код или код занят другим пользователем, ничего не делает.
+func insertReferralCode(ctx context.Context, tx ExecContexter, userID int64, code string) error {
+	err := ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.referral_codes (user_id, code)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, userID, code)
+	if err != nil {
+		return fmt.Errorf("failed to insert referral code: %w", err)
+	}
+	return nil
+}
+
+// createReferral сохраняет приглашение нового пользователя в рамках транзакции регистрации.
+// Код пригласившего блокируется, поэтому параллельные регистрации не превысят лимит приглашений.
+// Приглашение самого себя отклоняется: если пригласивший успешно входил с того же IP-адреса и того же
+// клиента, с которых идет регистрация, возвращается ошибка cstmerr.ErrorInvalidReferralCode.
+func createReferral(ctx context.Context, tx *sql.Tx, refereeID int64, invite ReferralInvite) error {
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT user_id FROM loyalty.referral_codes WHERE code = $1 FOR UPDATE`, invite.Code)
+	if err != nil {
+		return fmt.Errorf("failed to get referral code: %w", err)
+	}
+	var referrerID int64
+	if err = row.Scan(&referrerID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return cstmerr.ErrorInvalidReferralCode
+		}
+		return fmt.Errorf("failed to scan referral code: %w", err)
+	}
+	if invite.ClientIP != "" {
+		row, err = QueryRowWithRetry(ctx, tx, `
+			SELECT EXISTS (
+				SELECT 1
+				FROM loyalty.login_history lh
+				JOIN loyalty.users u ON u.name = lh.user_name
+				WHERE u.id = $1 AND lh.success AND lh.ip = $2 AND lh.user_agent = $3)`,
+			referrerID, invite.ClientIP, invite.UserAgent)
+		if err != nil {
+			return fmt.Errorf("failed to check referrer logins: %w", err)
+		}
+		var sameClient bool
+		if err = row.Scan(&sameClient); err != nil {
+			return fmt.Errorf("failed to scan referrer logins: %w", err)
+		}
+		if sameClient {
+			return fmt.Errorf("%w: self-referral is not allowed", cstmerr.ErrorInvalidReferralCode)
+		}
+	}
+
+	if invite.Limit > 0 {
+		row, err = QueryRowWithRetry(ctx, tx, `SELECT COUNT(*) FROM loyalty.referrals WHERE referrer_id = $1`, referrerID)
+		if err != nil {
+			return fmt.Errorf("failed to count referrals: %w", err)
+		}
+		var invited int
+		if err = row.Scan(&invited); err != nil {
+			return fmt.Errorf("failed to scan referrals count: %w", err)
+		}
+		if invited >= invite.Limit {
+			return cstmerr.ErrorReferralLimitReached
+		}
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.referrals (referrer_id, referee_id, referrer_bonus, referee_bonus)
+		VALUES ($1, $2, $3, $4)`, referrerID, refereeID, invite.ReferrerBonus, invite.RefereeBonus)
+	if err != nil {
+		return fmt.Errorf("failed to insert referral: %w", err)
+	}
+	return nil
+}
+
+// rewardReferral начисляет реферальные бонусы обоим участникам приглашения в рамках транзакции,
+// переводящей заказ приглашенного в PROCESSED. Бонусы начисляются один раз, за первый обработанный заказ,
+// и не отменяются, если заказ позже признан недействительным.
+func rewardReferral(ctx context.Context, tx *sql.Tx, refereeID, orderID int64) error {
+	row, err := QueryRowWithRetry(ctx, tx, `
+		UPDATE loyalty.referrals
+		SET order_id = $2, rewarded_at = NOW()
+		WHERE referee_id = $1 AND rewarded_at IS NULL
+		RETURNING id, referrer_id, referrer_bonus, referee_bonus`, refereeID, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to reward referral: %w", err)
+	}
+	var referralID, referrerID int64
+	var referrerBonus, refereeBonus float64
+	if err = row.Scan(&referralID, &referrerID, &referrerBonus, &refereeBonus); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to scan referral: %w", err)
+	}
+
+	bonuses := []struct {
+		userID int64
+		amount float64
+	}{
+		{userID: referrerID, amount: referrerBonus},
+		{userID: refereeID, amount: refereeBonus},
+	}
+	for _, bonus := range bonuses {
+		if bonus.amount <= 0 {
+			continue
+		}
+		err = ExecQueryWithRetry(ctx, tx, `
+			INSERT INTO loyalty.referral_bonuses (referral_id, user_id, amount)
+			VALUES ($1, $2, $3)`, referralID, bonus.userID, bonus.amount)
+		if err != nil {
+			return fmt.Errorf("failed to insert referral bonus: %w", err)
+		}
+		if err = addLot(ctx, tx, bonus.userID, nil, LotSourceReferral, bonus.amount); err != nil {
+			return err
+		}
+	}
+
+	// Событие баланса приглашенного записывает транзакция обработки заказа
+	if referrerBonus > 0 {
+		if err = recordBalanceEvent(ctx, tx, referrerID); err != nil {
+			return err
+		}
+	}
+
+	config.LoggerFromContext(ctx).Info("Referral rewarded",
+		zap.Int64("referral_id", referralID),
+		zap.Int64("referrer_id", referrerID),
+		zap.Int64("referee_id", refereeID))
+	return nil
+}
+
+// EnsureReferralCode возвращает реферальный код пользователя, при отсутствии сохраняет предложенный.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - code: код, который будет сохранен, если у пользователя еще нет кода.
+//
+// Возвращает:
+//   - string: реферальный код пользователя, пустая строка, если предложенный код занят другим пользователем.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func EnsureReferralCode(ctx context.Context, userID int64, code string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := insertReferralCode(ctx, DB, userID, code); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to save referral code", zap.Error(err))
+		return "", err
+	}
+
+	row, err := QueryRowWithRetry(ctx, DB, `SELECT code FROM loyalty.referral_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get referral code: %w", err)
+	}
+	var saved string
+	if err = row.Scan(&saved); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to scan referral code: %w", err)
+	}
+	return saved, nil
+}
+
+// ListReferrals возвращает пользователей, приглашенных пользователем, новые первыми.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - referrerID: идентификатор пригласившего пользователя.
+//
+// Возвращает:
+//   - []Referral: приглашенные пользователи.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ListReferrals(ctx context.Context, referrerID int64) ([]Referral, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := QueryRowsWithRetry(ctx, DB, `
+		SELECT r.id, u.name, r.referrer_bonus, r.created_at, r.rewarded_at
+		FROM loyalty.referrals r
+		JOIN loyalty.users u ON u.id = r.referee_id
+		WHERE r.referrer_id = $1
+		ORDER BY r.created_at DESC, r.id DESC`, referrerID)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch referrals", zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch referrals: %w", err)
+	}
+	defer rows.Close()
+
+	var referrals []Referral
+	for rows.Next() {
+		var referral Referral
+		if err := rows.Scan(&referral.ID, &referral.RefereeLogin, &referral.Bonus, &referral.RegisteredAt, &referral.RewardedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan referral: %w", err)
+		}
+		referrals = append(referrals, referral)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch referrals: %w", err)
+	}
+	return referrals, nil
+}
@@ -7,11 +7,14 @@ import (
 	"database/sql"
 
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/FollowLille/loyalty/internal/config"
 	cstmerr "github.com/FollowLille/loyalty/internal/errors"
 	"github.com/FollowLille/loyalty/internal/retry"
+	"github.com/FollowLille/loyalty/internal/tracing"
 )
 
 // ExecContexter представляет интерфейс для выполнения SQL-запросов.
@@ -37,21 +40,25 @@ type QueryRowContexter interface {
 // Возвращает:
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
 func ExecQueryWithRetry(ctx context.Context, db ExecContexter, query string, args ...interface{}) error {
+	attempt := 0
 	err := retry.Retry(func() error {
-		_, execErr := db.ExecContext(ctx, query, args...)
+		attempt++
+		attemptCtx, span := startQuerySpan(ctx, query, attempt)
+		_, execErr := db.ExecContext(attemptCtx, query, args...)
+		tracing.End(span, execErr)
 		if execErr != nil {
 			if retry.IsRetriablePostgresError(execErr) {
-				config.Logger.Error("Retrying query")
+				config.LoggerFromContext(ctx).Error("Retrying query")
 				return cstmerr.ErrorRetriablePostgres
 			}
-			config.Logger.Error("Non retriable error during query execution", zap.Error(execErr))
+			config.LoggerFromContext(ctx).Error("Non retriable error during query execution", zap.Error(execErr))
 			return cstmerr.ErrorNonRetriablePostgres
 		}
 		return nil
 	})
 
 	if err != nil {
-		config.Logger.Error("Failed to execute query", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to execute query", zap.Error(err))
 		return err
 	}
 	return nil
@@ -71,21 +78,25 @@ func QueryRowWithRetry(ctx context.Context, db QueryRowContexter, query string,
 	var row *sql.Row
 	var err error
 
+	attempt := 0
 	err = retry.Retry(func() error {
-		row = db.QueryRowContext(ctx, query, args...)
+		attempt++
+		attemptCtx, span := startQuerySpan(ctx, query, attempt)
+		row = db.QueryRowContext(attemptCtx, query, args...)
+		tracing.End(span, row.Err())
 		if err != nil {
 			if retry.IsRetriablePostgresError(err) {
-				config.Logger.Error("Retrying query")
+				config.LoggerFromContext(ctx).Error("Retrying query")
 				return cstmerr.ErrorRetriablePostgres
 			}
-			config.Logger.Error("Non retriable error during query execution", zap.Error(err))
+			config.LoggerFromContext(ctx).Error("Non retriable error during query execution", zap.Error(err))
 			return cstmerr.ErrorNonRetriablePostgres
 		}
 		return nil
 	})
 
 	if err != nil {
-		config.Logger.Error("Failed to execute query", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to execute query", zap.Error(err))
 		return nil, err
 	}
 	return row, nil
@@ -106,27 +117,49 @@ func QueryRowsWithRetry(ctx context.Context, db *sql.DB, query string, args ...i
 	var rows *sql.Rows
 	var err error
 
+	attempt := 0
 	err = retry.Retry(func() error {
-		rows, err = db.QueryContext(ctx, query, args...)
+		attempt++
+		attemptCtx, span := startQuerySpan(ctx, query, attempt)
+		rows, err = db.QueryContext(attemptCtx, query, args...)
+		tracing.End(span, err)
 		if err != nil {
 			if retry.IsRetriablePostgresError(err) {
-				config.Logger.Error("Retrying query")
+				config.LoggerFromContext(ctx).Error("Retrying query")
 				return cstmerr.ErrorRetriablePostgres
 			}
-			config.Logger.Error("Non retriable error during query execution", zap.Error(err))
+			config.LoggerFromContext(ctx).Error("Non retriable error during query execution", zap.Error(err))
 			return cstmerr.ErrorNonRetriablePostgres
 		}
 		if rows.Err() != nil {
-			config.Logger.Error("Failed to execute query", zap.Error(err))
+			config.LoggerFromContext(ctx).Error("Failed to execute query", zap.Error(err))
 			return cstmerr.ErrorNonRetriablePostgres
 		}
 		return nil
 	})
 
 	if err != nil {
-		config.Logger.Error("Failed to execute query", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to execute query", zap.Error(err))
 		return nil, err
 	}
-	config.Logger.Info("Query executed successfully")
+	config.LoggerFromContext(ctx).Info("Query executed successfully")
 	return rows, nil
 }
+
+// startQuerySpan начинает спан одной попытки выполнения SQL-запроса.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - query: SQL-запрос.
+//   - attempt: номер попытки, начиная с 1.
+//
+// Возвращает:
+//   - context.Context: контекст со спаном попытки.
+//   - trace.Span: спан попытки.
+func startQuerySpan(ctx context.Context, query string, attempt int) (context.Context, trace.Span) {
+	return tracing.StartClient(ctx, "db.query",
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", query),
+		attribute.Int("db.attempt", attempt),
+	)
+}
@@ -0,0 +1,211 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для работы с возвратами списанных баллов
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// Источники возврата списанных баллов.
+const (
+	ReversalSourceAdmin    = "admin"    // возврат выполнен администратором
+	ReversalSourceMerchant = "merchant" // возврат выполнен мерчантом по API-ключу
+)
+
+// amountEpsilon - допустимая погрешность при сравнении сумм баллов, хранящихся в FLOAT8.
+const amountEpsilon = 1e-9
+
+type WithdrawalReversal struct {
+	ID          int64
+	OrderNumber string
+	UserID      int64
+	Amount      float64 // сумма возврата, 0 при создании означает возврат всего остатка списания
+	Reason      string
+	Source      string
+	ActorID     int64  // идентификатор администратора или API-ключа мерчанта
+	Merchant    string // мерчант, выполняющий возврат; списание должно быть сделано в счет его заказа
+	CreatedAt   time.Time
+}
+
+// ReverseWithdrawal возвращает пользователю баллы, списанные по заказу, полностью или частично.
+// Списание блокируется на время транзакции, поэтому параллельные возвраты не могут в сумме превысить его.
+// Если списания по заказу нет, возвращает ошибку cstmerr.ErrorWithdrawalNotFound,
+// если сумма возврата больше невозвращенного остатка - cstmerr.ErrorRefundExceedsAmount.
+// Мерчант может вернуть только списание, сделанное в счет его заказа; для чужих списаний,
+// чтобы не раскрывать их, также возвращается cstmerr.ErrorWithdrawalNotFound.
+// Возврат, выполненный администратором, записывается в журнал действий администратора.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - reversal: параметры возврата, поля ID, UserID и CreatedAt заполняются базой данных.
+//
+// Возвращает:
+//   - WithdrawalReversal: сохраненный возврат.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ReverseWithdrawal(ctx context.Context, reversal WithdrawalReversal) (WithdrawalReversal, error) {
+	orderInt, err := strconv.ParseInt(reversal.OrderNumber, 10, 64)
+	if err != nil {
+		return WithdrawalReversal{}, cstmerr.ErrorWithdrawalNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return WithdrawalReversal{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		SELECT b.withdrawn, uo.user_id
+		FROM loyalty.bonuses b
+		JOIN loyalty.user_orders uo ON uo.order_id = b.order_id
+		WHERE b.order_id = $1 AND b.withdrawn > 0
+			AND (NOT $2::BOOLEAN OR b.merchant_name = $3)
+		LIMIT 1
+		FOR UPDATE OF b`, orderInt, reversal.Source == ReversalSourceMerchant, reversal.Merchant)
+	if err != nil {
+		return WithdrawalReversal{}, fmt.Errorf("failed to get withdrawal: %w", err)
+	}
+	var withdrawn float64
+	if err = row.Scan(&withdrawn, &reversal.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorWithdrawalNotFound
+			return WithdrawalReversal{}, err
+		}
+		return WithdrawalReversal{}, fmt.Errorf("failed to scan withdrawal: %w", err)
+	}
+
+	row, err = QueryRowWithRetry(ctx, tx, `
+		SELECT COALESCE(SUM(amount), 0) FROM loyalty.withdrawal_reversals WHERE order_id = $1`, orderInt)
+	if err != nil {
+		return WithdrawalReversal{}, fmt.Errorf("failed to get reversed amount: %w", err)
+	}
+	var reversed float64
+	if err = row.Scan(&reversed); err != nil {
+		return WithdrawalReversal{}, fmt.Errorf("failed to scan reversed amount: %w", err)
+	}
+
+	remaining := withdrawn - reversed
+	if reversal.Amount == 0 {
+		reversal.Amount = remaining
+	}
+	if remaining <= amountEpsilon || reversal.Amount > remaining+amountEpsilon {
+		err = cstmerr.ErrorRefundExceedsAmount
+		return WithdrawalReversal{}, err
+	}
+
+	row, err = QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.withdrawal_reversals (order_id, user_id, amount, reason, source, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		orderInt, reversal.UserID, reversal.Amount, reversal.Reason, reversal.Source, reversal.ActorID)
+	if err != nil {
+		return WithdrawalReversal{}, fmt.Errorf("failed to insert withdrawal reversal: %w", err)
+	}
+	if err = row.Scan(&reversal.ID, &reversal.CreatedAt); err != nil {
+		return WithdrawalReversal{}, fmt.Errorf("failed to scan withdrawal reversal: %w", err)
+	}
+
+	if err = addLot(ctx, tx, reversal.UserID, &orderInt, LotSourceReversal, reversal.Amount); err != nil {
+		return WithdrawalReversal{}, err
+	}
+
+	if reversal.Source == ReversalSourceAdmin {
+		err = insertAuditRecord(ctx, tx, AuditRecord{
+			AdminID:      reversal.ActorID,
+			Action:       "withdrawal_reversal",
+			TargetUserID: &reversal.UserID,
+			TargetOrder:  &orderInt,
+			Details: map[string]interface{}{
+				"reversal_id": reversal.ID,
+				"amount":      reversal.Amount,
+				"reason":      reversal.Reason,
+			},
+		})
+		if err != nil {
+			return WithdrawalReversal{}, err
+		}
+	}
+
+	if err = recordBalanceEvent(ctx, tx, reversal.UserID); err != nil {
+		return WithdrawalReversal{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return WithdrawalReversal{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Withdrawal reversed",
+		zap.String("order_number", reversal.OrderNumber),
+		zap.Int64("user_id", reversal.UserID),
+		zap.Float64("amount", reversal.Amount),
+		zap.String("source", reversal.Source),
+		zap.Int64("actor_id", reversal.ActorID))
+	return reversal, nil
+}
+
+// FetchWithdrawalReversals возвращает возвраты по списку заказов со списаниями.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - orderNumbers: номера заказов.
+//
+// Возвращает:
+//   - map[string][]WithdrawalReversal: возвраты, сгруппированные по номеру заказа, от старых к новым.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchWithdrawalReversals(ctx context.Context, orderNumbers []string) (map[string][]WithdrawalReversal, error) {
+	reversals := make(map[string][]WithdrawalReversal)
+	if len(orderNumbers) == 0 {
+		return reversals, nil
+	}
+
+	query := `
+		SELECT id, order_id, user_id, amount, reason, source, actor_id, created_at
+		FROM loyalty.withdrawal_reversals
+		WHERE order_id = ANY($1::BIGINT[])
+		ORDER BY created_at, id;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, query, pq.Array(orderNumbers))
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch withdrawal reversals", zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch withdrawal reversals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r WithdrawalReversal
+		if err := rows.Scan(&r.ID, &r.OrderNumber, &r.UserID, &r.Amount, &r.Reason, &r.Source, &r.ActorID, &r.CreatedAt); err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to scan withdrawal reversal", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan withdrawal reversal: %w", err)
+		}
+		reversals[r.OrderNumber] = append(reversals[r.OrderNumber], r)
+	}
+	if rows.Err() != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch withdrawal reversals", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("failed to fetch withdrawal reversals: %w", rows.Err())
+	}
+
+	return reversals, nil
+}
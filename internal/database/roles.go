@@ -0,0 +1,123 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для работы с ролями пользователей
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// GetUserRoles возвращает список ролей пользователя.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - []string: роли пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GetUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	query := `SELECT role FROM loyalty.user_roles WHERE user_id = $1 ORDER BY role`
+	rows, err := QueryRowsWithRetry(ctx, DB, query, userID)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user roles", zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch user roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to scan role", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	if rows.Err() != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user roles", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("failed to fetch user roles: %w", rows.Err())
+	}
+
+	return roles, nil
+}
+
+// GrantRole выдает пользователю роль.
+// Если роль выдана впервые, версия токенов пользователя увеличивается,
+// чтобы роли в ранее выданных токенах не расходились с базой данных.
+// Если пользователь не найден, возвращает ошибку cstmerr.ErrorUserDoesNotExist.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userName: имя пользователя.
+//   - role: выдаваемая роль.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GrantRole(ctx context.Context, userName, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `SELECT id FROM loyalty.users WHERE name = $1 FOR UPDATE`, userName)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	var userID int64
+	if err = row.Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorUserDoesNotExist
+			return err
+		}
+		return fmt.Errorf("failed to scan user ID: %w", err)
+	}
+
+	row, err = QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.user_roles (user_id, role)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, role) DO NOTHING
+		RETURNING user_id`, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to grant role: %w", err)
+	}
+	var granted int64
+	err = row.Scan(&granted)
+	if errors.Is(err, sql.ErrNoRows) {
+		config.LoggerFromContext(ctx).Info("User already has role", zap.String("user", userName), zap.String("role", role))
+		err = tx.Commit()
+		return err
+	} else if err != nil {
+		return fmt.Errorf("failed to scan granted role: %w", err)
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `UPDATE loyalty.users SET token_version = token_version + 1 WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to bump token version: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Role granted", zap.String("user", userName), zap.String("role", role))
+	return nil
+}
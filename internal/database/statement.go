@@ -0,0 +1,162 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для построения выписки по счету пользователя
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// Типы операций выписки.
+const (
+	StatementAccrual     = "accrual"        // начисление за обработанный заказ
+	StatementWithdrawal  = "withdrawal"     // списание в счет заказа
+	StatementRefund      = "refund"         // возврат списанных баллов
+	StatementAdjustment  = "adjustment"     // ручная корректировка администратором
+	StatementExpiration  = "expiration"     // сгорание баллов
+	StatementTierBonus   = "tier_bonus"     // бонус по множителю уровня за обработанный заказ
+	StatementCampaign    = "campaign_bonus" // бонус по маркетинговой кампании
+	StatementReferral    = "referral_bonus" // бонус реферальной программы
+	StatementTransferIn  = "transfer_in"    // перевод от другого пользователя
+	StatementTransferOut = "transfer_out"   // перевод другому пользователю
+)
+
+// StatementEntry описывает операцию выписки. Сумма положительна для поступлений и отрицательна для списаний.
+type StatementEntry struct {
+	Type         string
+	Order        *string
+	Counterparty *string // логин второй стороны перевода или реферального приглашения
+	Campaign     *string // название кампании, по которой начислен бонус
+	Amount       float64
+	CreatedAt    time.Time
+}
+
+// StreamStatement читает операции по счету пользователя за период в порядке возникновения
+// и передает их в onEntry по одному, не загружая выписку в память.
+// Перед операциями в onOpening передается баланс на начало периода. Баланс и операции
+// читаются одним запросом, поэтому согласованы между собой. Операции повторяют
+// расчет текущего баланса в представлении loyalty.user_bonuses.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса.
+//   - userID: идентификатор пользователя.
+//   - from: начало периода, nil - с первой операции.
+//   - to: конец периода (не включительно), nil - до текущего момента.
+//   - onOpening: обработчик баланса на начало периода.
+//   - onEntry: обработчик операции.
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или ошибка обработчика.
+func StreamStatement(ctx context.Context, userID int64, from, to *time.Time,
+	onOpening func(float64) error, onEntry func(StatementEntry) error) error {
+	query := `
+		WITH entries AS (
+			SELECT 'accrual' AS type, b.order_id::text AS order_number, b.accrual AS amount, b.created_at, b.order_id AS id,
+				NULL::text AS counterparty, NULL::text AS campaign
+			FROM loyalty.bonuses b
+			JOIN loyalty.orders o ON o.id = b.order_id
+			JOIN loyalty.user_orders uo ON uo.order_id = b.order_id
+			JOIN loyalty.status_dictionary sd ON sd.id = o.status
+			WHERE uo.user_id = $1 AND sd.status_name = 'PROCESSED' AND b.accrual > 0
+			UNION ALL
+			SELECT 'withdrawal', b.order_id::text, -b.withdrawn, b.created_at, b.order_id, NULL, NULL
+			FROM loyalty.bonuses b
+			JOIN loyalty.orders o ON o.id = b.order_id
+			JOIN loyalty.user_orders uo ON uo.order_id = b.order_id
+			JOIN loyalty.status_dictionary sd ON sd.id = o.status
+			WHERE uo.user_id = $1 AND sd.status_name != 'INVALID' AND b.withdrawn > 0
+			UNION ALL
+			SELECT 'tier_bonus', tb.order_id::text, tb.amount, tb.created_at, tb.order_id, NULL, NULL
+			FROM loyalty.tier_bonuses tb
+			JOIN loyalty.orders o ON o.id = tb.order_id
+			JOIN loyalty.status_dictionary sd ON sd.id = o.status
+			WHERE tb.user_id = $1 AND sd.status_name = 'PROCESSED'
+			UNION ALL
+			SELECT 'campaign_bonus', cb.order_id::text, cb.amount, cb.created_at, cb.id, NULL, c.name
+			FROM loyalty.campaign_bonuses cb
+			JOIN loyalty.campaigns c ON c.id = cb.campaign_id
+			WHERE cb.user_id = $1 AND ` + campaignBonusEffective + `
+			UNION ALL
+			SELECT 'referral_bonus', NULL, rb.amount, rb.created_at, rb.id, u.name, NULL
+			FROM loyalty.referral_bonuses rb
+			JOIN loyalty.referrals r ON r.id = rb.referral_id
+			JOIN loyalty.users u ON u.id = CASE WHEN r.referrer_id = rb.user_id THEN r.referee_id ELSE r.referrer_id END
+			WHERE rb.user_id = $1
+			UNION ALL
+			SELECT 'refund', order_id::text, amount, created_at, id, NULL, NULL
+			FROM loyalty.withdrawal_reversals
+			WHERE user_id = $1
+			UNION ALL
+			SELECT 'adjustment', NULL, amount, created_at, id, NULL, NULL
+			FROM loyalty.balance_adjustments
+			WHERE user_id = $1
+			UNION ALL
+			SELECT 'expiration', NULL, -amount, created_at, id, NULL, NULL
+			FROM loyalty.point_expirations
+			WHERE user_id = $1
+			UNION ALL
+			SELECT 'transfer_in', NULL, pt.amount, pt.created_at, pt.id, u.name, NULL
+			FROM loyalty.point_transfers pt
+			JOIN loyalty.users u ON u.id = pt.sender_id
+			WHERE pt.recipient_id = $1
+			UNION ALL
+			SELECT 'transfer_out', NULL, -pt.amount, pt.created_at, pt.id, u.name, NULL
+			FROM loyalty.point_transfers pt
+			JOIN loyalty.users u ON u.id = pt.recipient_id
+			WHERE pt.sender_id = $1
+		)
+		SELECT 0 AS part, 'opening', NULL, COALESCE(SUM(amount), 0), NULL::timestamp, 0, NULL, NULL
+		FROM entries
+		WHERE $2::timestamp IS NOT NULL AND created_at < $2::timestamp
+		UNION ALL
+		SELECT 1, type, order_number, amount, created_at, id, counterparty, campaign
+		FROM entries
+		WHERE ($2::timestamp IS NULL OR created_at >= $2::timestamp)
+			AND ($3::timestamp IS NULL OR created_at < $3::timestamp)
+		ORDER BY 1, 5, 2, 6`
+
+	rows, err := QueryRowsWithRetry(ctx, DB, query, userID, nullTime(from), nullTime(to))
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch statement", zap.Error(err))
+		return fmt.Errorf("failed to fetch statement: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var part int
+		var entry StatementEntry
+		var createdAt sql.NullTime
+		var id int64
+		if err := rows.Scan(&part, &entry.Type, &entry.Order, &entry.Amount, &createdAt, &id, &entry.Counterparty, &entry.Campaign); err != nil {
+			return fmt.Errorf("failed to scan statement entry: %w", err)
+		}
+		if part == 0 {
+			if err := onOpening(entry.Amount); err != nil {
+				return err
+			}
+			continue
+		}
+		entry.CreatedAt = createdAt.Time
+		if err := onEntry(entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to fetch statement: %w", err)
+	}
+	return nil
+}
+
+// nullTime переводит необязательное время в аргумент запроса: nil передается как NULL.
+func nullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
@@ -0,0 +1,204 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для учета уровней программы лояльности и бонусов по множителю уровня
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// TierWindowMonths хранит период в месяцах, за который учитываются начисления для определения уровня.
+const TierWindowMonths = 12
+
+// UserTier описывает текущий уровень пользователя.
+type UserTier struct {
+	Tier             string     // название уровня, пустое, если уровни не настроены
+	QualifyingPoints float64    // начисления за последние TierWindowMonths месяцев без бонусов уровня
+	UpdatedAt        *time.Time // время последнего изменения уровня, nil - уровень еще не рассчитывался
+}
+
+// qualifyingPointsQuery считает начисления пользователя $1 за обработанные заказы за последние $2 месяцев.
+// Бонусы по множителю уровня не учитываются, чтобы уровень не повышал сам себя.
+const qualifyingPointsQuery = `
+	SELECT uo.user_id, SUM(b.accrual) AS points
+	FROM loyalty.bonuses b
+	JOIN loyalty.orders o ON o.id = b.order_id
+	JOIN loyalty.user_orders uo ON uo.order_id = b.order_id
+	JOIN loyalty.status_dictionary sd ON sd.id = o.status
+	WHERE ($1::bigint IS NULL OR uo.user_id = $1) AND sd.status_name = 'PROCESSED' AND b.accrual > 0
+		AND b.created_at >= NOW() - make_interval(months => $2)
+	GROUP BY uo.user_id`
+
+// tierEvaluationQuery пересчитывает уровень пользователя $1 или всех пользователей, если $1 равен NULL.
+// Если $3 ложно, уровень может только повыситься.
+const tierEvaluationQuery = `
+	WITH points AS (` + qualifyingPointsQuery + `
+	), candidates AS (
+		SELECT user_id FROM loyalty.user_tiers WHERE $1::bigint IS NULL OR user_id = $1
+		UNION
+		SELECT user_id FROM points
+	), target AS (
+		SELECT c.user_id, (
+			SELECT t.name FROM loyalty.tiers t
+			WHERE t.min_points <= COALESCE(p.points, 0)
+			ORDER BY t.min_points DESC
+			LIMIT 1) AS tier
+		FROM candidates c
+		LEFT JOIN points p ON p.user_id = c.user_id
+	)
+	INSERT INTO loyalty.user_tiers AS ut (user_id, tier)
+	SELECT user_id, tier FROM target WHERE tier IS NOT NULL
+	ON CONFLICT (user_id) DO UPDATE SET tier = EXCLUDED.tier, updated_at = NOW()
+	WHERE ut.tier <> EXCLUDED.tier AND ($3 OR
+		(SELECT min_points FROM loyalty.tiers WHERE name = EXCLUDED.tier) >
+		COALESCE((SELECT min_points FROM loyalty.tiers WHERE name = ut.tier), -1))`
+
+// SyncTiers заменяет уровни программы лояльности в базе данных уровнями из конфигурации.
+// Уровни пользователей не пересчитываются до следующего начисления или ежесуточного пересчета.
+//
+// Параметры:
+//   - tiers: уровни программы лояльности.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func SyncTiers(tiers []config.LoyaltyTier) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.Logger.Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.Logger.Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	if err = ExecQueryWithRetry(ctx, tx, `DELETE FROM loyalty.tiers`); err != nil {
+		return fmt.Errorf("failed to delete tiers: %w", err)
+	}
+	for _, tier := range tiers {
+		err = ExecQueryWithRetry(ctx, tx, `
+			INSERT INTO loyalty.tiers (name, min_points, multiplier) VALUES ($1, $2, $3)`,
+			tier.Name, tier.MinPoints, tier.Multiplier)
+		if err != nil {
+			return fmt.Errorf("failed to insert tier %s: %w", tier.Name, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	config.Logger.Info("Tiers are ready", zap.Int("count", len(tiers)))
+	return nil
+}
+
+// applyTierBonus начисляет бонус по множителю текущего уровня пользователя за обработанный заказ
+// в рамках транзакции начисления. Бонус сохраняется отдельно от начисления системы расчета баллов
+// и образует собственную партию баллов. Повторный вызов для того же заказа бонус не дублирует.
+func applyTierBonus(ctx context.Context, tx *sql.Tx, userID, orderID int64, accrual float64) error {
+	row, err := QueryRowWithRetry(ctx, tx, `
+		WITH tier AS (
+			SELECT name, multiplier
+			FROM loyalty.tiers
+			WHERE name = COALESCE(
+				(SELECT tier FROM loyalty.user_tiers WHERE user_id = $2),
+				(SELECT name FROM loyalty.tiers ORDER BY min_points LIMIT 1))
+		)
+		INSERT INTO loyalty.tier_bonuses (order_id, user_id, tier, multiplier, amount)
+		SELECT $1, $2, name, multiplier, ROUND(($3 * (multiplier - 1))::numeric, 2)::float8
+		FROM tier
+		WHERE ROUND(($3 * (multiplier - 1))::numeric, 2) > 0
+		ON CONFLICT (order_id) DO NOTHING
+		RETURNING amount`, orderID, userID, accrual)
+	if err != nil {
+		return fmt.Errorf("failed to add tier bonus: %w", err)
+	}
+	var bonus float64
+	if err = row.Scan(&bonus); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to add tier bonus: %w", err)
+	}
+	return addLot(ctx, tx, userID, &orderID, LotSourceTierBonus, bonus)
+}
+
+// upgradeTier пересчитывает уровень пользователя после начисления в рамках транзакции начисления.
+// Уровень может только повыситься, понижение выполняет ежесуточный пересчет.
+func upgradeTier(ctx context.Context, tx ExecContexter, userID int64) error {
+	if err := ExecQueryWithRetry(ctx, tx, tierEvaluationQuery, userID, TierWindowMonths, false); err != nil {
+		return fmt.Errorf("failed to evaluate tier: %w", err)
+	}
+	return nil
+}
+
+// EvaluateTiers пересчитывает уровни всех пользователей по начислениям за последние TierWindowMonths месяцев.
+// В отличие от пересчета после начисления, уровень может как повыситься, так и понизиться.
+//
+// Возвращает:
+//   - int64: количество пользователей, чей уровень изменился.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func EvaluateTiers(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := DB.ExecContext(ctx, tierEvaluationQuery, nil, TierWindowMonths, true)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to evaluate tiers", zap.Error(err))
+		return 0, fmt.Errorf("failed to evaluate tiers: %w", err)
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate tiers: %w", err)
+	}
+	return changed, nil
+}
+
+// FetchUserTier возвращает текущий уровень пользователя и сумму начислений, по которой он определяется.
+// Пользователю, уровень которого еще не рассчитывался, соответствует уровень с наименьшим порогом.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - UserTier: уровень пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchUserTier(ctx context.Context, userID int64) (UserTier, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	row, err := QueryRowWithRetry(ctx, DB, `
+		WITH points AS (`+qualifyingPointsQuery+`
+		)
+		SELECT
+			COALESCE(ut.tier, (SELECT name FROM loyalty.tiers ORDER BY min_points LIMIT 1), ''),
+			ut.updated_at,
+			COALESCE((SELECT points FROM points), 0)
+		FROM (SELECT $1::bigint AS user_id) u
+		LEFT JOIN loyalty.user_tiers ut ON ut.user_id = u.user_id`, userID, TierWindowMonths)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user tier", zap.Error(err))
+		return UserTier{}, fmt.Errorf("failed to fetch user tier: %w", err)
+	}
+
+	var tier UserTier
+	var updatedAt sql.NullTime
+	if err = row.Scan(&tier.Tier, &updatedAt, &tier.QualifyingPoints); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan user tier", zap.Error(err))
+		return UserTier{}, fmt.Errorf("failed to scan user tier: %w", err)
+	}
+	if updatedAt.Valid {
+		tier.UpdatedAt = &updatedAt.Time
+	}
+	return tier, nil
+}
@@ -0,0 +1,144 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для переводов баллов между пользователями
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+type PointTransfer struct {
+	ID             int64
+	SenderID       int64
+	SenderLogin    string
+	RecipientID    int64
+	RecipientLogin string
+	Amount         float64
+	CreatedAt      time.Time
+}
+
+// CreatePointTransfer переводит баллы от отправителя получателю в одной транзакции.
+// Отправитель и получатель блокируются, поэтому параллельные переводы не уведут баланс в минус
+// и не превысят суточный лимит. Баллы списываются с самых старых партий отправителя
+// и поступают получателю несгораемой партией.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - senderID: идентификатор отправителя.
+//   - recipientLogin: логин получателя.
+//   - amount: сумма перевода.
+//   - dailyLimit: максимальная сумма переводов отправителя за календарные сутки (UTC), 0 - без ограничения.
+//
+// Возвращает:
+//   - PointTransfer: сохраненный перевод.
+//   - error: cstmerr.ErrorUserDoesNotExist, если получатель не найден, cstmerr.ErrorInvalidTransfer при переводе самому себе,
+//     cstmerr.ErrorTransferLimitExceeded, cstmerr.ErrorInsufficientBalance или ошибка выполнения запроса.
+func CreatePointTransfer(ctx context.Context, senderID int64, recipientLogin string, amount, dailyLimit float64) (PointTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return PointTransfer{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	transfer := PointTransfer{SenderID: senderID, RecipientLogin: recipientLogin, Amount: amount}
+	row, err := QueryRowWithRetry(ctx, tx, `SELECT id FROM loyalty.users WHERE name = $1`, recipientLogin)
+	if err != nil {
+		return PointTransfer{}, fmt.Errorf("failed to get recipient: %w", err)
+	}
+	if err = row.Scan(&transfer.RecipientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("%w: recipient %s", cstmerr.ErrorUserDoesNotExist, recipientLogin)
+			return PointTransfer{}, err
+		}
+		return PointTransfer{}, fmt.Errorf("failed to scan recipient: %w", err)
+	}
+	if transfer.RecipientID == senderID {
+		err = fmt.Errorf("%w: cannot transfer points to yourself", cstmerr.ErrorInvalidTransfer)
+		return PointTransfer{}, err
+	}
+
+	// Пользователи блокируются в порядке идентификаторов, чтобы встречные переводы не приводили к взаимной блокировке
+	err = ExecQueryWithRetry(ctx, tx, `
+		SELECT id FROM loyalty.users WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`, senderID, transfer.RecipientID)
+	if err != nil {
+		return PointTransfer{}, fmt.Errorf("failed to lock users: %w", err)
+	}
+
+	if err = lockBalanceForDebit(ctx, tx, senderID, amount); err != nil {
+		return PointTransfer{}, err
+	}
+
+	// created_at хранится без часового пояса во времени сессии, поэтому начало суток UTC
+	// переводится в тот же пояс: сравнение остается верным при любой настройке TimeZone
+	row, err = QueryRowWithRetry(ctx, tx, `
+		SELECT u.name,
+			COALESCE((SELECT SUM(pt.amount) FROM loyalty.point_transfers pt
+				WHERE pt.sender_id = u.id
+					AND pt.created_at >= (date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC') AT TIME ZONE current_setting('TimeZone')), 0)
+		FROM loyalty.users u
+		WHERE u.id = $1`, senderID)
+	if err != nil {
+		return PointTransfer{}, fmt.Errorf("failed to fetch sender transfers: %w", err)
+	}
+	var sentToday float64
+	if err = row.Scan(&transfer.SenderLogin, &sentToday); err != nil {
+		return PointTransfer{}, fmt.Errorf("failed to scan sender transfers: %w", err)
+	}
+	if dailyLimit > 0 && sentToday+amount > dailyLimit {
+		err = fmt.Errorf("%w: daily limit is %g, %g already transferred today", cstmerr.ErrorTransferLimitExceeded, dailyLimit, sentToday)
+		return PointTransfer{}, err
+	}
+
+	row, err = QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.point_transfers (sender_id, recipient_id, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`, senderID, transfer.RecipientID, amount)
+	if err != nil {
+		return PointTransfer{}, fmt.Errorf("failed to insert point transfer: %w", err)
+	}
+	if err = row.Scan(&transfer.ID, &transfer.CreatedAt); err != nil {
+		return PointTransfer{}, fmt.Errorf("failed to scan point transfer: %w", err)
+	}
+
+	if err = consumeLots(ctx, tx, senderID, amount); err != nil {
+		return PointTransfer{}, err
+	}
+	if err = addLot(ctx, tx, transfer.RecipientID, nil, LotSourceTransfer, amount); err != nil {
+		return PointTransfer{}, err
+	}
+
+	if err = recordBalanceEvent(ctx, tx, senderID); err != nil {
+		return PointTransfer{}, err
+	}
+	if err = recordBalanceEvent(ctx, tx, transfer.RecipientID); err != nil {
+		return PointTransfer{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return PointTransfer{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Points transferred",
+		zap.Int64("transfer_id", transfer.ID),
+		zap.Int64("sender_id", senderID),
+		zap.Int64("recipient_id", transfer.RecipientID),
+		zap.Float64("amount", amount))
+	return transfer, nil
+}
@@ -9,6 +9,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
@@ -21,53 +22,91 @@ import (
 // Если пользователь существует, возвращает true. В противном случае возвращает false.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - name: имя пользователя для проверки.
 //
 // Возвращает:
 //   - bool: true, если пользователь существует; false в противном случае.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func IsUserExists(name string) (bool, error) {
+func IsUserExists(ctx context.Context, name string) (bool, error) {
 	query := "SELECT EXISTS (SELECT 1 FROM loyalty.users WHERE name = $1)"
 	var exists bool
-	row, err := QueryRowWithRetry(context.Background(), DB, query, name)
+	row, err := QueryRowWithRetry(ctx, DB, query, name)
 	if err != nil {
-		config.Logger.Error("Failed to check if user exists", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to check if user exists", zap.Error(err))
 		return false, err
 	}
 	if err = row.Scan(&exists); err != nil {
-		config.Logger.Error("Failed to scan result", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
 		return false, err
 	}
 	return exists, nil
 }
 
 // CreateUser создает нового пользователя в базе данных с указанными именем и хэшем пароля.
+// Вместе с пользователем в одной транзакции сохраняются его реферальный код и приглашение, по которому он зарегистрировался.
 // Если пользователь с таким именем уже существует, возвращает ошибку cstmerr.ErrorUserAlreadyExists.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - name: имя пользователя для создания.
 //   - passwordHash: хэш пароля пользователя.
+//   - referralCode: реферальный код нового пользователя. Если код уже занят, пользователь получит код при первом обращении к нему.
+//   - invite: приглашение, по которому регистрируется пользователь, nil - без приглашения.
 //
 // Возвращает:
-//   - error: ошибка, если произошла ошибка при создании пользователя или пользователь уже существует.
-func CreateUser(name, passwordHash string) error {
-	exists, err := IsUserExists(name)
+//   - error: ошибка, если произошла ошибка при создании пользователя, пользователь уже существует
+//     или приглашение недействительно.
+func CreateUser(ctx context.Context, name, passwordHash, referralCode string, invite *ReferralInvite) error {
+	exists, err := IsUserExists(ctx, name)
 	if err != nil {
 		return err
 	}
 
 	if exists {
-		config.Logger.Warn("User already exists", zap.String("user", name))
+		config.LoggerFromContext(ctx).Warn("User already exists", zap.String("user", name))
 		return cstmerr.ErrorUserAlreadyExists
 	}
 
-	query := "INSERT INTO loyalty.users (name, password_hash) VALUES ($1, $2)"
-	err = ExecQueryWithRetry(context.Background(), DB, query, name, passwordHash)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
-		config.Logger.Error("Failed to create user", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, "INSERT INTO loyalty.users (name, password_hash) VALUES ($1, $2) RETURNING id", name, passwordHash)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to create user", zap.Error(err))
 		return err
 	}
-	config.Logger.Info("User created", zap.String("user", name))
+	var userID int64
+	if err = row.Scan(&userID); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to create user", zap.Error(err))
+		return err
+	}
+
+	if err = insertReferralCode(ctx, tx, userID, referralCode); err != nil {
+		return err
+	}
+	if invite != nil {
+		if err = createReferral(ctx, tx, userID, *invite); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	config.LoggerFromContext(ctx).Info("User created", zap.String("user", name))
 	return nil
 }
 
@@ -75,29 +114,33 @@ func CreateUser(name, passwordHash string) error {
 // Если пользователь не найден, возвращает пустую строку и nil.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - name: имя пользователя для поиска.
 //
 // Возвращает:
 //   - string: хэш пароля пользователя, если он найден.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func GetUserPasswordHash(name string) (string, error) {
+func GetUserPasswordHash(ctx context.Context, name string) (string, error) {
 	query := "SELECT password_hash FROM loyalty.users WHERE name = $1"
 	var passwordHash string
-	row, err := QueryRowWithRetry(context.Background(), DB, query, name)
+	row, err := QueryRowWithRetry(ctx, DB, query, name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			config.Logger.Warn("User does not exist", zap.String("user", name))
+			config.LoggerFromContext(ctx).Warn("User does not exist", zap.String("user", name))
 			return "", cstmerr.ErrorUserDoesNotExist
 		}
-		config.Logger.Error("Failed to get user password hash", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to get user password hash", zap.Error(err))
 		return "", err
 	}
 	err = row.Scan(&passwordHash)
-	if err != nil {
-		config.Logger.Error("Failed to scan result", zap.Error(err))
+	if errors.Is(err, sql.ErrNoRows) {
+		config.LoggerFromContext(ctx).Warn("User does not exist", zap.String("user", name))
+		return "", cstmerr.ErrorUserDoesNotExist
+	} else if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
 		return "", err
 	}
-	config.Logger.Info("User password hash retrieved", zap.String("user", name))
+	config.LoggerFromContext(ctx).Info("User password hash retrieved", zap.String("user", name))
 	return passwordHash, nil
 }
 
@@ -105,23 +148,24 @@ func GetUserPasswordHash(name string) (string, error) {
 // Если пользователь не найден, возвращает 0 и nil.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - username: имя пользователя для проверки.
 //   - password: пароль пользователя.
 //
 // Возвращает:
 //   - int64: идентификатор пользователя, если он найден.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func ValidateUser(username, password string) (int64, error) {
+func ValidateUser(ctx context.Context, username, password string) (int64, error) {
 	var userID int64
 	var hashedPassword string
 
 	query := `SELECT id, password_hash FROM loyalty.users WHERE name = $1`
-	err := DB.QueryRow(query, username).Scan(&userID, &hashedPassword)
+	err := DB.QueryRowContext(ctx, query, username).Scan(&userID, &hashedPassword)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, err
 		}
-		config.Logger.Error("Failed to validate user", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to validate user", zap.Error(err))
 		return 0, fmt.Errorf("failed to validate user: %w", err)
 	}
 
@@ -135,24 +179,116 @@ func ValidateUser(username, password string) (int64, error) {
 // GetUserIDByName возвращает идентификатор пользователя по его имени.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - name: имя пользователя для поиска.
 //
 // Возвращает:
 //   - int64: идентификатор пользователя.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func GetUserIDByName(name string) (int64, error) {
+func GetUserIDByName(ctx context.Context, name string) (int64, error) {
 	var userID int64
 	query := `SELECT id FROM loyalty.users WHERE name = $1`
-	row, err := QueryRowWithRetry(context.Background(), DB, query, name)
+	row, err := QueryRowWithRetry(ctx, DB, query, name)
 	if err != nil {
 		return 0, err
 	}
 	err = row.Scan(&userID)
 	if errors.Is(err, sql.ErrNoRows) {
-		config.Logger.Error("User not found", zap.String("user", name))
+		config.LoggerFromContext(ctx).Error("User not found", zap.String("user", name))
 	} else if err != nil {
-		config.Logger.Error("Failed to scan result", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
 		return 0, err
 	}
 	return userID, nil
 }
+
+// GetUserAuthInfo возвращает идентификатор пользователя и текущую версию его токенов.
+// Если пользователь не найден, возвращает ошибку cstmerr.ErrorUserDoesNotExist.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - name: имя пользователя для поиска.
+//
+// Возвращает:
+//   - int64: идентификатор пользователя.
+//   - int64: версия токенов пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GetUserAuthInfo(ctx context.Context, name string) (int64, int64, error) {
+	var userID, tokenVersion int64
+	query := `SELECT id, token_version FROM loyalty.users WHERE name = $1`
+	row, err := QueryRowWithRetry(ctx, DB, query, name)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = row.Scan(&userID, &tokenVersion)
+	if errors.Is(err, sql.ErrNoRows) {
+		config.LoggerFromContext(ctx).Warn("User not found", zap.String("user", name))
+		return 0, 0, cstmerr.ErrorUserDoesNotExist
+	} else if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
+		return 0, 0, err
+	}
+	return userID, tokenVersion, nil
+}
+
+// GetUserCredentialsByID возвращает имя пользователя и хэш его пароля по идентификатору.
+// Если пользователь не найден, возвращает ошибку cstmerr.ErrorUserDoesNotExist.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - string: имя пользователя.
+//   - string: хэш пароля пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GetUserCredentialsByID(ctx context.Context, userID int64) (string, string, error) {
+	var name, passwordHash string
+	query := `SELECT name, password_hash FROM loyalty.users WHERE id = $1`
+	row, err := QueryRowWithRetry(ctx, DB, query, userID)
+	if err != nil {
+		return "", "", err
+	}
+	err = row.Scan(&name, &passwordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", cstmerr.ErrorUserDoesNotExist
+	} else if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
+		return "", "", err
+	}
+	return name, passwordHash, nil
+}
+
+// UpdateUserPassword сохраняет новый хэш пароля пользователя и увеличивает версию его токенов,
+// тем самым делая недействительными все ранее выданные токены.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - passwordHash: новый хэш пароля.
+//
+// Возвращает:
+//   - int64: новая версия токенов пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) (int64, error) {
+	query := `
+		UPDATE loyalty.users
+		SET password_hash = $1, token_version = token_version + 1
+		WHERE id = $2
+		RETURNING token_version`
+	row, err := QueryRowWithRetry(ctx, DB, query, passwordHash, userID)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to update user password", zap.Error(err))
+		return 0, err
+	}
+	var tokenVersion int64
+	err = row.Scan(&tokenVersion)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, cstmerr.ErrorUserDoesNotExist
+	} else if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan result", zap.Error(err))
+		return 0, err
+	}
+	config.LoggerFromContext(ctx).Info("User password updated", zap.Int64("user_id", userID))
+	return tokenVersion, nil
+}
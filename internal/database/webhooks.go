@@ -0,0 +1,538 @@
+// Package database предоставляет функции для работы с базой данных в системе лояльности.
+// Включает функции для работы с подписками на вебхуки, исходящими событиями и журналом доставок
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// Типы событий, доставляемых вебхуками.
+const (
+	WebhookEventOrderProcessed   = "order.processed"    // заказ обработан и баллы начислены
+	WebhookEventWithdrawalCreate = "withdrawal.created" // баллы списаны в счет заказа
+)
+
+// Состояния доставки вебхука.
+const (
+	WebhookDeliveryPending   = "pending"   // ожидает очередной попытки
+	WebhookDeliveryDelivered = "delivered" // получатель ответил кодом 2xx
+	WebhookDeliveryDead      = "dead"      // попытки исчерпаны, требуется ручной повтор
+)
+
+type WebhookSubscription struct {
+	ID         int64
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedBy  int64
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// WebhookDelivery описывает доставку события одной подписке.
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	OutboxID       int64
+	EventType      string
+	Payload        json.RawMessage
+	EventCreatedAt time.Time
+	URL            string
+	Secret         string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      *string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	AttemptLog     []WebhookAttempt
+}
+
+// WebhookAttempt описывает одну попытку доставки.
+type WebhookAttempt struct {
+	DeliveryID int64
+	Attempt    int
+	StatusCode *int
+	Error      *string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// WithdrawalEventPayload описывает данные события о списании баллов.
+type WithdrawalEventPayload struct {
+	Order string  `json:"order"`
+	Sum   float64 `json:"sum"`
+}
+
+// CreateWebhookSubscription сохраняет подписку на вебхуки и запись в журнале действий администратора.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - subscription: подписка, поля ID и CreatedAt заполняются базой данных.
+//
+// Возвращает:
+//   - WebhookSubscription: сохранённая подписка.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func CreateWebhookSubscription(ctx context.Context, subscription WebhookSubscription) (WebhookSubscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return WebhookSubscription{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.webhook_subscriptions (url, secret, event_types, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		subscription.URL, subscription.Secret, pq.Array(subscription.EventTypes), subscription.CreatedBy)
+	if err != nil {
+		return WebhookSubscription{}, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	if err = row.Scan(&subscription.ID, &subscription.CreatedAt); err != nil {
+		return WebhookSubscription{}, fmt.Errorf("failed to scan webhook subscription: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: subscription.CreatedBy,
+		Action:  "webhook_create",
+		Details: map[string]interface{}{
+			"subscription_id": subscription.ID,
+			"url":             subscription.URL,
+			"event_types":     subscription.EventTypes,
+		},
+	})
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return WebhookSubscription{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Webhook subscription created", zap.Int64("subscription_id", subscription.ID), zap.String("url", subscription.URL))
+	return subscription, nil
+}
+
+// ListWebhookSubscriptions возвращает все подписки на вебхуки, включая отозванные. Секреты не возвращаются.
+//
+// Возвращает:
+//   - []WebhookSubscription: подписки, новые первыми.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	query := `
+		SELECT id, url, event_types, created_by, created_at, revoked_at
+		FROM loyalty.webhook_subscriptions
+		ORDER BY created_at DESC, id DESC;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, query)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to list webhook subscriptions", zap.Error(err))
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []WebhookSubscription
+	for rows.Next() {
+		var s WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.URL, pq.Array(&s.EventTypes), &s.CreatedBy, &s.CreatedAt, &s.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", rows.Err())
+	}
+	return subscriptions, nil
+}
+
+// RevokeWebhookSubscription отзывает подписку на вебхуки. Новые события подписке не доставляются,
+// ожидающие доставки остаются в журнале, но диспетчер их больше не выполняет.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - subscriptionID: идентификатор подписки.
+//
+// Возвращает:
+//   - error: cstmerr.ErrorWebhookNotFound, если активная подписка не найдена, или ошибка выполнения запроса.
+func RevokeWebhookSubscription(ctx context.Context, adminID, subscriptionID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		UPDATE loyalty.webhook_subscriptions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING url`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke webhook subscription: %w", err)
+	}
+	var url string
+	if err = row.Scan(&url); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorWebhookNotFound
+			return err
+		}
+		return fmt.Errorf("failed to scan webhook subscription: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: adminID,
+		Action:  "webhook_revoke",
+		Details: map[string]interface{}{
+			"subscription_id": subscriptionID,
+			"url":             url,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	config.LoggerFromContext(ctx).Info("Webhook subscription revoked", zap.Int64("subscription_id", subscriptionID), zap.Int64("admin_id", adminID))
+	return nil
+}
+
+// recordWebhookEvent записывает событие в outbox и создает доставки для активных подписок на этот тип события.
+// Должна вызываться в транзакции, которая вносит изменение: событие уходит получателям только после ее фиксации.
+// К данным события добавляются идентификатор и логин пользователя.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса.
+//   - tx: транзакция.
+//   - eventType: тип события.
+//   - userID: идентификатор пользователя.
+//   - payload: данные события.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func recordWebhookEvent(ctx context.Context, tx ExecContexter, eventType string, userID int64, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	query := `
+		WITH event AS (
+			INSERT INTO loyalty.webhook_outbox (event_type, payload)
+			SELECT $1, jsonb_build_object('user_id', u.id, 'login', u.name) || $3::jsonb
+			FROM loyalty.users u
+			WHERE u.id = $2
+			RETURNING id
+		)
+		INSERT INTO loyalty.webhook_deliveries (outbox_id, subscription_id)
+		SELECT event.id, s.id
+		FROM event
+		JOIN loyalty.webhook_subscriptions s ON s.revoked_at IS NULL AND $1 = ANY(s.event_types)`
+	if err = ExecQueryWithRetry(ctx, tx, query, eventType, userID, string(data)); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to record webhook event", zap.Error(err), zap.String("type", eventType))
+		return fmt.Errorf("failed to record webhook event: %w", err)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries выбирает доставки, для которых наступило время очередной попытки,
+// и закрепляет их на время lease, чтобы другие экземпляры сервиса не выполнили их одновременно.
+// Если экземпляр не успеет сохранить результат, доставка снова станет доступной по истечении lease.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - limit: максимальное число доставок.
+//   - lease: время, на которое доставки закрепляются.
+//
+// Возвращает:
+//   - []WebhookDelivery: доставки вместе с событием, адресом и секретом подписки.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE loyalty.webhook_deliveries d
+			SET next_attempt_at = NOW() + make_interval(secs => $2)
+			WHERE d.id IN (
+				SELECT d2.id
+				FROM loyalty.webhook_deliveries d2
+				JOIN loyalty.webhook_subscriptions s ON s.id = d2.subscription_id
+				WHERE d2.status = 'pending' AND d2.next_attempt_at <= NOW() AND s.revoked_at IS NULL
+				ORDER BY d2.next_attempt_at
+				LIMIT $1
+				FOR UPDATE OF d2 SKIP LOCKED
+			)
+			RETURNING d.id, d.outbox_id, d.subscription_id, d.attempts
+		)
+		SELECT c.id, c.outbox_id, c.subscription_id, c.attempts, o.event_type, o.payload, o.created_at, s.url, s.secret
+		FROM claimed c
+		JOIN loyalty.webhook_outbox o ON o.id = c.outbox_id
+		JOIN loyalty.webhook_subscriptions s ON s.id = c.subscription_id
+		ORDER BY c.id`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	rows, err := QueryRowsWithRetry(ctx, DB, query, limit, lease.Seconds())
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to claim webhook deliveries", zap.Error(err))
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload []byte
+		if err = rows.Scan(&d.ID, &d.OutboxID, &d.SubscriptionID, &d.Attempts, &d.EventType, &payload, &d.EventCreatedAt, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		d.Status = WebhookDeliveryPending
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// SaveWebhookAttempt сохраняет результат попытки доставки и новое состояние доставки.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - attempt: результат попытки.
+//   - status: новое состояние доставки.
+//   - nextAttemptAt: время следующей попытки, учитывается для состояния WebhookDeliveryPending.
+//
+// Возвращает:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func SaveWebhookAttempt(ctx context.Context, attempt WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)`,
+		attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.Duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook attempt: %w", err)
+	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		UPDATE loyalty.webhook_deliveries
+		SET status = $2,
+			attempts = $3,
+			next_attempt_at = $4,
+			last_status_code = $5,
+			last_error = $6,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END
+		WHERE id = $1`,
+		attempt.DeliveryID, status, attempt.Attempt, nextAttemptAt, attempt.StatusCode, attempt.Error)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries возвращает журнал доставок подписки вместе с попытками.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - subscriptionID: идентификатор подписки.
+//   - status: состояние доставок, пустая строка - все состояния.
+//   - limit: максимальное число доставок.
+//
+// Возвращает:
+//   - []WebhookDelivery: доставки, новые первыми.
+//   - error: cstmerr.ErrorWebhookNotFound, если подписка не найдена, или ошибка выполнения запроса.
+func ListWebhookDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) ([]WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row, err := QueryRowWithRetry(ctx, DB, `SELECT EXISTS (SELECT 1 FROM loyalty.webhook_subscriptions WHERE id = $1)`, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	var exists bool
+	if err = row.Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+	}
+	if !exists {
+		return nil, cstmerr.ErrorWebhookNotFound
+	}
+
+	rows, err := QueryRowsWithRetry(ctx, DB, `
+		SELECT d.id, d.outbox_id, d.subscription_id, o.event_type, o.payload, o.created_at,
+			d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at
+		FROM loyalty.webhook_deliveries d
+		JOIN loyalty.webhook_outbox o ON o.id = d.outbox_id
+		WHERE d.subscription_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.id DESC
+		LIMIT $3`, subscriptionID, status, limit)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to list webhook deliveries", zap.Error(err))
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	index := make(map[int64]int)
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload []byte
+		if err = rows.Scan(&d.ID, &d.OutboxID, &d.SubscriptionID, &d.EventType, &payload, &d.EventCreatedAt,
+			&d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		index[d.ID] = len(deliveries)
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]int64, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.ID
+	}
+	attempts, err := QueryRowsWithRetry(ctx, DB, `
+		SELECT delivery_id, attempt, status_code, error, duration_ms, created_at
+		FROM loyalty.webhook_delivery_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY delivery_id, id`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook attempts: %w", err)
+	}
+	defer attempts.Close()
+
+	for attempts.Next() {
+		var a WebhookAttempt
+		var durationMS int64
+		if err = attempts.Scan(&a.DeliveryID, &a.Attempt, &a.StatusCode, &a.Error, &durationMS, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook attempt: %w", err)
+		}
+		a.Duration = time.Duration(durationMS) * time.Millisecond
+		i := index[a.DeliveryID]
+		deliveries[i].AttemptLog = append(deliveries[i].AttemptLog, a)
+	}
+	if err = attempts.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook attempts: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery возвращает доставку в состоянии dead в очередь: счетчик попыток сбрасывается,
+// и диспетчер выполнит ее при следующем проходе. Действие записывается в журнал администратора.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - deliveryID: идентификатор доставки.
+//
+// Возвращает:
+//   - error: cstmerr.ErrorWebhookDeliveryNotFound, если доставка не найдена или не в состоянии dead,
+//     или ошибка выполнения запроса.
+func RetryWebhookDelivery(ctx context.Context, adminID, deliveryID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
+			}
+		}
+	}()
+
+	row, err := QueryRowWithRetry(ctx, tx, `
+		UPDATE loyalty.webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+		RETURNING subscription_id`, deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to retry webhook delivery: %w", err)
+	}
+	var subscriptionID int64
+	if err = row.Scan(&subscriptionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorWebhookDeliveryNotFound
+			return err
+		}
+		return fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+
+	err = insertAuditRecord(ctx, tx, AuditRecord{
+		AdminID: adminID,
+		Action:  "webhook_retry",
+		Details: map[string]interface{}{
+			"delivery_id":     deliveryID,
+			"subscription_id": subscriptionID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
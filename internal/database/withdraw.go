@@ -4,71 +4,99 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
 )
 
 type Withdrawal struct {
 	OrderNumber string
 	Sum         float64
 	ProcessedAt time.Time
+	Merchant    string  // мерчант, в счет заказа которого списаны баллы, пустая строка - не указан
+	Refunded    float64 // сумма возвратов по списанию, заполняется только StreamUserWithdrawals
 }
 
 // RegisterWithdraw регистрирует вывод баланса пользователя
+// Баланс проверяется в той же транзакции под блокировкой пользователя, поэтому параллельные списания
+// и переводы не уводят его в минус. Номер заказа списания должен быть новым: уже известный заказ
+// (загруженный для начисления или использованный в другом списании) не перезаписывается.
 // Если произошла ошибка при выполнении запроса, программа завершается с кодом ошибки.
 // В случае успеха, возвращает nil.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //   - orderNumber: идентификатор заказа.
 //   - sum: сумма вывода.
+//   - merchant: мерчант, в счет заказа которого списываются баллы, пустая строка - не указан.
 //
 // Возвращает:
-//   - error: ошибка, если произошла ошибка при выполнении запроса.
-func RegisterWithdraw(userID int64, orderNumber string, sum float64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//   - error: cstmerr.ErrorInsufficientBalance, если баланса недостаточно, cstmerr.ErrorMerchantNotFound,
+//     если у мерчанта нет действующего API-ключа, cstmerr.ErrorOrderAlreadyExists, если заказ с таким
+//     номером уже существует, или ошибка выполнения запроса.
+func RegisterWithdraw(ctx context.Context, userID int64, orderNumber string, sum float64, merchant string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	tx, err := DB.BeginTx(ctx, nil)
 
 	if err != nil {
-		config.Logger.Error("Failed to start transaction", zap.Error(err))
+		config.LoggerFromContext(ctx).Error("Failed to start transaction", zap.Error(err))
 		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer func() {
 		if err != nil {
 			if rbErr := tx.Rollback(); rbErr != nil {
-				config.Logger.Error("Failed to rollback transaction", zap.Error(rbErr))
+				config.LoggerFromContext(ctx).Error("Failed to rollback transaction", zap.Error(rbErr))
 			}
 		}
 	}()
 
-	query := `
-		INSERT INTO loyalty.bonuses (order_id, withdrawn)
-		VALUES ($1, $2)
-		ON CONFLICT (order_id) 
-		DO UPDATE SET withdrawn = EXCLUDED.withdrawn
-		returning order_id
-		`
+	if merchant != "" {
+		var exists bool
+		if exists, err = merchantExists(ctx, tx, merchant); err != nil {
+			return err
+		}
+		if !exists {
+			err = cstmerr.ErrorMerchantNotFound
+			return err
+		}
+	}
+
+	if err = lockBalanceForDebit(ctx, tx, userID, sum); err != nil {
+		return err
+	}
 
-	var orderID int
-	row, err := QueryRowWithRetry(ctx, tx, query, orderNumber, sum)
+	// Заказ создается первым: конфликт по номеру означает, что заказ уже существует, и его статус,
+	// начисление или списание перезаписывать нельзя
+	var orderID int64
+	row, err := QueryRowWithRetry(ctx, tx, `
+		INSERT INTO loyalty.orders (id, status) VALUES ($1, 4)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id`, orderNumber)
 	if err != nil {
-		return fmt.Errorf("failed to get order ID: %w", err)
+		return fmt.Errorf("failed to insert order: %w", err)
 	}
 	if err = row.Scan(&orderID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = cstmerr.ErrorOrderAlreadyExists
+			return err
+		}
 		return fmt.Errorf("failed to scan order ID: %w", err)
 	}
+
+	err = ExecQueryWithRetry(ctx, tx, `
+		INSERT INTO loyalty.bonuses (order_id, withdrawn, merchant_name)
+		VALUES ($1, $2, NULLIF($3, ''))`, orderID, sum, merchant)
 	if err != nil {
-		return fmt.Errorf("failed to insert order: %w", err)
-	}
-	err = ExecQueryWithRetry(ctx, tx, `INSERT INTO loyalty.orders (id, status) VALUES ($1, 4) on conflict (id) do update set status = EXCLUDED.status`, orderID)
-	if err != nil {
-		config.Logger.Error("Failed to update order status", zap.Error(err))
-		return fmt.Errorf("failed to update order status: %w", err)
+		return fmt.Errorf("failed to insert withdrawal: %w", err)
 	}
 
 	err = ExecQueryWithRetry(ctx, tx, `INSERT INTO loyalty.user_orders (user_id, order_id) VALUES ($1, $2)`, userID, orderID)
@@ -76,6 +104,19 @@ func RegisterWithdraw(userID int64, orderNumber string, sum float64) error {
 		return fmt.Errorf("failed to link user and order: %w", err)
 	}
 
+	if err = consumeLots(ctx, tx, userID, sum); err != nil {
+		return err
+	}
+
+	if err = recordBalanceEvent(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	payload := WithdrawalEventPayload{Order: orderNumber, Sum: sum}
+	if err = recordWebhookEvent(ctx, tx, WebhookEventWithdrawalCreate, userID, payload); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -83,52 +124,177 @@ func RegisterWithdraw(userID int64, orderNumber string, sum float64) error {
 	return nil
 }
 
+// WithdrawalsFilter описывает фильтры и параметры постраничной выборки списаний пользователя.
+// Списания выбираются от новых к старым по ключу (created_at, order_id).
+type WithdrawalsFilter struct {
+	From        *time.Time // списания не раньше указанного времени
+	To          *time.Time // списания раньше указанного времени
+	AfterTime   *time.Time // время списания-курсора
+	AfterNumber int64      // номер заказа списания-курсора
+	Limit       int        // максимальное количество списаний, 0 - без ограничения
+}
+
+// WithdrawalsSummary описывает итоги по списаниям пользователя.
+type WithdrawalsSummary struct {
+	Count    int64
+	Total    float64 // сумма списаний за вычетом возвратов
+	Refunded float64 // сумма возвратов по этим списаниям
+}
+
 // FetchUserWithdrawals возвращает список выводов баланса пользователя
 // Если произошла ошибка при выполнении запроса, программа завершается с кодом ошибки.
 // В случае успеха, возвращает список выводов баланса пользователя.
 //
 // Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - []Withdrawal: список выводов баланса пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchUserWithdrawals(ctx context.Context, userID int64) ([]Withdrawal, error) {
+	return FetchUserWithdrawalsPage(ctx, userID, WithdrawalsFilter{})
+}
+
+// FetchUserWithdrawalsPage возвращает список выводов баланса пользователя с учетом фильтров и постраничной выборки
+//
+// Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
+//   - filter: фильтры и параметры постраничной выборки.
 //
 // Возвращает:
 //   - []Withdrawal: список выводов баланса пользователя.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func FetchUserWithdrawals(userID int64) ([]Withdrawal, error) {
-	query := `
+func FetchUserWithdrawalsPage(ctx context.Context, userID int64, filter WithdrawalsFilter) ([]Withdrawal, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var withdrawals []Withdrawal
+	err := StreamUserWithdrawals(ctx, userID, filter, func(withdrawal Withdrawal) error {
+		withdrawals = append(withdrawals, withdrawal)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withdrawals, nil
+}
+
+// StreamUserWithdrawals читает списания пользователя по фильтру и передает их в fn по одному,
+// не загружая всю выборку в память. Если fn возвращает ошибку, чтение прекращается.
+// Для каждого списания сразу считается сумма возвратов по нему.
+//
+// Параметры:
+//   - ctx: контекст выполнения запроса.
+//   - userID: идентификатор пользователя.
+//   - filter: фильтры и параметры постраничной выборки.
+//   - fn: обработчик списания.
+//
+// Возвращает:
+//   - error: ошибка выполнения запроса или ошибка fn.
+func StreamUserWithdrawals(ctx context.Context, userID int64, filter WithdrawalsFilter, fn func(Withdrawal) error) error {
+	conditions, args := withdrawalsConditions(userID, filter.From, filter.To)
+	if filter.AfterTime != nil {
+		args = append(args, *filter.AfterTime, filter.AfterNumber)
+		conditions = append(conditions, fmt.Sprintf("(b.created_at, b.order_id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := fmt.Sprintf(`
 		SELECT 
 		    o.id as order_number,
 		    b.withdrawn as sum,
-		    b.created_at as processed_at
+		    b.created_at as processed_at,
+		    COALESCE(b.merchant_name, '') as merchant,
+		    COALESCE((SELECT SUM(wr.amount) FROM loyalty.withdrawal_reversals wr
+		        WHERE wr.order_id = b.order_id AND wr.user_id = uo.user_id), 0) as refunded
 		FROM loyalty.bonuses b 
 		JOIN loyalty.orders o ON o.id = b.order_id
 		JOIN loyalty.user_orders uo on o.id = uo.order_id
-		WHERE uo.user_id = $1 and b.withdrawn > 0
-		ORDER BY processed_at DESC;
-	`
+		WHERE %s
+		ORDER BY b.created_at DESC, b.order_id DESC`, strings.Join(conditions, " AND "))
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	var withdrawals []Withdrawal
-	rows, err := QueryRowsWithRetry(ctx, DB, query, userID)
+	rows, err := QueryRowsWithRetry(ctx, DB, query, args...)
 	if err != nil {
-		config.Logger.Error("Failed to fetch user withdrawals", zap.Error(err))
-		return nil, err
+		config.LoggerFromContext(ctx).Error("Failed to fetch user withdrawals", zap.Error(err))
+		return err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var withdrawal Withdrawal
-		if err := rows.Scan(&withdrawal.OrderNumber, &withdrawal.Sum, &withdrawal.ProcessedAt); err != nil {
-			config.Logger.Error("Failed to scan row", zap.Error(err))
-			return nil, err
+		if err := rows.Scan(&withdrawal.OrderNumber, &withdrawal.Sum, &withdrawal.ProcessedAt, &withdrawal.Merchant, &withdrawal.Refunded); err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to scan row", zap.Error(err))
+			return err
+		}
+		if err := fn(withdrawal); err != nil {
+			return err
 		}
-		withdrawals = append(withdrawals, withdrawal)
 	}
 	if rows.Err() != nil {
-		config.Logger.Error("Failed to fetch user withdrawals", zap.Error(rows.Err()))
-		return nil, rows.Err()
+		config.LoggerFromContext(ctx).Error("Failed to fetch user withdrawals", zap.Error(rows.Err()))
+		return rows.Err()
 	}
 
-	return withdrawals, nil
+	return nil
+}
+
+// FetchUserWithdrawalsSummary возвращает количество и общую сумму списаний пользователя за период.
+// Общая сумма считается за вычетом возвратов по этим списаниям, как withdrawn в балансе пользователя.
+// Курсор и ограничение размера страницы на итоги не влияют.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - from: начало периода, nil - без ограничения.
+//   - to: конец периода (не включительно), nil - без ограничения.
+//
+// Возвращает:
+//   - WithdrawalsSummary: итоги по списаниям.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchUserWithdrawalsSummary(ctx context.Context, userID int64, from, to *time.Time) (WithdrawalsSummary, error) {
+	conditions, args := withdrawalsConditions(userID, from, to)
+	query := fmt.Sprintf(`
+		SELECT COUNT(*), COALESCE(SUM(b.withdrawn), 0),
+			COALESCE(SUM((SELECT SUM(wr.amount) FROM loyalty.withdrawal_reversals wr
+				WHERE wr.order_id = b.order_id AND wr.user_id = uo.user_id)), 0)
+		FROM loyalty.bonuses b
+		JOIN loyalty.user_orders uo on b.order_id = uo.order_id
+		WHERE %s`, strings.Join(conditions, " AND "))
 
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	row, err := QueryRowWithRetry(ctx, DB, query, args...)
+	if err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to fetch user withdrawals summary", zap.Error(err))
+		return WithdrawalsSummary{}, err
+	}
+
+	var summary WithdrawalsSummary
+	var gross float64
+	if err = row.Scan(&summary.Count, &gross, &summary.Refunded); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to scan user withdrawals summary", zap.Error(err))
+		return WithdrawalsSummary{}, err
+	}
+	summary.Total = gross - summary.Refunded
+	return summary, nil
+}
+
+// withdrawalsConditions собирает общие условия выборки списаний пользователя за период.
+func withdrawalsConditions(userID int64, from, to *time.Time) ([]string, []interface{}) {
+	args := []interface{}{userID}
+	conditions := []string{"uo.user_id = $1", "b.withdrawn > 0"}
+	if from != nil {
+		args = append(args, *from)
+		conditions = append(conditions, fmt.Sprintf("b.created_at >= $%d", len(args)))
+	}
+	if to != nil {
+		args = append(args, *to)
+		conditions = append(conditions, fmt.Sprintf("b.created_at < $%d", len(args)))
+	}
+	return conditions, args
 }
@@ -1,15 +1,77 @@
 package cstmerr
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
-	ErrorConnection           = errors.New("connection error")
-	ErrorServer               = errors.New("server error")
-	ErrorNonRetriable         = errors.New("non retriable error")
-	ErrorRetriable            = errors.New("retriable error")
-	ErrorNonRetriablePostgres = errors.New("non retriable postgres error")
-	ErrorRetriablePostgres    = errors.New("retriable postgres error")
-	ErrorUserAlreadyExists    = errors.New("user already exists")
-	ErrorUserDoesNotExist     = errors.New("user does not exist")
-	ErrOrderNotFound          = errors.New("order not found")
+	ErrorConnection                 = errors.New("connection error")
+	ErrorServer                     = errors.New("server error")
+	ErrorNonRetriable               = errors.New("non retriable error")
+	ErrorRetriable                  = errors.New("retriable error")
+	ErrorNonRetriablePostgres       = errors.New("non retriable postgres error")
+	ErrorRetriablePostgres          = errors.New("retriable postgres error")
+	ErrorUserAlreadyExists          = errors.New("user already exists")
+	ErrorUserDoesNotExist           = errors.New("user does not exist")
+	ErrOrderNotFound                = errors.New("order not found")
+	ErrorAccountLocked              = errors.New("account temporarily locked")
+	ErrorTooManyLoginAttempts       = errors.New("too many login attempts")
+	ErrorInvalidPassword            = errors.New("invalid password")
+	ErrorWeakPassword               = errors.New("password does not meet requirements")
+	ErrorInvalidResetToken          = errors.New("invalid or expired reset token")
+	ErrorInsufficientBalance        = errors.New("insufficient balance")
+	ErrorInvalidReasonCode          = errors.New("invalid reason code")
+	ErrorInvalidAmount              = errors.New("invalid amount")
+	ErrorInvalidAPIKey              = errors.New("invalid API key")
+	ErrorInvalidScope               = errors.New("invalid scope")
+	ErrorAPIKeyNotFound             = errors.New("API key not found")
+	ErrorOrderIsWithdrawal          = errors.New("order is a withdrawal")
+	ErrorInvalidCursor              = errors.New("invalid cursor")
+	ErrorInvalidFilter              = errors.New("invalid filter")
+	ErrorWithdrawalNotFound         = errors.New("withdrawal not found")
+	ErrorRefundExceedsAmount        = errors.New("refund exceeds withdrawn amount")
+	ErrorReasonRequired             = errors.New("reason is required")
+	ErrorInvalidRequest             = errors.New("invalid request")
+	ErrorUnauthorized               = errors.New("unauthorized")
+	ErrorForbidden                  = errors.New("insufficient permissions")
+	ErrorInvalidCredentials         = errors.New("invalid username or password")
+	ErrorIdempotencyKeyReused       = errors.New("idempotency key is already used with a different request")
+	ErrorIdempotencyKeyInProgress   = errors.New("request with this idempotency key is still in progress")
+	ErrorInvalidOrderNumber         = errors.New("invalid order number")
+	ErrorOrderAlreadyUploaded       = errors.New("order already uploaded by you")
+	ErrorOrderUploadedByAnotherUser = errors.New("order already uploaded by another user")
+	ErrorInvalidWebhook             = errors.New("invalid webhook subscription")
+	ErrorWebhookNotFound            = errors.New("webhook subscription not found")
+	ErrorWebhookDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrorInvalidTransfer            = errors.New("invalid transfer")
+	ErrorTransferLimitExceeded      = errors.New("transfer limit exceeded")
+	ErrorInvalidCampaign            = errors.New("invalid campaign")
+	ErrorCampaignNotFound           = errors.New("campaign not found")
+	ErrorCampaignCodeTaken          = errors.New("promo code is used by another active campaign")
+	ErrorPromoCodeNotFound          = errors.New("promo code not found")
+	ErrorPromoCodeInactive          = errors.New("promo code is not active")
+	ErrorPromoLimitReached          = errors.New("promo code redemption limit reached")
+	ErrorCampaignBudgetExhausted    = errors.New("campaign budget exhausted")
+	ErrorInvalidReferralCode        = errors.New("invalid referral code")
+	ErrorReferralLimitReached       = errors.New("referral limit reached")
+	ErrorMerchantNotFound           = errors.New("merchant not found")
+	ErrorOrderAlreadyExists         = errors.New("order already exists")
 )
+
+// LoginBlockedError описывает отказ во входе из-за превышения числа неудачных попыток.
+// Оборачивает ErrorAccountLocked или ErrorTooManyLoginAttempts и хранит время,
+// через которое можно повторить попытку.
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.RetryAfter)
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,187 @@
+// Package events предоставляет брокер событий пользователей для потоковой доставки клиентам.
+// События записываются в базу данных вместе с изменением и публикуются через Postgres NOTIFY,
+// поэтому подписчики получают их независимо от того, какой экземпляр сервиса внес изменение.
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+)
+
+// subscriptionBuffer - размер буфера событий одного подписчика.
+const subscriptionBuffer = 64
+
+// Default хранит брокер событий, используемый по умолчанию.
+var Default = NewBroker()
+
+// Subscription описывает подписку на события одного пользователя.
+type Subscription struct {
+	C      <-chan database.UserEvent // канал событий, закрывается при отмене подписки
+	ch     chan database.UserEvent
+	userID int64
+	broker *Broker
+	once   sync.Once
+}
+
+// Close отменяет подписку. Повторный вызов ничего не делает.
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+// Broker рассылает события пользователей подписчикам внутри процесса.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[int64]map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewBroker создает брокер без подписчиков.
+//
+// Возвращает:
+//   - *Broker: брокер событий.
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[int64]map[*Subscription]struct{})}
+}
+
+// Subscribe подписывает на события пользователя.
+// Подписку необходимо закрыть вызовом Close. После закрытия брокера канал подписки сразу закрыт.
+//
+// Параметры:
+//   - userID: идентификатор пользователя.
+//
+// Возвращает:
+//   - *Subscription: подписка.
+func (b *Broker) Subscribe(userID int64) *Subscription {
+	ch := make(chan database.UserEvent, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, userID: userID, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.once.Do(func() { close(ch) })
+		return sub
+	}
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+	return sub
+}
+
+// Publish рассылает событие подписчикам его пользователя.
+// Подписчик, который не успевает читать события, отключается: его канал закрывается,
+// и клиент может возобновить поток по Last-Event-ID без потери событий.
+//
+// Параметры:
+//   - event: событие.
+func (b *Broker) Publish(event database.UserEvent) {
+	var slow []*Subscription
+
+	b.mu.RLock()
+	for sub := range b.subscribers[event.UserID] {
+		select {
+		case sub.ch <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range slow {
+		config.Logger.Warn("Dropping slow event subscriber", zap.Int64("user_id", sub.userID))
+		b.unsubscribe(sub)
+	}
+}
+
+// Close закрывает все подписки и перестает принимать новые.
+// Вызывается при завершении работы сервиса, чтобы открытые потоки событий не задерживали остановку HTTP-сервера.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.disconnectAll()
+}
+
+// disconnectAll закрывает все текущие подписки. Клиенты могут возобновить поток по Last-Event-ID.
+func (b *Broker) disconnectAll() {
+	var subs []*Subscription
+	b.mu.RLock()
+	for _, userSubs := range b.subscribers {
+		for sub := range userSubs {
+			subs = append(subs, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		b.unsubscribe(sub)
+	}
+}
+
+// unsubscribe удаляет подписку и закрывает ее канал.
+func (b *Broker) unsubscribe(sub *Subscription) {
+	sub.once.Do(func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[sub.userID], sub)
+		if len(b.subscribers[sub.userID]) == 0 {
+			delete(b.subscribers, sub.userID)
+		}
+		close(sub.ch)
+	})
+}
+
+// Listen подписывается на канал database.UserEventsChannel и публикует полученные события в брокер.
+// Блокируется до остановки stop. При разрыве соединения переподключается автоматически.
+// Уведомления, отправленные, пока соединения не было, теряются, поэтому после переподключения
+// все подписки закрываются: клиенты переподключаются с Last-Event-ID и получают пропущенные события из user_events.
+//
+// Параметры:
+//   - connStr: строка подключения к базе данных.
+//   - stop: канал, закрытие которого останавливает прослушивание.
+//
+// Возвращает:
+//   - error: ошибка, если не удалось подписаться на канал.
+func (b *Broker) Listen(connStr string, stop <-chan struct{}) error {
+	listener := pq.NewListener(connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			config.Logger.Error("User events listener error", zap.Error(err))
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(database.UserEventsChannel); err != nil {
+		return err
+	}
+	config.Logger.Info("Listening for user events", zap.String("channel", database.UserEventsChannel))
+
+	for {
+		select {
+		case notification := <-listener.Notify:
+			// После переподключения приходит nil: закрываем подписки, чтобы клиенты возобновили поток
+			if notification == nil {
+				config.Logger.Warn("User events listener reconnected, disconnecting subscribers")
+				b.disconnectAll()
+				continue
+			}
+			var event database.UserEvent
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				config.Logger.Error("Failed to decode user event", zap.Error(err))
+				continue
+			}
+			b.Publish(event)
+		case <-time.After(90 * time.Second):
+			// Проверка соединения, если событий давно не было
+			go listener.Ping()
+		case <-stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/database"
+)
+
+func TestBroker_PublishToUserSubscribers(t *testing.T) {
+	broker := NewBroker()
+	first := broker.Subscribe(1)
+	defer first.Close()
+	second := broker.Subscribe(1)
+	defer second.Close()
+	other := broker.Subscribe(2)
+	defer other.Close()
+
+	broker.Publish(database.UserEvent{ID: 10, UserID: 1, Type: database.UserEventOrder})
+
+	for _, sub := range []*Subscription{first, second} {
+		select {
+		case event := <-sub.C:
+			assert.Equal(t, int64(10), event.ID)
+		default:
+			t.Fatal("event was not delivered")
+		}
+	}
+	assert.Len(t, other.C, 0)
+}
+
+func TestBroker_Close(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(1)
+
+	sub.Close()
+	sub.Close()
+
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	assert.Empty(t, broker.subscribers)
+
+	// Публикация после отмены подписки не должна паниковать
+	broker.Publish(database.UserEvent{ID: 1, UserID: 1})
+}
+
+func TestBroker_CloseDisconnectsSubscribers(t *testing.T) {
+	broker := NewBroker()
+	first := broker.Subscribe(1)
+	second := broker.Subscribe(2)
+
+	broker.Close()
+
+	for _, sub := range []*Subscription{first, second} {
+		_, ok := <-sub.C
+		assert.False(t, ok)
+	}
+	assert.Empty(t, broker.subscribers)
+
+	// Подписка после закрытия брокера сразу закрыта
+	late := broker.Subscribe(1)
+	_, ok := <-late.C
+	assert.False(t, ok)
+	late.Close()
+	assert.Empty(t, broker.subscribers)
+}
+
+func TestBroker_DisconnectAllKeepsAcceptingSubscribers(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(1)
+
+	broker.disconnectAll()
+
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	resumed := broker.Subscribe(1)
+	defer resumed.Close()
+	broker.Publish(database.UserEvent{ID: 5, UserID: 1})
+	event := <-resumed.C
+	assert.Equal(t, int64(5), event.ID)
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(1)
+	defer sub.Close()
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		broker.Publish(database.UserEvent{ID: int64(i + 1), UserID: 1})
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	require.Equal(t, subscriptionBuffer, received)
+	assert.Empty(t, broker.subscribers)
+}
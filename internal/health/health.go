@@ -0,0 +1,147 @@
+// Package health предоставляет проверки готовности сервиса к приему запросов.
+// Зависимости регистрируют проверки в Checker, а /readyz возвращает результат по каждой из них.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Состояния готовности сервиса.
+const (
+	StatusReady        = "ready"         // все проверки пройдены
+	StatusNotReady     = "not_ready"     // хотя бы одна проверка не пройдена
+	StatusShuttingDown = "shutting_down" // сервис завершает работу и не принимает новые запросы
+)
+
+// Состояния отдельной проверки.
+const (
+	CheckOK     = "ok"
+	CheckFailed = "failed"
+)
+
+// checkTimeout ограничивает время выполнения одной проверки.
+const checkTimeout = 2 * time.Second
+
+// Default хранит набор проверок, используемый по умолчанию.
+var Default = NewChecker()
+
+// Check проверяет одну зависимость сервиса и возвращает ошибку, если она недоступна.
+type Check func(ctx context.Context) error
+
+// CheckResult описывает результат одной проверки.
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report описывает готовность сервиса с результатами по каждой зависимости.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Ready сообщает, готов ли сервис принимать запросы.
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Checker хранит проверки зависимостей и признак завершения работы сервиса.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+// NewChecker создает набор проверок без зарегистрированных зависимостей.
+//
+// Возвращает:
+//   - *Checker: набор проверок.
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Register добавляет проверку зависимости. Проверка с тем же именем заменяется.
+//
+// Параметры:
+//   - name: название зависимости в отчете.
+//   - check: проверка зависимости.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// SetShuttingDown отмечает, что сервис завершает работу. После вызова сервис всегда не готов.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check выполняет все проверки параллельно, каждую с ограничением по времени.
+// Во время завершения работы проверки не выполняются.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//
+// Возвращает:
+//   - Report: готовность сервиса и результаты проверок.
+func (c *Checker) Check(ctx context.Context) Report {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	checks := make([]Check, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]CheckResult, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != CheckOK {
+			report.Status = StatusNotReady
+		}
+	}
+	// Завершение работы могло начаться, пока выполнялись проверки
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// runCheck выполняет одну проверку с ограничением по времени.
+func runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: CheckOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = CheckFailed
+		result.Error = err.Error()
+	}
+	return result
+}
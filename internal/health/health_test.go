@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(context.Context) error { return nil }
+
+func TestChecker_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		wantStatus string
+		wantFailed []string
+	}{
+		{name: "no_checks", checks: map[string]Check{}, wantStatus: StatusReady},
+		{name: "all_ok", checks: map[string]Check{"database": ok, "agent": ok}, wantStatus: StatusReady},
+		{
+			name: "one_failed",
+			checks: map[string]Check{
+				"database": ok,
+				"accrual":  func(context.Context) error { return errors.New("connection refused") },
+			},
+			wantStatus: StatusNotReady,
+			wantFailed: []string{"accrual"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker()
+			for name, check := range tt.checks {
+				checker.Register(name, check)
+			}
+
+			report := checker.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Equal(t, tt.wantStatus == StatusReady, report.Ready())
+			require.Len(t, report.Checks, len(tt.checks))
+
+			var failed []string
+			for name, result := range report.Checks {
+				if result.Status == CheckFailed {
+					failed = append(failed, name)
+					assert.NotEmpty(t, result.Error)
+				}
+			}
+			assert.ElementsMatch(t, tt.wantFailed, failed)
+		})
+	}
+}
+
+func TestChecker_CheckTimeout(t *testing.T) {
+	checker := NewChecker()
+	checker.Register("slow", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Minute):
+			return nil
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := checker.Check(ctx)
+
+	assert.Equal(t, StatusNotReady, report.Status)
+	assert.Equal(t, CheckFailed, report.Checks["slow"].Status)
+}
+
+func TestChecker_ShuttingDown(t *testing.T) {
+	checker := NewChecker()
+	called := false
+	checker.Register("database", func(context.Context) error {
+		called = true
+		return nil
+	})
+
+	checker.SetShuttingDown()
+	report := checker.Check(context.Background())
+
+	assert.Equal(t, StatusShuttingDown, report.Status)
+	assert.False(t, report.Ready())
+	assert.Empty(t, report.Checks)
+	assert.False(t, called)
+}
@@ -0,0 +1,162 @@
+// Package metrics предоставляет метрики Prometheus системы лояльности.
+// Метрики регистрируются в собственном реестре и отдаются по /metrics на отдельном административном адресе.
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// namespace - общий префикс имен метрик.
+const namespace = "gophermart"
+
+// Registry - реестр, в котором зарегистрированы все метрики приложения.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	agentCycleDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "agent_cycle_duration_seconds",
+		Help:      "Duration of background agent cycles by task.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"task"})
+
+	orderBacklog = promauto.With(Registry).NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "agent_order_backlog",
+		Help:      "Number of orders waiting for accrual at the start of the last agent cycle.",
+	})
+
+	accrualRequests = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "accrual_requests_total",
+		Help:      "Number of accrual system requests by response status code, \"error\" if no response was received.",
+	}, []string{"status"})
+
+	retryAttempts = promauto.With(Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retry_attempts_total",
+		Help:      "Number of repeated attempts made after a failed database operation.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveHTTPRequest учитывает обработанный HTTP-запрос.
+//
+// Параметры:
+//   - method: HTTP-метод.
+//   - route: шаблон маршрута, например /api/user/orders/:number.
+//   - status: код ответа.
+//   - duration: время обработки запроса.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpRequestDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// ObserveAgentCycle учитывает длительность цикла фоновой задачи агента.
+//
+// Параметры:
+//   - task: название задачи.
+//   - duration: длительность цикла.
+func ObserveAgentCycle(task string, duration time.Duration) {
+	agentCycleDuration.WithLabelValues(task).Observe(duration.Seconds())
+}
+
+// SetOrderBacklog сохраняет количество заказов, ожидающих начисления.
+//
+// Параметры:
+//   - count: количество заказов.
+func SetOrderBacklog(count int) {
+	orderBacklog.Set(float64(count))
+}
+
+// ObserveAccrualRequest учитывает запрос к системе начислений.
+//
+// Параметры:
+//   - status: код ответа, 0 - ответ не получен.
+func ObserveAccrualRequest(status int) {
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	accrualRequests.WithLabelValues(code).Inc()
+}
+
+// ObserveRetryAttempt учитывает повторную попытку выполнения операции с базой данных.
+func ObserveRetryAttempt() {
+	retryAttempts.Inc()
+}
+
+// RegisterDBStats регистрирует метрики пула соединений с базой данных по данным db.Stats().
+//
+// Параметры:
+//   - db: соединение с базой данных.
+//
+// Возвращает:
+//   - error: ошибка, если метрики пула уже зарегистрированы.
+func RegisterDBStats(db *sql.DB) error {
+	if err := Registry.Register(collectors.NewDBStatsCollector(db, "loyalty")); err != nil {
+		return fmt.Errorf("failed to register database metrics: %w", err)
+	}
+	return nil
+}
+
+// Handler возвращает HTTP-обработчик, отдающий метрики реестра Registry в формате Prometheus.
+//
+// Возвращает:
+//   - http.Handler: обработчик /metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Run запускает административный HTTP-сервер с метриками по /metrics. Блокируется до остановки сервера.
+//
+// Параметры:
+//   - address: адрес, на котором принимаются соединения.
+//
+// Возвращает:
+//   - error: ошибка, если не удалось начать принимать соединения или сервер остановлен.
+func Run(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	config.Logger.Info("Starting metrics server...", zap.String("address", address))
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	return server.Serve(listener)
+}
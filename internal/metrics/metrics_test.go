@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveAccrualRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantLabel string
+	}{
+		{name: "ok", status: http.StatusOK, wantLabel: "200"},
+		{name: "too_many_requests", status: http.StatusTooManyRequests, wantLabel: "429"},
+		{name: "no_response", status: 0, wantLabel: "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(accrualRequests.WithLabelValues(tt.wantLabel))
+			ObserveAccrualRequest(tt.status)
+			assert.Equal(t, before+1, testutil.ToFloat64(accrualRequests.WithLabelValues(tt.wantLabel)))
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ObserveHTTPRequest(http.MethodGet, "/api/user/balance", http.StatusOK, 10*time.Millisecond)
+	ObserveAgentCycle("process_orders", time.Second)
+	SetOrderBacklog(3)
+	ObserveRetryAttempt()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `gophermart_http_requests_total{method="GET",route="/api/user/balance",status="200"}`)
+	assert.Contains(t, body, `gophermart_http_request_duration_seconds_bucket{method="GET",route="/api/user/balance",status="200"`)
+	assert.Contains(t, body, `gophermart_agent_cycle_duration_seconds_count{task="process_orders"}`)
+	assert.Contains(t, body, "gophermart_agent_order_backlog 3")
+	assert.Contains(t, body, "gophermart_retry_attempts_total")
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,73 @@
+// Package notify предоставляет механизм доставки уведомлений пользователям системы лояльности.
+// Конкретный способ доставки скрыт за интерфейсом Notifier, что позволяет подменять его
+// (например, файл или лог для локального запуска, почта или SMS в боевом окружении).
+package notify
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// Notifier доставляет уведомление пользователю.
+type Notifier interface {
+	Notify(recipient, subject, message string) error
+}
+
+// Default хранит используемый по умолчанию способ доставки уведомлений.
+var Default Notifier = LogNotifier{}
+
+// Init выбирает способ доставки уведомлений.
+// Если указан путь к файлу, уведомления дописываются в файл, иначе пишутся в лог.
+//
+// Параметры:
+//   - filePath: путь к файлу для уведомлений, может быть пустым.
+func Init(filePath string) {
+	if filePath == "" {
+		Default = LogNotifier{}
+		return
+	}
+	Default = &FileNotifier{Path: filePath}
+}
+
+// LogNotifier пишет уведомления в лог приложения.
+type LogNotifier struct{}
+
+// Notify пишет уведомление в лог.
+func (LogNotifier) Notify(recipient, subject, message string) error {
+	config.Logger.Info("Notification",
+		zap.String("recipient", recipient),
+		zap.String("subject", subject),
+		zap.String("message", message),
+	)
+	return nil
+}
+
+// FileNotifier дописывает уведомления в текстовый файл.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Notify дописывает уведомление в конец файла.
+func (n *FileNotifier) Notify(recipient, subject, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), recipient, subject, message)
+	if err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileNotifier_Notify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.log")
+	notifier := &FileNotifier{Path: path}
+
+	require.NoError(t, notifier.Notify("first_user", "Hello", "first message"))
+	require.NoError(t, notifier.Notify("second_user", "Hello", "second message"))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: first_user")
+	assert.Contains(t, string(content), "first message")
+	assert.Contains(t, string(content), "To: second_user")
+	assert.Contains(t, string(content), "second message")
+}
@@ -9,12 +9,16 @@ import (
 
 	"github.com/FollowLille/loyalty/internal/config"
 	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/metrics"
 )
 
 func Retry(f func() error) error {
 
 	var err error
-	for _, delay := range config.DatabaseRetryDelays {
+	for i, delay := range config.DatabaseRetryDelays {
+		if i > 0 {
+			metrics.ObserveRetryAttempt()
+		}
 		err = f()
 		if err == nil {
 			return nil
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
+	"github.com/FollowLille/loyalty/internal/utils"
+)
+
+// AdjustmentReasonCodes перечисляет допустимые коды причин ручной корректировки баланса.
+var AdjustmentReasonCodes = []string{
+	"GOODWILL",     // компенсация по обращению клиента
+	"CORRECTION",   // исправление ошибки начисления или списания
+	"COMPENSATION", // компенсация за сбой в работе сервиса
+	"FRAUD",        // списание баллов, полученных мошенническим путём
+	"MIGRATION",    // перенос баллов из другой системы
+}
+
+type AdjustmentRequest struct {
+	Amount     float64 `json:"amount"`
+	ReasonCode string  `json:"reason_code"`
+	Comment    string  `json:"comment"`
+}
+
+type AdjustmentResponse struct {
+	ID         int64     `json:"id"`
+	Amount     float64   `json:"amount"`
+	ReasonCode string    `json:"reason_code"`
+	Comment    string    `json:"comment,omitempty"`
+	AdminID    int64     `json:"admin_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type UserOverview struct {
+	ID          int64                `json:"id"`
+	Login       string               `json:"login"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Roles       []string             `json:"roles"`
+	Balance     UserBalance          `json:"balance"`
+	Orders      []Order              `json:"orders"`
+	Withdrawals []WithdrawResponse   `json:"withdrawals"`
+	Adjustments []AdjustmentResponse `json:"adjustments"`
+}
+
+// GetUserOverview выполняет бизнес-логику для получения сводной информации о пользователе для администратора:
+// баланс, заказы, списания и ручные корректировки.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - login: имя пользователя.
+//
+// Возвращаемое значение:
+//   - overview: сводная информация о пользователе.
+//   - error: ошибка, если пользователь не найден или произошла ошибка при выполнении запроса.
+func GetUserOverview(ctx context.Context, login string) (UserOverview, error) {
+	ctx, span := tracing.Start(ctx, "services.GetUserOverview")
+	defer span.End()
+
+	user, err := database.GetUserByName(ctx, login)
+	if err != nil {
+		return UserOverview{}, err
+	}
+
+	roles, err := database.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return UserOverview{}, fmt.Errorf("failed to fetch user roles: %w", err)
+	}
+
+	balance, err := FetchUserBalance(ctx, user.ID)
+	if err != nil {
+		return UserOverview{}, err
+	}
+
+	orders, err := database.GetUserOrders(ctx, user.ID)
+	if err != nil {
+		return UserOverview{}, fmt.Errorf("failed to fetch orders: %w", err)
+	}
+
+	withdrawals, err := FetchWithdrawals(ctx, user.ID)
+	if err != nil {
+		return UserOverview{}, err
+	}
+
+	adjustments, err := database.FetchUserAdjustments(ctx, user.ID)
+	if err != nil {
+		return UserOverview{}, fmt.Errorf("failed to fetch adjustments: %w", err)
+	}
+
+	overview := UserOverview{
+		ID:          user.ID,
+		Login:       user.Name,
+		CreatedAt:   user.CreatedAt,
+		Roles:       roles,
+		Balance:     balance,
+		Orders:      make([]Order, len(orders)),
+		Withdrawals: withdrawals,
+		Adjustments: make([]AdjustmentResponse, len(adjustments)),
+	}
+	for i, order := range orders {
+		overview.Orders[i] = Order{
+			Number:     order.Number,
+			Status:     order.Status,
+			Accrual:    order.Accrual,
+			UploadedAt: order.UploadedAt,
+		}
+	}
+	for i, adjustment := range adjustments {
+		overview.Adjustments[i] = newAdjustmentResponse(adjustment)
+	}
+	return overview, nil
+}
+
+// AdjustBalance выполняет бизнес-логику для ручного начисления или списания баллов администратором.
+// Положительная сумма начисляет баллы, отрицательная списывает.
+// Код причины обязателен и должен входить в AdjustmentReasonCodes.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - login: имя пользователя, чей баланс корректируется.
+//   - req: параметры корректировки.
+//
+// Возвращаемое значение:
+//   - adjustment: сохранённая корректировка.
+//   - error: ошибка, если параметры некорректны или произошла ошибка при выполнении запроса.
+func AdjustBalance(ctx context.Context, adminID int64, login string, req AdjustmentRequest) (AdjustmentResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.AdjustBalance")
+	defer span.End()
+
+	if req.Amount == 0 || math.IsNaN(req.Amount) || math.IsInf(req.Amount, 0) {
+		return AdjustmentResponse{}, cstmerr.ErrorInvalidAmount
+	}
+	if !isValidReasonCode(req.ReasonCode) {
+		return AdjustmentResponse{}, fmt.Errorf("%w: allowed codes are %s", cstmerr.ErrorInvalidReasonCode, strings.Join(AdjustmentReasonCodes, ", "))
+	}
+
+	user, err := database.GetUserByName(ctx, login)
+	if err != nil {
+		return AdjustmentResponse{}, err
+	}
+
+	adjustment, err := database.CreateBalanceAdjustment(ctx, database.BalanceAdjustment{
+		UserID:     user.ID,
+		Amount:     req.Amount,
+		ReasonCode: req.ReasonCode,
+		Comment:    req.Comment,
+		AdminID:    adminID,
+	})
+	if err != nil {
+		return AdjustmentResponse{}, err
+	}
+	return newAdjustmentResponse(adjustment), nil
+}
+
+// ReprocessOrder выполняет бизнес-логику для возврата заказа в статус NEW,
+// чтобы агент заново запросил начисление по нему.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - orderNumber: номер заказа.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если номер заказа некорректен, заказ не найден, начисление по нему уже потрачено
+//     или произошла ошибка при выполнении запроса.
+func ReprocessOrder(ctx context.Context, adminID int64, orderNumber string) error {
+	ctx, span := tracing.Start(ctx, "services.ReprocessOrder")
+	defer span.End()
+
+	if !utils.CheckLunar(orderNumber) {
+		return cstmerr.ErrorInvalidOrderNumber
+	}
+	return database.SetOrderStatusByAdmin(ctx, adminID, orderNumber, "NEW", "order_reprocess")
+}
+
+// InvalidateOrder выполняет бизнес-логику для перевода заказа в статус INVALID.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - orderNumber: номер заказа.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если номер заказа некорректен, заказ не найден, начисление по нему уже потрачено
+//     или произошла ошибка при выполнении запроса.
+func InvalidateOrder(ctx context.Context, adminID int64, orderNumber string) error {
+	ctx, span := tracing.Start(ctx, "services.InvalidateOrder")
+	defer span.End()
+
+	if !utils.CheckLunar(orderNumber) {
+		return cstmerr.ErrorInvalidOrderNumber
+	}
+	return database.SetOrderStatusByAdmin(ctx, adminID, orderNumber, "INVALID", "order_invalidate")
+}
+
+// isValidReasonCode проверяет, входит ли код причины в AdjustmentReasonCodes.
+func isValidReasonCode(code string) bool {
+	for _, c := range AdjustmentReasonCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func newAdjustmentResponse(adjustment database.BalanceAdjustment) AdjustmentResponse {
+	return AdjustmentResponse{
+		ID:         adjustment.ID,
+		Amount:     adjustment.Amount,
+		ReasonCode: adjustment.ReasonCode,
+		Comment:    adjustment.Comment,
+		AdminID:    adjustment.AdminID,
+		CreatedAt:  adjustment.CreatedAt,
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestAdjustBalance_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     AdjustmentRequest
+		wantErr error
+	}{
+		{
+			name:    "zero_amount",
+			req:     AdjustmentRequest{Amount: 0, ReasonCode: "GOODWILL"},
+			wantErr: cstmerr.ErrorInvalidAmount,
+		},
+		{
+			name:    "nan_amount",
+			req:     AdjustmentRequest{Amount: math.NaN(), ReasonCode: "GOODWILL"},
+			wantErr: cstmerr.ErrorInvalidAmount,
+		},
+		{
+			name:    "infinite_amount",
+			req:     AdjustmentRequest{Amount: math.Inf(-1), ReasonCode: "FRAUD"},
+			wantErr: cstmerr.ErrorInvalidAmount,
+		},
+		{
+			name:    "empty_reason_code",
+			req:     AdjustmentRequest{Amount: 100},
+			wantErr: cstmerr.ErrorInvalidReasonCode,
+		},
+		{
+			name:    "unknown_reason_code",
+			req:     AdjustmentRequest{Amount: -50, ReasonCode: "BIRTHDAY"},
+			wantErr: cstmerr.ErrorInvalidReasonCode,
+		},
+		{
+			name:    "lowercase_reason_code",
+			req:     AdjustmentRequest{Amount: 100, ReasonCode: "goodwill"},
+			wantErr: cstmerr.ErrorInvalidReasonCode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := AdjustBalance(context.Background(), 1, "user", tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSetOrderStatus_InvalidOrderNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		orderNumber string
+	}{
+		{name: "empty", orderNumber: ""},
+		{name: "not_a_number", orderNumber: "abc"},
+		{name: "bad_checksum", orderNumber: "2377225625"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, ReprocessOrder(context.Background(), 1, tt.orderNumber), cstmerr.ErrorInvalidOrderNumber)
+			assert.ErrorIs(t, InvalidateOrder(context.Background(), 1, tt.orderNumber), cstmerr.ErrorInvalidOrderNumber)
+		})
+	}
+}
@@ -2,7 +2,10 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
@@ -10,64 +13,282 @@ import (
 	"github.com/FollowLille/loyalty/internal/auth"
 	"github.com/FollowLille/loyalty/internal/config"
 	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
 )
 
 // RegisterUser регистрирует нового пользователя в системе лояльности.
+// Если пароль не удовлетворяет требованиям сложности, возвращает ошибку cstmerr.ErrorWeakPassword.
 // Если пользователь существует, возвращает ошибку cstmerr.ErrorUserAlreadyExists.
-// Если пользователь не существует, создает нового пользователя и возвращает токен.
+// Если пользователь не существует, создает нового пользователя с реферальным кодом и возвращает токен.
+// Если передан реферальный код, пользователь регистрируется как приглашенный владельцем кода.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - username: имя пользователя.
 //   - password: пароль пользователя.
+//   - referralCode: реферальный код пригласившего пользователя, пустая строка - без приглашения.
+//   - ip: IP-адрес клиента, по нему вместе с userAgent отклоняется приглашение самого себя.
+//   - userAgent: заголовок User-Agent клиента.
 //
 // Возвращаемое значение:
 //   - token: токен для доступа к системе лояльности.
-//   - error: ошибка, если произошла ошибка при регистрации пользователя.
-func RegisterUser(username, password string) (string, error) {
+//   - error: ошибка, если произошла ошибка при регистрации пользователя, cstmerr.ErrorInvalidReferralCode
+//     или cstmerr.ErrorReferralLimitReached, если приглашение недействительно.
+func RegisterUser(ctx context.Context, username, password, referralCode, ip, userAgent string) (string, error) {
+	ctx, span := tracing.Start(ctx, "services.RegisterUser")
+	defer span.End()
+
+	if err := ValidatePassword(password); err != nil {
+		return "", err
+	}
+
+	invite, err := referralInvite(referralCode)
+	if err != nil {
+		return "", err
+	}
+	if invite != nil {
+		invite.ClientIP = ip
+		invite.UserAgent = userAgent
+	}
+
+	ownCode, err := generateReferralCode()
+	if err != nil {
+		return "", err
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", errors.New("failed to hash password")
+		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	err = database.CreateUser(username, string(hashedPassword))
+	err = database.CreateUser(ctx, username, string(hashedPassword), ownCode, invite)
 	if err != nil {
 		return "", err
 	}
 
 	token, err := auth.GenerateToken(username)
 	if err != nil {
-		return "", errors.New("failed to generate token")
+		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	return token, nil
 }
 
+// dummyPasswordHash сравнивается с паролем, если пользователь не найден, чтобы время ответа
+// не позволяло отличить несуществующий логин от неверного пароля. Стоимость совпадает с хэшами пользователей.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 // LoginUser выполняет вход пользователя в систему лояльности.
 // Если пользователь существует, создает новый токен и возвращает его в качестве ответа.
+// Перед проверкой пароля проверяет, не заблокированы ли логин и IP-адрес из-за неудачных попыток.
+// Каждая попытка входа сохраняется в историю.
+// При неверном логине или пароле возвращает ошибку cstmerr.ErrorInvalidCredentials.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - username: имя пользователя.
 //   - password: пароль пользователя.
+//   - ip: IP-адрес клиента.
+//   - userAgent: User-Agent клиента.
 //
 // Возвращаемое значение:
 //   - token: токен для доступа к системе лояльности.
 //   - error: ошибка, если произошла ошибка при входе пользователя.
-func LoginUser(username, password string) (string, error) {
-	storedHash, err := database.GetUserPasswordHash(username)
-	if err != nil {
-		config.Logger.Error("Failed to get user password hash", zap.Error(err))
+func LoginUser(ctx context.Context, username, password, ip, userAgent string) (string, error) {
+	ctx, span := tracing.Start(ctx, "services.LoginUser")
+	defer span.End()
+
+	if err := checkLoginAllowed(ctx, username, ip); err != nil {
+		recordLoginAttempt(ctx, username, ip, userAgent, false, "blocked")
+		return "", err
+	}
+
+	storedHash, err := database.GetUserPasswordHash(ctx, username)
+	if err != nil && !errors.Is(err, cstmerr.ErrorUserDoesNotExist) {
+		config.LoggerFromContext(ctx).Error("Failed to get user password hash", zap.Error(err))
 		return "", err
 	}
-	if err = bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password)); err != nil {
-		config.Logger.Error("Failed to compare hash and password", zap.Error(err))
-		return "", errors.New("invalid password")
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password))
+		if err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to compare hash and password", zap.Error(err))
+			err = cstmerr.ErrorInvalidPassword
+		}
+	} else {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+	}
+	if err != nil {
+		recordLoginAttempt(ctx, username, ip, userAgent, false, "invalid_credentials")
+		if blockErr := registerLoginFailure(ctx, username, ip); blockErr != nil {
+			return "", blockErr
+		}
+		// Клиенту не сообщается, что именно неверно: логин или пароль
+		return "", cstmerr.ErrorInvalidCredentials
 	}
 
-	token, err := auth.GenerateToken(username)
+	if err = database.ResetLoginFailures(ctx, loginSubject(username)); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to reset login failures", zap.Error(err))
+	}
+
+	token, err := issueUserToken(ctx, username)
 	if err != nil {
-		config.Logger.Error("Failed to generate token", zap.Error(err))
-		return "", errors.New("failed to generate token")
+		config.LoggerFromContext(ctx).Error("Failed to generate token", zap.Error(err))
+		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
 
+	recordLoginAttempt(ctx, username, ip, userAgent, true, "")
 	return token, nil
 }
+
+// AuthenticateToken проверяет JWT-токен пользователя: подпись, срок действия и версию токенов.
+// После смены пароля версия токенов увеличивается, и ранее выданные токены больше не принимаются.
+// Если токен недействителен, возвращает ошибку, оборачивающую cstmerr.ErrorUnauthorized.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - token: JWT-токен без префикса Bearer.
+//
+// Возвращаемое значение:
+//   - userID: идентификатор пользователя.
+//   - roles: роли пользователя из токена.
+//   - error: ошибка, если токен недействителен.
+func AuthenticateToken(ctx context.Context, token string) (int64, []string, error) {
+	ctx, span := tracing.Start(ctx, "services.AuthenticateToken")
+	defer span.End()
+
+	claims, err := auth.ParseToken(token)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: invalid or expired token", cstmerr.ErrorUnauthorized)
+	}
+	userID, tokenVersion, err := database.GetUserAuthInfo(ctx, claims.Username)
+	if errors.Is(err, cstmerr.ErrorUserDoesNotExist) {
+		return 0, nil, fmt.Errorf("%w: invalid or expired token", cstmerr.ErrorUnauthorized)
+	} else if err != nil {
+		return 0, nil, err
+	}
+	if claims.TokenVersion != tokenVersion {
+		return 0, nil, fmt.Errorf("%w: invalid or expired token", cstmerr.ErrorUnauthorized)
+	}
+	return userID, claims.Roles, nil
+}
+
+// issueUserToken выпускает токен пользователю с учетом текущей версии его токенов и его ролей.
+func issueUserToken(ctx context.Context, username string) (string, error) {
+	userID, tokenVersion, err := database.GetUserAuthInfo(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	roles, err := database.GetUserRoles(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return auth.IssueToken(auth.TokenClaims{Username: username, TokenVersion: tokenVersion, Roles: roles})
+}
+
+// GrantRole выдает пользователю роль. Ранее выданные пользователю токены становятся
+// недействительными, чтобы новая роль попала в токен при следующем входе.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - username: имя пользователя.
+//   - role: выдаваемая роль.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если пользователь не найден или произошла ошибка при выдаче роли.
+func GrantRole(ctx context.Context, username, role string) error {
+	ctx, span := tracing.Start(ctx, "services.GrantRole")
+	defer span.End()
+
+	return database.GrantRole(ctx, username, role)
+}
+
+// loginPolicy описывает ограничения на неудачные попытки входа для одного субъекта.
+type loginPolicy struct {
+	subject      string
+	freeAttempts int
+	maxAttempts  int
+	lockErr      error
+}
+
+// loginPolicies возвращает ограничения для логина и IP-адреса клиента.
+func loginPolicies(username, ip string) []loginPolicy {
+	return []loginPolicy{
+		{
+			subject:      loginSubject(username),
+			freeAttempts: config.LoginFreeAttempts,
+			maxAttempts:  config.LoginMaxAttempts,
+			lockErr:      cstmerr.ErrorAccountLocked,
+		},
+		{
+			subject:      "ip:" + ip,
+			freeAttempts: config.LoginIPFreeAttempts,
+			maxAttempts:  config.LoginIPMaxAttempts,
+			lockErr:      cstmerr.ErrorTooManyLoginAttempts,
+		},
+	}
+}
+
+func loginSubject(username string) string {
+	return "login:" + username
+}
+
+// checkLoginAllowed проверяет, можно ли сейчас выполнить попытку входа.
+// Возвращает *cstmerr.LoginBlockedError, если логин или IP-адрес заблокированы
+// или с последней неудачной попытки прошло меньше положенной задержки.
+func checkLoginAllowed(ctx context.Context, username, ip string) error {
+	for _, policy := range loginPolicies(username, ip) {
+		failures, err := database.GetLoginFailures(ctx, policy.subject)
+		if err != nil {
+			return err
+		}
+		if failures.LockedFor > 0 {
+			return &cstmerr.LoginBlockedError{Err: policy.lockErr, RetryAfter: failures.LockedFor}
+		}
+		if wait := loginDelay(failures.FailedCount, policy.freeAttempts) - failures.SinceLastFailure; wait > 0 {
+			return &cstmerr.LoginBlockedError{Err: cstmerr.ErrorTooManyLoginAttempts, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// registerLoginFailure увеличивает счётчики неудачных попыток для логина и IP-адреса.
+// Если после этого кто-то из них оказался заблокирован, возвращает *cstmerr.LoginBlockedError.
+func registerLoginFailure(ctx context.Context, username, ip string) error {
+	var blockErr error
+	for _, policy := range loginPolicies(username, ip) {
+		failures, err := database.RegisterLoginFailure(ctx, policy.subject, policy.maxAttempts, config.LoginLockoutDuration)
+		if err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to register login failure", zap.Error(err))
+			continue
+		}
+		if failures.LockedFor > 0 && blockErr == nil {
+			blockErr = &cstmerr.LoginBlockedError{Err: policy.lockErr, RetryAfter: failures.LockedFor}
+		}
+	}
+	return blockErr
+}
+
+// loginDelay возвращает минимальную паузу после failures неудачных попыток подряд.
+// Первые freeAttempts попыток проходят без задержки, далее задержка удваивается
+// начиная с config.LoginBaseDelay, но не превышает config.LoginLockoutDuration.
+func loginDelay(failures, freeAttempts int) time.Duration {
+	if failures < freeAttempts {
+		return 0
+	}
+	delay := config.LoginBaseDelay
+	for i := freeAttempts; i < failures && delay < config.LoginLockoutDuration; i++ {
+		delay *= 2
+	}
+	if delay > config.LoginLockoutDuration {
+		return config.LoginLockoutDuration
+	}
+	return delay
+}
+
+// recordLoginAttempt сохраняет попытку входа в историю. Ошибка сохранения только логируется.
+func recordLoginAttempt(ctx context.Context, username, ip, userAgent string, success bool, reason string) {
+	if err := database.RecordLoginAttempt(ctx, username, ip, userAgent, success, reason); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to record login attempt", zap.Error(err))
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+func TestLoginDelay(t *testing.T) {
+	baseDelay, lockoutDuration := config.LoginBaseDelay, config.LoginLockoutDuration
+	defer func() { config.LoginBaseDelay, config.LoginLockoutDuration = baseDelay, lockoutDuration }()
+	config.LoginBaseDelay = time.Second
+	config.LoginLockoutDuration = 10 * time.Second
+
+	type args struct {
+		failures     int
+		freeAttempts int
+	}
+	tests := []struct {
+		name string
+		args args
+		want time.Duration
+	}{
+		{
+			name: "no_failures",
+			args: args{failures: 0, freeAttempts: 3},
+			want: 0,
+		},
+		{
+			name: "within_free_attempts",
+			args: args{failures: 2, freeAttempts: 3},
+			want: 0,
+		},
+		{
+			name: "first_delayed_attempt",
+			args: args{failures: 3, freeAttempts: 3},
+			want: time.Second,
+		},
+		{
+			name: "delay_doubles",
+			args: args{failures: 5, freeAttempts: 3},
+			want: 4 * time.Second,
+		},
+		{
+			name: "delay_capped_by_lockout",
+			args: args{failures: 40, freeAttempts: 3},
+			want: 10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := loginDelay(tt.args.failures, tt.args.freeAttempts)
+			assert.Equal(t, tt.want, got, "loginDelay() failed for test case: %v", tt.name)
+		})
+	}
+}
@@ -1,14 +1,33 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"time"
 
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
 	"github.com/FollowLille/loyalty/internal/database"
+	"github.com/FollowLille/loyalty/internal/tracing"
 )
 
 type UserBalance struct {
-	Current   float64
-	Withdrawn float64
+	Current      float64          `json:"current"`
+	Withdrawn    float64          `json:"withdrawn"`
+	Pending      PendingBalance   `json:"pending"`
+	ExpiringSoon []ExpiringPoints `json:"expiring_soon,omitempty"`
+}
+
+// PendingBalance описывает заказы, которые еще не обработаны системой начислений.
+type PendingBalance struct {
+	Orders  int64   `json:"orders"`  // количество заказов в статусах NEW и PROCESSING
+	Accrual float64 `json:"accrual"` // предварительное начисление по ним, если оно уже известно
+}
+
+type ExpiringPoints struct {
+	Amount    float64   `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // FetchUserBalance выполняет бизнес-логику для получения баланса пользователя.
@@ -16,19 +35,64 @@ type UserBalance struct {
 // В случае успеха, возвращает баланс пользователя.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //
 // Возвращаемое значение:
 //   - balance: баланс пользователя.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func FetchUserBalance(userID int64) (UserBalance, error) {
-	balance, withdrawn, err := database.FetchUserBalance(userID)
+func FetchUserBalance(ctx context.Context, userID int64) (UserBalance, error) {
+	ctx, span := tracing.Start(ctx, "services.FetchUserBalance")
+	defer span.End()
+
+	balance, withdrawn, err := database.FetchUserBalance(ctx, userID)
 	if err != nil {
-		return UserBalance{}, errors.New("failed to fetch user balance")
+		return UserBalance{}, fmt.Errorf("failed to fetch user balance: %w", err)
 	}
 
-	return UserBalance{
+	pendingOrders, pendingAccrual, err := database.FetchUserPendingBalance(ctx, userID)
+	if err != nil {
+		return UserBalance{}, fmt.Errorf("failed to fetch user pending balance: %w", err)
+	}
+
+	userBalance := UserBalance{
 		Current:   balance,
 		Withdrawn: withdrawn,
-	}, nil
+		Pending:   PendingBalance{Orders: pendingOrders, Accrual: pendingAccrual},
+	}
+	if config.PointsExpiryMonths > 0 {
+		expiring, err := database.FetchExpiringPoints(ctx, userID, config.PointsExpiryMonths, config.PointsExpiringSoonWindow)
+		if err != nil {
+			return UserBalance{}, fmt.Errorf("failed to fetch expiring points: %w", err)
+		}
+		for _, points := range expiring {
+			userBalance.ExpiringSoon = append(userBalance.ExpiringSoon, ExpiringPoints{
+				Amount:    points.Amount,
+				ExpiresAt: points.ExpiresAt,
+			})
+		}
+	}
+	return userBalance, nil
+}
+
+// ExpirePoints выполняет бизнес-логику сгорания баллов, начисленных больше config.PointsExpiryMonths месяцев назад.
+// Если срок жизни баллов не задан, ничего не делает.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ExpirePoints(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "services.ExpirePoints")
+	defer span.End()
+
+	if config.PointsExpiryMonths <= 0 {
+		return nil
+	}
+	count, total, err := database.ExpirePoints(ctx, config.PointsExpiryMonths)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		config.LoggerFromContext(ctx).Info("Points expired", zap.Int64("entries", count), zap.Float64("total", total))
+	}
+	return nil
 }
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+func TestExpirePoints_Disabled(t *testing.T) {
+	months := config.PointsExpiryMonths
+	defer func() { config.PointsExpiryMonths = months }()
+
+	// Без срока жизни баллов задача не обращается к базе данных
+	config.PointsExpiryMonths = 0
+	assert.NoError(t, ExpirePoints(context.Background()))
+}
+
+func TestUserBalance_JSON(t *testing.T) {
+	balance := UserBalance{
+		Current:   500.5,
+		Withdrawn: 42,
+		Pending:   PendingBalance{Orders: 2, Accrual: 120.25},
+	}
+
+	data, err := json.Marshal(balance)
+	require.NoError(t, err)
+
+	// Прежние поля current и withdrawn сохраняются, ожидающие начисления добавлены вложенным объектом
+	assert.JSONEq(t, `{"current":500.5,"withdrawn":42,"pending":{"orders":2,"accrual":120.25}}`, string(data))
+}
+
+func TestUserBalance_JSONWithoutPending(t *testing.T) {
+	data, err := json.Marshal(UserBalance{Current: 10})
+	require.NoError(t, err)
+
+	// Блок pending выводится и без ожидающих заказов, чтобы клиенты могли на него полагаться
+	assert.JSONEq(t, `{"current":10,"withdrawn":0,"pending":{"orders":0,"accrual":0}}`, string(data))
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// Состояния кампании, рассчитываемые по периоду действия.
+const (
+	CampaignScheduled = "scheduled" // период действия еще не начался
+	CampaignActive    = "active"    // кампания действует
+	CampaignEnded     = "ended"     // период действия закончился
+	CampaignArchived  = "archived"  // кампания завершена администратором
+)
+
+// promoCodePattern описывает допустимый промокод после приведения к верхнему регистру.
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+type CampaignRequest struct {
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	Code         string    `json:"code,omitempty"`
+	Bonus        float64   `json:"bonus,omitempty"`
+	Multiplier   float64   `json:"multiplier,omitempty"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	Budget       *float64  `json:"budget,omitempty"`         // nil - без ограничения
+	PerUserLimit *int      `json:"per_user_limit,omitempty"` // nil - 1 для промокода, без ограничения для множителя
+}
+
+type CampaignResponse struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Status       string     `json:"status"`
+	Code         string     `json:"code,omitempty"`
+	Bonus        float64    `json:"bonus,omitempty"`
+	Multiplier   float64    `json:"multiplier,omitempty"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	Budget       *float64   `json:"budget,omitempty"`
+	PerUserLimit int        `json:"per_user_limit"`
+	Spent        float64    `json:"spent"`
+	Redemptions  int64      `json:"redemptions"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+}
+
+type PromoRequest struct {
+	Code string `json:"code"`
+}
+
+type PromoResponse struct {
+	CampaignID int64     `json:"campaign_id"`
+	Campaign   string    `json:"campaign"`
+	Amount     float64   `json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// normalizePromoCode приводит промокод к виду, в котором он хранится: без пробелов по краям, в верхнем регистре.
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// newCampaign проверяет параметры кампании и преобразует их в кампанию для сохранения.
+//
+// Параметры:
+//   - req: параметры кампании.
+//
+// Возвращаемое значение:
+//   - database.Campaign: кампания без идентификатора и автора.
+//   - error: cstmerr.ErrorInvalidCampaign, если параметры некорректны.
+func newCampaign(req CampaignRequest) (database.Campaign, error) {
+	campaign := database.Campaign{
+		Name:     strings.TrimSpace(req.Name),
+		Type:     req.Type,
+		StartsAt: req.StartsAt.UTC(),
+		EndsAt:   req.EndsAt.UTC(),
+		Budget:   req.Budget,
+	}
+	if campaign.Name == "" {
+		return database.Campaign{}, fmt.Errorf("%w: name is required", cstmerr.ErrorInvalidCampaign)
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() || !req.StartsAt.Before(req.EndsAt) {
+		return database.Campaign{}, fmt.Errorf("%w: starts_at and ends_at are required and starts_at must be before ends_at", cstmerr.ErrorInvalidCampaign)
+	}
+	if req.Budget != nil && !isPositiveAmount(*req.Budget) {
+		return database.Campaign{}, fmt.Errorf("%w: budget must be positive", cstmerr.ErrorInvalidCampaign)
+	}
+	if req.PerUserLimit != nil && *req.PerUserLimit < 0 {
+		return database.Campaign{}, fmt.Errorf("%w: per_user_limit must not be negative", cstmerr.ErrorInvalidCampaign)
+	}
+
+	switch req.Type {
+	case database.CampaignTypeCode:
+		code := normalizePromoCode(req.Code)
+		if !promoCodePattern.MatchString(code) {
+			return database.Campaign{}, fmt.Errorf("%w: code must be 3-32 letters, digits, '-' or '_'", cstmerr.ErrorInvalidCampaign)
+		}
+		if !isPositiveAmount(req.Bonus) {
+			return database.Campaign{}, fmt.Errorf("%w: bonus must be positive", cstmerr.ErrorInvalidCampaign)
+		}
+		if req.Multiplier != 0 {
+			return database.Campaign{}, fmt.Errorf("%w: multiplier is not allowed for code campaigns", cstmerr.ErrorInvalidCampaign)
+		}
+		campaign.Code = &code
+		campaign.Bonus = req.Bonus
+		campaign.Multiplier = 1
+		campaign.PerUserLimit = 1
+	case database.CampaignTypeMultiplier:
+		if math.IsNaN(req.Multiplier) || math.IsInf(req.Multiplier, 0) || req.Multiplier <= 1 {
+			return database.Campaign{}, fmt.Errorf("%w: multiplier must be greater than 1", cstmerr.ErrorInvalidCampaign)
+		}
+		if req.Code != "" || req.Bonus != 0 {
+			return database.Campaign{}, fmt.Errorf("%w: code and bonus are not allowed for multiplier campaigns", cstmerr.ErrorInvalidCampaign)
+		}
+		campaign.Multiplier = req.Multiplier
+	default:
+		return database.Campaign{}, fmt.Errorf("%w: type must be %s or %s",
+			cstmerr.ErrorInvalidCampaign, database.CampaignTypeCode, database.CampaignTypeMultiplier)
+	}
+	if req.PerUserLimit != nil {
+		campaign.PerUserLimit = *req.PerUserLimit
+	}
+	return campaign, nil
+}
+
+// isPositiveAmount проверяет, что сумма баллов - конечное положительное число.
+func isPositiveAmount(amount float64) bool {
+	return amount > 0 && !math.IsNaN(amount) && !math.IsInf(amount, 0)
+}
+
+// newCampaignResponse преобразует кампанию в ответ API с состоянием на момент now.
+func newCampaignResponse(c database.Campaign, now time.Time) CampaignResponse {
+	response := CampaignResponse{
+		ID:           c.ID,
+		Name:         c.Name,
+		Type:         c.Type,
+		StartsAt:     c.StartsAt,
+		EndsAt:       c.EndsAt,
+		Budget:       c.Budget,
+		PerUserLimit: c.PerUserLimit,
+		Spent:        roundPoints(c.Spent),
+		Redemptions:  c.Redemptions,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		ArchivedAt:   c.ArchivedAt,
+	}
+	if c.Code != nil {
+		response.Code = *c.Code
+		response.Bonus = c.Bonus
+	} else {
+		response.Multiplier = c.Multiplier
+	}
+
+	switch {
+	case c.ArchivedAt != nil:
+		response.Status = CampaignArchived
+	case now.Before(c.StartsAt):
+		response.Status = CampaignScheduled
+	case now.Before(c.EndsAt):
+		response.Status = CampaignActive
+	default:
+		response.Status = CampaignEnded
+	}
+	return response
+}
+
+// CreateCampaign выполняет бизнес-логику для создания кампании.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - req: параметры кампании.
+//
+// Возвращаемое значение:
+//   - CampaignResponse: созданная кампания.
+//   - error: ошибка, если параметры некорректны, промокод занят или произошла ошибка при сохранении кампании.
+func CreateCampaign(ctx context.Context, adminID int64, req CampaignRequest) (CampaignResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.CreateCampaign")
+	defer span.End()
+
+	campaign, err := newCampaign(req)
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	campaign.CreatedBy = adminID
+
+	campaign, err = database.CreateCampaign(ctx, campaign)
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	return newCampaignResponse(campaign, time.Now()), nil
+}
+
+// UpdateCampaign выполняет бизнес-логику для изменения кампании. Тип кампании изменить нельзя.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - campaignID: идентификатор кампании.
+//   - req: новые параметры кампании.
+//
+// Возвращаемое значение:
+//   - CampaignResponse: измененная кампания.
+//   - error: ошибка, если параметры некорректны, кампания не найдена или произошла ошибка при выполнении запроса.
+func UpdateCampaign(ctx context.Context, adminID, campaignID int64, req CampaignRequest) (CampaignResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.UpdateCampaign")
+	defer span.End()
+
+	campaign, err := newCampaign(req)
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	campaign.ID = campaignID
+
+	campaign, err = database.UpdateCampaign(ctx, adminID, campaign)
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	return newCampaignResponse(campaign, time.Now()), nil
+}
+
+// ArchiveCampaign выполняет бизнес-логику для завершения кампании администратором.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - campaignID: идентификатор кампании.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если кампания не найдена или произошла ошибка при выполнении запроса.
+func ArchiveCampaign(ctx context.Context, adminID, campaignID int64) error {
+	ctx, span := tracing.Start(ctx, "services.ArchiveCampaign")
+	defer span.End()
+
+	return database.ArchiveCampaign(ctx, adminID, campaignID)
+}
+
+// GetCampaign выполняет бизнес-логику для получения кампании.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - campaignID: идентификатор кампании.
+//
+// Возвращаемое значение:
+//   - CampaignResponse: кампания.
+//   - error: ошибка, если кампания не найдена или произошла ошибка при выполнении запроса.
+func GetCampaign(ctx context.Context, campaignID int64) (CampaignResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.GetCampaign")
+	defer span.End()
+
+	campaign, err := database.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	return newCampaignResponse(campaign, time.Now()), nil
+}
+
+// ListCampaigns выполняет бизнес-логику для получения списка кампаний.
+//
+// Возвращаемое значение:
+//   - []CampaignResponse: кампании, новые первыми.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ListCampaigns(ctx context.Context) ([]CampaignResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.ListCampaigns")
+	defer span.End()
+
+	campaigns, err := database.ListCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	response := make([]CampaignResponse, len(campaigns))
+	for i, campaign := range campaigns {
+		response[i] = newCampaignResponse(campaign, now)
+	}
+	return response, nil
+}
+
+// RedeemPromoCode выполняет бизнес-логику для активации промокода пользователем.
+// Регистр и пробелы по краям промокода не учитываются.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - req: промокод.
+//
+// Возвращаемое значение:
+//   - PromoResponse: начисленный бонус.
+//   - error: ошибка, если промокод не найден, не действует, исчерпан лимит активаций или бюджет кампании,
+//     или произошла ошибка при выполнении запроса.
+func RedeemPromoCode(ctx context.Context, userID int64, req PromoRequest) (PromoResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.RedeemPromoCode")
+	defer span.End()
+
+	code := normalizePromoCode(req.Code)
+	if !promoCodePattern.MatchString(code) {
+		return PromoResponse{}, cstmerr.ErrorPromoCodeNotFound
+	}
+
+	bonus, err := database.RedeemPromoCode(ctx, userID, code)
+	if err != nil {
+		return PromoResponse{}, err
+	}
+	return PromoResponse{
+		CampaignID: bonus.CampaignID,
+		Campaign:   bonus.CampaignName,
+		Amount:     bonus.Amount,
+		CreatedAt:  bonus.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestNewCampaign(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	t.Run("code_defaults", func(t *testing.T) {
+		campaign, err := newCampaign(CampaignRequest{Name: " Spring ", Type: "code", Code: " spring-24 ", Bonus: 500, StartsAt: start, EndsAt: end})
+		require.NoError(t, err)
+		assert.Equal(t, "Spring", campaign.Name)
+		require.NotNil(t, campaign.Code)
+		assert.Equal(t, "SPRING-24", *campaign.Code)
+		assert.Equal(t, 500.0, campaign.Bonus)
+		assert.Equal(t, 1, campaign.PerUserLimit)
+	})
+
+	t.Run("multiplier_defaults", func(t *testing.T) {
+		campaign, err := newCampaign(CampaignRequest{Name: "Weekend", Type: "multiplier", Multiplier: 2, StartsAt: start, EndsAt: end})
+		require.NoError(t, err)
+		assert.Nil(t, campaign.Code)
+		assert.Equal(t, 2.0, campaign.Multiplier)
+		assert.Equal(t, 0, campaign.PerUserLimit)
+	})
+
+	negative := -1
+	zero := 0.0
+	tests := []struct {
+		name string
+		req  CampaignRequest
+	}{
+		{name: "empty_name", req: CampaignRequest{Type: "code", Code: "SPRING", Bonus: 500, StartsAt: start, EndsAt: end}},
+		{name: "unknown_type", req: CampaignRequest{Name: "Spring", Type: "cashback", StartsAt: start, EndsAt: end}},
+		{name: "no_window", req: CampaignRequest{Name: "Spring", Type: "code", Code: "SPRING", Bonus: 500}},
+		{name: "reversed_window", req: CampaignRequest{Name: "Spring", Type: "code", Code: "SPRING", Bonus: 500, StartsAt: end, EndsAt: start}},
+		{name: "short_code", req: CampaignRequest{Name: "Spring", Type: "code", Code: "S", Bonus: 500, StartsAt: start, EndsAt: end}},
+		{name: "code_with_spaces", req: CampaignRequest{Name: "Spring", Type: "code", Code: "SPR ING", Bonus: 500, StartsAt: start, EndsAt: end}},
+		{name: "zero_bonus", req: CampaignRequest{Name: "Spring", Type: "code", Code: "SPRING", StartsAt: start, EndsAt: end}},
+		{name: "code_with_multiplier", req: CampaignRequest{Name: "Spring", Type: "code", Code: "SPRING", Bonus: 500, Multiplier: 2, StartsAt: start, EndsAt: end}},
+		{name: "multiplier_one", req: CampaignRequest{Name: "Weekend", Type: "multiplier", Multiplier: 1, StartsAt: start, EndsAt: end}},
+		{name: "multiplier_nan", req: CampaignRequest{Name: "Weekend", Type: "multiplier", Multiplier: math.NaN(), StartsAt: start, EndsAt: end}},
+		{name: "multiplier_with_code", req: CampaignRequest{Name: "Weekend", Type: "multiplier", Multiplier: 2, Code: "WEEKEND", StartsAt: start, EndsAt: end}},
+		{name: "zero_budget", req: CampaignRequest{Name: "Weekend", Type: "multiplier", Multiplier: 2, Budget: &zero, StartsAt: start, EndsAt: end}},
+		{name: "negative_limit", req: CampaignRequest{Name: "Weekend", Type: "multiplier", Multiplier: 2, PerUserLimit: &negative, StartsAt: start, EndsAt: end}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newCampaign(tt.req)
+			assert.ErrorIs(t, err, cstmerr.ErrorInvalidCampaign)
+		})
+	}
+}
+
+func TestNewCampaignResponse_Status(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	campaign := database.Campaign{Type: "multiplier", Multiplier: 2, StartsAt: start, EndsAt: end}
+
+	assert.Equal(t, CampaignScheduled, newCampaignResponse(campaign, start.Add(-time.Second)).Status)
+	assert.Equal(t, CampaignActive, newCampaignResponse(campaign, start).Status)
+	assert.Equal(t, CampaignEnded, newCampaignResponse(campaign, end).Status)
+
+	archivedAt := start.Add(time.Hour)
+	campaign.ArchivedAt = &archivedAt
+	assert.Equal(t, CampaignArchived, newCampaignResponse(campaign, start.Add(2*time.Hour)).Status)
+}
+
+func TestRedeemPromoCode_InvalidCode(t *testing.T) {
+	_, err := RedeemPromoCode(context.Background(), 1, PromoRequest{Code: "  "})
+	assert.ErrorIs(t, err, cstmerr.ErrorPromoCodeNotFound)
+}
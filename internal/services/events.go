@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	"github.com/FollowLille/loyalty/internal/events"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// UserEvent описывает событие пользователя: изменение заказа или баланса.
+type UserEvent = database.UserEvent
+
+// SubscribeUserEvents подписывает на события пользователя и возвращает события, пропущенные клиентом.
+// Подписка оформляется до чтения пропущенных событий, поэтому события, возникшие между ними,
+// могут прийти дважды: клиенту следует пропускать события с уже полученным идентификатором.
+// Если lastEventID равен 0, пропущенные события не возвращаются.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - lastEventID: идентификатор последнего полученного клиентом события.
+//
+// Возвращаемое значение:
+//   - *events.Subscription: подписка на новые события, ее необходимо закрыть.
+//   - []UserEvent: пропущенные события в порядке возникновения.
+//   - error: ошибка, если не удалось получить пропущенные события.
+func SubscribeUserEvents(ctx context.Context, userID, lastEventID int64) (*events.Subscription, []UserEvent, error) {
+	ctx, span := tracing.Start(ctx, "services.SubscribeUserEvents")
+	defer span.End()
+
+	subscription := events.Default.Subscribe(userID)
+	if lastEventID == 0 {
+		return subscription, nil, nil
+	}
+
+	missed, err := database.FetchUserEvents(ctx, userID, lastEventID, config.UserEventsReplayLimit)
+	if err != nil {
+		subscription.Close()
+		return nil, nil, err
+	}
+	return subscription, missed, nil
+}
+
+// DeleteExpiredUserEvents удаляет события пользователей старше config.UserEventsTTL.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если произошла ошибка при удалении.
+func DeleteExpiredUserEvents(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "services.DeleteExpiredUserEvents")
+	defer span.End()
+
+	return database.DeleteExpiredUserEvents(ctx, config.UserEventsTTL)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// StatementQuery описывает период выписки по счету пользователя.
+type StatementQuery struct {
+	From *time.Time // начало периода, nil - с первой операции
+	To   *time.Time // конец периода (не включительно), nil - до текущего момента
+}
+
+// StatementEntry описывает операцию выписки вместе с балансом после нее.
+type StatementEntry struct {
+	Type         string    `json:"type"`
+	Order        string    `json:"order,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"` // логин второй стороны перевода или реферального приглашения
+	Campaign     string    `json:"campaign,omitempty"`     // название кампании, по которой начислен бонус
+	Amount       float64   `json:"amount"`
+	Balance      float64   `json:"balance"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// StatementTotals описывает итоги выписки за период.
+type StatementTotals struct {
+	Opening float64 `json:"opening_balance"`
+	Credits float64 `json:"credits"` // сумма поступлений
+	Debits  float64 `json:"debits"`  // сумма списаний, положительное число
+	Closing float64 `json:"closing_balance"`
+	Count   int64   `json:"count"`
+}
+
+// StatementWriter выводит выписку по мере ее чтения из базы данных.
+// Методы вызываются в порядке Opening, Entry для каждой операции, Closing.
+type StatementWriter interface {
+	Opening(query StatementQuery, balance float64) error
+	Entry(entry StatementEntry) error
+	Closing(totals StatementTotals) error
+}
+
+// StreamOrders выполняет бизнес-логику для выгрузки заказов пользователя.
+// Фильтры по статусу, периоду и порядок сортировки учитываются, параметры постраничной выдачи - нет:
+// выгружаются все подходящие заказы, каждый передается в fn сразу после чтения.
+//
+// Параметры:
+//   - ctx: контекст запроса, при его отмене выгрузка прекращается.
+//   - userID: идентификатор пользователя.
+//   - query: параметры запроса.
+//   - fn: обработчик заказа.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если параметры некорректны, произошла ошибка при выполнении запроса или ошибка fn.
+func StreamOrders(ctx context.Context, userID int64, query OrdersQuery, fn func(Order) error) error {
+	ctx, span := tracing.Start(ctx, "services.StreamOrders")
+	defer span.End()
+
+	query.Limit, query.Cursor = 0, ""
+	filter, err := newOrdersFilter(query)
+	if err != nil {
+		return err
+	}
+	filter.Limit = 0
+
+	return database.StreamUserOrders(ctx, userID, filter, func(order database.Order) error {
+		return fn(Order{
+			Number:     order.Number,
+			Status:     order.Status,
+			Accrual:    order.Accrual,
+			UploadedAt: order.UploadedAt.Truncate(time.Second),
+		})
+	})
+}
+
+// StreamWithdrawals выполняет бизнес-логику для выгрузки списаний пользователя.
+// Фильтр по периоду учитывается, параметры постраничной выдачи и итоги - нет.
+// Для каждого списания передается сумма возвратов по нему, сами возвраты не передаются.
+//
+// Параметры:
+//   - ctx: контекст запроса, при его отмене выгрузка прекращается.
+//   - userID: идентификатор пользователя.
+//   - query: параметры запроса.
+//   - fn: обработчик списания.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если параметры некорректны, произошла ошибка при выполнении запроса или ошибка fn.
+func StreamWithdrawals(ctx context.Context, userID int64, query WithdrawalsQuery, fn func(WithdrawResponse) error) error {
+	ctx, span := tracing.Start(ctx, "services.StreamWithdrawals")
+	defer span.End()
+
+	query.Limit, query.Cursor = 0, ""
+	filter, err := newWithdrawalsFilter(query)
+	if err != nil {
+		return err
+	}
+	filter.Limit = 0
+
+	return database.StreamUserWithdrawals(ctx, userID, filter, func(withdrawal database.Withdrawal) error {
+		return fn(WithdrawResponse{
+			Order:       withdrawal.OrderNumber,
+			Sum:         withdrawal.Sum,
+			ProcessedAt: withdrawal.ProcessedAt,
+			Merchant:    withdrawal.Merchant,
+			Refunded:    withdrawal.Refunded,
+		})
+	})
+}
+
+// StreamStatement выполняет бизнес-логику для построения выписки по счету пользователя за период.
+// В выписку попадают все операции, меняющие баланс: начисления, бонусы уровня и кампаний, списания, возвраты, корректировки
+// сгорание баллов и переводы между пользователями. Для каждой операции рассчитывается баланс после нее, итоги выводятся в конце.
+//
+// Параметры:
+//   - ctx: контекст запроса, при его отмене выгрузка прекращается.
+//   - userID: идентификатор пользователя.
+//   - query: период выписки.
+//   - w: получатель выписки.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если период некорректен, произошла ошибка при выполнении запроса или ошибка w.
+func StreamStatement(ctx context.Context, userID int64, query StatementQuery, w StatementWriter) error {
+	ctx, span := tracing.Start(ctx, "services.StreamStatement")
+	defer span.End()
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return fmt.Errorf("%w: from must be before to", cstmerr.ErrorInvalidFilter)
+	}
+	if query.From != nil {
+		from := query.From.UTC()
+		query.From = &from
+	}
+	if query.To != nil {
+		to := query.To.UTC()
+		query.To = &to
+	}
+
+	var totals StatementTotals
+	balance := 0.0
+	err := database.StreamStatement(ctx, userID, query.From, query.To,
+		func(opening float64) error {
+			balance = roundPoints(opening)
+			totals.Opening = balance
+			return w.Opening(query, balance)
+		},
+		func(entry database.StatementEntry) error {
+			balance = roundPoints(balance + entry.Amount)
+			if entry.Amount >= 0 {
+				totals.Credits = roundPoints(totals.Credits + entry.Amount)
+			} else {
+				totals.Debits = roundPoints(totals.Debits - entry.Amount)
+			}
+			totals.Count++
+
+			line := StatementEntry{Type: entry.Type, Amount: entry.Amount, Balance: balance, CreatedAt: entry.CreatedAt}
+			if entry.Order != nil {
+				line.Order = *entry.Order
+			}
+			if entry.Counterparty != nil {
+				line.Counterparty = *entry.Counterparty
+			}
+			if entry.Campaign != nil {
+				line.Campaign = *entry.Campaign
+			}
+			return w.Entry(line)
+		})
+	if err != nil {
+		return err
+	}
+
+	totals.Closing = balance
+	return w.Closing(totals)
+}
+
+// roundPoints округляет сумму баллов до сотых, чтобы накопленный баланс не содержал погрешностей float64.
+func roundPoints(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
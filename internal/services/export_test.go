@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestStreamStatement_InvalidPeriod(t *testing.T) {
+	from := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+
+	err := StreamStatement(context.Background(), 1, StatementQuery{From: &from, To: &to}, nil)
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidFilter)
+}
+
+func TestStreamOrders_InvalidStatus(t *testing.T) {
+	err := StreamOrders(context.Background(), 1, OrdersQuery{Statuses: []string{"LOST"}}, nil)
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidFilter)
+}
+
+func TestRoundPoints(t *testing.T) {
+	assert.Equal(t, 0.3, roundPoints(0.1+0.2))
+	assert.Equal(t, 729.98, roundPoints(729.98))
+	assert.Equal(t, -20.5, roundPoints(-20.499999999))
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// Области действия API-ключей мерчантов.
+const (
+	ScopeOrdersWrite       = "orders:write"       // загрузка заказов от имени пользователя
+	ScopeWithdrawalsRefund = "withdrawals:refund" // возврат баллов, списанных по заказу
+)
+
+// MerchantScopes перечисляет все допустимые области действия API-ключей.
+var MerchantScopes = []string{ScopeOrdersWrite, ScopeWithdrawalsRefund}
+
+// maxMerchantNameLength - максимальная длина названия мерчанта, совпадает с размером столбца в базе данных.
+const maxMerchantNameLength = 255
+
+// merchantKeyPrefix - префикс, по которому API-ключ мерчанта легко узнать в логах и конфигурации.
+const merchantKeyPrefix = "mk_"
+
+type MerchantKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type MerchantKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Key        string     `json:"key,omitempty"` // сам ключ, возвращается только при создании
+}
+
+type MerchantOrderRequest struct {
+	Login string `json:"login"`
+	Order string `json:"order"`
+}
+
+// Merchant описывает мерчанта, прошедшего аутентификацию по API-ключу.
+type Merchant struct {
+	KeyID  int64
+	Name   string
+	Scopes []string
+}
+
+// HasScope проверяет, разрешена ли мерчанту указанная операция.
+func (m Merchant) HasScope(scope string) bool {
+	for _, s := range m.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateMerchantKey выполняет бизнес-логику для выпуска нового API-ключа мерчанта.
+// Ключ возвращается в открытом виде только один раз, в базе данных хранится его хэш.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора, выпускающего ключ.
+//   - req: имя мерчанта и области действия ключа.
+//
+// Возвращаемое значение:
+//   - key: выпущенный ключ вместе с его открытым значением.
+//   - error: ошибка, если параметры некорректны или произошла ошибка при сохранении ключа.
+func CreateMerchantKey(ctx context.Context, adminID int64, req MerchantKeyRequest) (MerchantKeyResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.CreateMerchantKey")
+	defer span.End()
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(req.Scopes) == 0 {
+		return MerchantKeyResponse{}, fmt.Errorf("%w: name and at least one scope are required", cstmerr.ErrorInvalidScope)
+	}
+	for _, scope := range req.Scopes {
+		if !isValidMerchantScope(scope) {
+			return MerchantKeyResponse{}, fmt.Errorf("%w: %s, allowed scopes are %s", cstmerr.ErrorInvalidScope, scope, strings.Join(MerchantScopes, ", "))
+		}
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return MerchantKeyResponse{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return MerchantKeyResponse{}, err
+	}
+	plainKey := merchantKeyPrefix + prefix + "_" + secret
+
+	key, err := database.CreateMerchantAPIKey(ctx, database.MerchantAPIKey{
+		MerchantName: name,
+		KeyPrefix:    prefix,
+		KeyHash:      hashMerchantKey(plainKey),
+		Scopes:       req.Scopes,
+		CreatedBy:    adminID,
+	})
+	if err != nil {
+		return MerchantKeyResponse{}, err
+	}
+
+	response := newMerchantKeyResponse(key)
+	response.Key = plainKey
+	return response, nil
+}
+
+// ListMerchantKeys выполняет бизнес-логику для получения списка API-ключей мерчантов.
+// Открытые значения ключей не возвращаются.
+//
+// Возвращаемое значение:
+//   - keys: список ключей.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ListMerchantKeys(ctx context.Context) ([]MerchantKeyResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.ListMerchantKeys")
+	defer span.End()
+
+	keys, err := database.ListMerchantAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]MerchantKeyResponse, len(keys))
+	for i, key := range keys {
+		response[i] = newMerchantKeyResponse(key)
+	}
+	return response, nil
+}
+
+// RevokeMerchantKey выполняет бизнес-логику для отзыва API-ключа мерчанта.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - keyID: идентификатор ключа.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если ключ не найден или произошла ошибка при выполнении запроса.
+func RevokeMerchantKey(ctx context.Context, adminID, keyID int64) error {
+	ctx, span := tracing.Start(ctx, "services.RevokeMerchantKey")
+	defer span.End()
+
+	return database.RevokeMerchantAPIKey(ctx, adminID, keyID)
+}
+
+// AuthenticateMerchant находит мерчанта по API-ключу.
+// Если ключ неизвестен или отозван, возвращает ошибку cstmerr.ErrorInvalidAPIKey.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - plainKey: API-ключ из заголовка запроса.
+//
+// Возвращаемое значение:
+//   - merchant: мерчант, которому принадлежит ключ.
+//   - error: ошибка, если ключ недействителен или произошла ошибка при выполнении запроса.
+func AuthenticateMerchant(ctx context.Context, plainKey string) (Merchant, error) {
+	ctx, span := tracing.Start(ctx, "services.AuthenticateMerchant")
+	defer span.End()
+
+	if !strings.HasPrefix(plainKey, merchantKeyPrefix) {
+		return Merchant{}, cstmerr.ErrorInvalidAPIKey
+	}
+
+	key, err := database.GetActiveMerchantAPIKey(ctx, hashMerchantKey(plainKey))
+	if err != nil {
+		return Merchant{}, err
+	}
+	return Merchant{KeyID: key.ID, Name: key.MerchantName, Scopes: key.Scopes}, nil
+}
+
+// UploadMerchantOrder выполняет бизнес-логику для загрузки заказа мерчантом от имени пользователя.
+// Проверка номера заказа и правила конфликтов те же, что и при загрузке заказа самим пользователем.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - req: логин пользователя и номер заказа.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если пользователь не найден или заказ не может быть загружен.
+func UploadMerchantOrder(ctx context.Context, req MerchantOrderRequest) error {
+	ctx, span := tracing.Start(ctx, "services.UploadMerchantOrder")
+	defer span.End()
+
+	user, err := database.GetUserByName(ctx, req.Login)
+	if err != nil {
+		return err
+	}
+	return UploadOrder(ctx, user.ID, strings.TrimSpace(req.Order))
+}
+
+func isValidMerchantScope(scope string) bool {
+	for _, s := range MerchantScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func newMerchantKeyResponse(key database.MerchantAPIKey) MerchantKeyResponse {
+	return MerchantKeyResponse{
+		ID:         key.ID,
+		Name:       key.MerchantName,
+		Prefix:     merchantKeyPrefix + key.KeyPrefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// hashMerchantKey возвращает SHA-256 хэш API-ключа, под которым он хранится в базе данных.
+func hashMerchantKey(plainKey string) string {
+	sum := sha256.Sum256([]byte(plainKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex возвращает n случайных байт в шестнадцатеричном виде.
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestCreateMerchantKey_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  MerchantKeyRequest
+	}{
+		{
+			name: "empty_name",
+			req:  MerchantKeyRequest{Name: "  ", Scopes: []string{ScopeOrdersWrite}},
+		},
+		{
+			name: "no_scopes",
+			req:  MerchantKeyRequest{Name: "storefront"},
+		},
+		{
+			name: "unknown_scope",
+			req:  MerchantKeyRequest{Name: "storefront", Scopes: []string{ScopeOrdersWrite, "users:delete"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateMerchantKey(context.Background(), 1, tt.req)
+			assert.ErrorIs(t, err, cstmerr.ErrorInvalidScope, "CreateMerchantKey(context.Background()) should reject test case: %v", tt.name)
+		})
+	}
+}
+
+func TestAuthenticateMerchant_UnknownPrefix(t *testing.T) {
+	_, err := AuthenticateMerchant(context.Background(), "not-a-merchant-key")
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidAPIKey)
+}
+
+func TestMerchant_HasScope(t *testing.T) {
+	merchant := Merchant{Name: "storefront", Scopes: []string{ScopeOrdersWrite}}
+	assert.True(t, merchant.HasScope(ScopeOrdersWrite))
+	assert.False(t, merchant.HasScope("withdrawals:refund"))
+}
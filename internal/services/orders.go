@@ -1,81 +1,175 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
 	"github.com/FollowLille/loyalty/internal/utils"
 )
 
 type Order struct {
-	Number     string
-	Status     string
-	Accrual    float64
-	UploadedAt time.Time
+	Number     string    `json:"number"`
+	Status     string    `json:"status"`
+	Accrual    float64   `json:"accrual"`
+	UploadedAt time.Time `json:"uploaded_at"`
 }
 
-// GetOrders выполняет бизнес-логику для получения списка заказов пользователя.
-// Если произошла ошибка при выполнении запроса, возвращает ошибку.
-// В случае успеха, возвращает список заказов пользователя.
+// OrderStatuses перечисляет статусы заказов, по которым можно фильтровать список заказов.
+var OrderStatuses = []string{"NEW", "PROCESSING", "INVALID", "PROCESSED"}
+
+// OrdersQuery описывает параметры запроса списка заказов пользователя.
+type OrdersQuery struct {
+	Limit     int        // размер страницы, 0 - размер по умолчанию
+	Cursor    string     // курсор следующей страницы из предыдущего ответа
+	Statuses  []string   // статусы заказов, пустой список - любые статусы
+	From      *time.Time // заказы, загруженные не раньше указанного времени
+	To        *time.Time // заказы, загруженные раньше указанного времени
+	Ascending bool       // сортировка от старых к новым, по умолчанию от новых к старым
+}
+
+// OrdersPage описывает страницу списка заказов пользователя.
+type OrdersPage struct {
+	Orders     []Order
+	NextCursor string // курсор следующей страницы, пустая строка если страница последняя
+}
+
+// GetOrders выполняет бизнес-логику для получения страницы списка заказов пользователя.
+// Если параметры запроса некорректны, возвращает ошибку cstmerr.ErrorInvalidFilter или cstmerr.ErrorInvalidCursor.
+// В случае успеха, возвращает страницу заказов и курсор следующей страницы.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
+//   - query: параметры запроса.
 //
 // Возвращаемое значение:
-//   - orders: список заказов пользователя.
+//   - page: страница заказов пользователя.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func GetOrders(userID int64) ([]map[string]interface{}, error) {
-	orders, err := database.GetUserOrders(userID)
+func GetOrders(ctx context.Context, userID int64, query OrdersQuery) (OrdersPage, error) {
+	ctx, span := tracing.Start(ctx, "services.GetOrders")
+	defer span.End()
+
+	filter, err := newOrdersFilter(query)
 	if err != nil {
-		return nil, errors.New("failed to fetch orders")
+		return OrdersPage{}, err
 	}
+	limit := filter.Limit
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	filter.Limit++
 
-	if len(orders) == 0 {
-		return nil, nil
+	orders, err := database.FetchUserOrders(ctx, userID, filter)
+	if err != nil {
+		return OrdersPage{}, fmt.Errorf("failed to fetch orders: %w", err)
 	}
 
-	response := make([]map[string]interface{}, len(orders))
+	var page OrdersPage
+	if len(orders) > limit {
+		orders = orders[:limit]
+		last := orders[limit-1]
+		number, err := strconv.ParseInt(last.Number, 10, 64)
+		if err != nil {
+			return OrdersPage{}, fmt.Errorf("failed to parse order number: %w", err)
+		}
+		page.NextCursor = encodeCursor(Cursor{Time: last.UploadedAt, ID: number})
+	}
+
+	page.Orders = make([]Order, len(orders))
 	for i, order := range orders {
-		response[i] = map[string]interface{}{
-			"number":      order.Number,
-			"status":      order.Status,
-			"accrual":     order.Accrual,
-			"uploaded_at": order.UploadedAt.Format(time.RFC3339),
+		page.Orders[i] = Order{
+			Number:     order.Number,
+			Status:     order.Status,
+			Accrual:    order.Accrual,
+			UploadedAt: order.UploadedAt.Truncate(time.Second),
 		}
 	}
+	return page, nil
+}
+
+// newOrdersFilter проверяет параметры запроса списка заказов и переводит их в фильтр базы данных.
+func newOrdersFilter(query OrdersQuery) (database.OrdersFilter, error) {
+	limit, err := pageLimit(query.Limit)
+	if err != nil {
+		return database.OrdersFilter{}, err
+	}
+	filter := database.OrdersFilter{Limit: limit, Ascending: query.Ascending}
 
-	return response, nil
+	for _, status := range query.Statuses {
+		if !isValidOrderStatus(status) {
+			return database.OrdersFilter{}, fmt.Errorf("%w: unknown status %q", cstmerr.ErrorInvalidFilter, status)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return database.OrdersFilter{}, fmt.Errorf("%w: from must be before to", cstmerr.ErrorInvalidFilter)
+	}
+	if query.From != nil {
+		from := query.From.UTC()
+		filter.From = &from
+	}
+	if query.To != nil {
+		to := query.To.UTC()
+		filter.To = &to
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return database.OrdersFilter{}, err
+		}
+		filter.AfterTime = &cursor.Time
+		filter.AfterNumber = cursor.ID
+	}
+	return filter, nil
+}
+
+// isValidOrderStatus проверяет, входит ли статус в OrderStatuses.
+func isValidOrderStatus(status string) bool {
+	for _, s := range OrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
 }
 
 // UploadOrder выполняет бизнес-логику для загрузки заказа пользователя.
 // Если произошла ошибка при выполнении запроса, возвращает ошибку.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //   - orderNumber: номер заказа.
 //
 // Возвращаемое значение:
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func UploadOrder(userID int64, orderNumber string) error {
+func UploadOrder(ctx context.Context, userID int64, orderNumber string) error {
+	ctx, span := tracing.Start(ctx, "services.UploadOrder")
+	defer span.End()
+
 	if !utils.CheckLunar(orderNumber) {
-		return errors.New("invalid order number")
+		return cstmerr.ErrorInvalidOrderNumber
 	}
 
-	ownerID, err := database.GetOrderOwner(orderNumber)
+	ownerID, err := database.GetOrderOwner(ctx, orderNumber)
 	if err != nil {
-		return errors.New("failed to get order owner")
+		return fmt.Errorf("failed to get order owner: %w", err)
 	}
 
 	if ownerID != nil {
 		if *ownerID == userID {
-			return errors.New("order already uploaded by you")
+			return cstmerr.ErrorOrderAlreadyUploaded
 		}
-		return errors.New("order already uploaded by another user")
+		return cstmerr.ErrorOrderUploadedByAnotherUser
 	}
 
-	if err := database.CreateOrder(userID, orderNumber); err != nil {
-		return errors.New("failed to create order")
+	if err := database.CreateOrder(ctx, userID, orderNumber); err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+// Cursor указывает на последнюю запись страницы: следующая страница начинается строго после неё.
+type Cursor struct {
+	Time time.Time // время создания записи
+	ID   int64     // идентификатор записи
+}
+
+// encodeCursor кодирует курсор в непрозрачную для клиента строку.
+//
+// Параметры:
+//   - cursor: курсор.
+//
+// Возвращаемое значение:
+//   - string: закодированный курсор.
+func encodeCursor(cursor Cursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.Time.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor разбирает строку, полученную от encodeCursor.
+// Если строка некорректна, возвращает ошибку cstmerr.ErrorInvalidCursor.
+//
+// Параметры:
+//   - value: закодированный курсор.
+//
+// Возвращаемое значение:
+//   - Cursor: курсор.
+//   - error: ошибка, если строка некорректна.
+func decodeCursor(value string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, cstmerr.ErrorInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return Cursor{}, cstmerr.ErrorInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, cstmerr.ErrorInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return Cursor{}, cstmerr.ErrorInvalidCursor
+	}
+	return Cursor{Time: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+// pageLimit приводит запрошенный размер страницы к допустимому диапазону.
+// Нулевой размер заменяется на config.PageDefaultLimit, отрицательный считается ошибкой.
+//
+// Параметры:
+//   - limit: запрошенный размер страницы.
+//
+// Возвращаемое значение:
+//   - int: размер страницы.
+//   - error: ошибка, если размер страницы отрицательный.
+func pageLimit(limit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, fmt.Errorf("%w: limit must be positive", cstmerr.ErrorInvalidFilter)
+	case limit == 0:
+		return config.PageDefaultLimit, nil
+	case limit > config.PageMaxLimit:
+		return config.PageMaxLimit, nil
+	}
+	return limit, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := Cursor{Time: time.Date(2024, 7, 1, 12, 30, 15, 123456000, time.UTC), ID: 12345678903}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	assert.NoError(t, err)
+	assert.True(t, cursor.Time.Equal(decoded.Time))
+	assert.Equal(t, cursor.ID, decoded.ID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "not_base64", value: "!!!"},
+		{name: "no_separator", value: "MTIz"},
+		{name: "not_numbers", value: "YTpi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.value)
+			assert.ErrorIs(t, err, cstmerr.ErrorInvalidCursor)
+		})
+	}
+}
+
+func TestPageLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		want    int
+		wantErr bool
+	}{
+		{name: "default", limit: 0, want: config.PageDefaultLimit},
+		{name: "regular", limit: 10, want: 10},
+		{name: "capped", limit: config.PageMaxLimit + 1, want: config.PageMaxLimit},
+		{name: "negative", limit: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pageLimit(tt.limit)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, cstmerr.ErrorInvalidFilter)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewOrdersFilter(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	filter, err := newOrdersFilter(OrdersQuery{Statuses: []string{"NEW", "PROCESSED"}, From: &from, To: &to})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"NEW", "PROCESSED"}, filter.Statuses)
+	assert.Equal(t, config.PageDefaultLimit, filter.Limit)
+
+	_, err = newOrdersFilter(OrdersQuery{Statuses: []string{"DONE"}})
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidFilter)
+
+	_, err = newOrdersFilter(OrdersQuery{From: &to, To: &from})
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidFilter)
+
+	_, err = newOrdersFilter(OrdersQuery{Cursor: "!!!"})
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidCursor)
+}
+
+func TestNewWithdrawalsFilter(t *testing.T) {
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	to := from.AddDate(0, 1, 0)
+	cursor := encodeCursor(Cursor{Time: to.Add(-time.Hour).UTC(), ID: 79927398713})
+
+	filter, err := newWithdrawalsFilter(WithdrawalsQuery{Limit: 20, From: &from, To: &to, Cursor: cursor})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, filter.Limit)
+	assert.Equal(t, time.UTC, filter.From.Location())
+	assert.True(t, from.Equal(*filter.From))
+	assert.Equal(t, int64(79927398713), filter.AfterNumber)
+
+	_, err = newWithdrawalsFilter(WithdrawalsQuery{From: &to, To: &from})
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidFilter)
+}
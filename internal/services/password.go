@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/notify"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// ValidatePassword проверяет пароль на соответствие требованиям config.Password.
+// Если пароль не подходит, возвращает ошибку, оборачивающую cstmerr.ErrorWeakPassword,
+// с перечислением нарушенных требований.
+//
+// Параметры:
+//   - password: пароль для проверки.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если пароль не удовлетворяет требованиям.
+func ValidatePassword(password string) error {
+	policy := config.Password
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSpecial = true
+		}
+	}
+
+	var violations []string
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		violations = append(violations, fmt.Sprintf("at least %d characters", policy.MinLength))
+	}
+	if policy.RequireUpper && !hasUpper {
+		violations = append(violations, "an uppercase letter")
+	}
+	if policy.RequireLower && !hasLower {
+		violations = append(violations, "a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		violations = append(violations, "a digit")
+	}
+	if policy.RequireSpecial && !hasSpecial {
+		violations = append(violations, "a special character")
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: password must contain %s", cstmerr.ErrorWeakPassword, strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// ChangePassword меняет пароль пользователя после проверки старого пароля.
+// Все ранее выданные пользователю токены становятся недействительными,
+// взамен возвращается новый токен.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - oldPassword: текущий пароль.
+//   - newPassword: новый пароль.
+//
+// Возвращаемое значение:
+//   - token: новый токен для доступа к системе лояльности.
+//   - error: ошибка, если произошла ошибка при смене пароля.
+func ChangePassword(ctx context.Context, userID int64, oldPassword, newPassword string) (string, error) {
+	ctx, span := tracing.Start(ctx, "services.ChangePassword")
+	defer span.End()
+
+	username, storedHash, err := database.GetUserCredentialsByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(oldPassword)); err != nil {
+		return "", cstmerr.ErrorInvalidPassword
+	}
+	if err = ValidatePassword(newPassword); err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if _, err = database.UpdateUserPassword(ctx, userID, string(hashedPassword)); err != nil {
+		return "", err
+	}
+
+	token, err := issueUserToken(ctx, username)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return token, nil
+}
+
+// RequestPasswordReset выпускает одноразовый токен сброса пароля и отправляет его пользователю
+// через notify.Default. Если пользователь не существует, молча ничего не делает,
+// чтобы по ответу нельзя было узнать, зарегистрирован ли логин.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - username: имя пользователя.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если произошла ошибка при выпуске или отправке токена.
+func RequestPasswordReset(ctx context.Context, username string) error {
+	ctx, span := tracing.Start(ctx, "services.RequestPasswordReset")
+	defer span.End()
+
+	userID, _, err := database.GetUserAuthInfo(ctx, username)
+	if errors.Is(err, cstmerr.ErrorUserDoesNotExist) {
+		config.LoggerFromContext(ctx).Info("Password reset requested for unknown user", zap.String("user", username))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		return err
+	}
+
+	if err = database.CreatePasswordResetToken(ctx, userID, hashResetToken(token), config.PasswordResetTTL); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Use this token to reset your password: %s\nThe token is valid for %s.", token, config.PasswordResetTTL)
+	if err = notify.Default.Notify(username, "Password reset", message); err != nil {
+		return fmt.Errorf("failed to send reset token: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword устанавливает новый пароль по токену сброса.
+// Токен одноразовый, после использования все ранее выданные пользователю токены доступа
+// становятся недействительными.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - token: токен сброса пароля.
+//   - newPassword: новый пароль.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если токен недействителен или произошла ошибка при смене пароля.
+func ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "services.ResetPassword")
+	defer span.End()
+
+	if err := ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return database.ResetPasswordByToken(ctx, hashResetToken(token), string(hashedPassword))
+}
+
+// generateResetToken генерирует случайный токен сброса пароля.
+func generateResetToken() (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	return token, nil
+}
+
+// hashResetToken возвращает SHA-256 хэш токена, под которым он хранится в базе данных.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
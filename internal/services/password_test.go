@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestValidatePassword(t *testing.T) {
+	type args struct {
+		password string
+		policy   config.PasswordPolicy
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "long_enough",
+			args:    args{password: "password", policy: config.PasswordPolicy{MinLength: 8}},
+			wantErr: false,
+		},
+		{
+			name:    "too_short",
+			args:    args{password: "pass", policy: config.PasswordPolicy{MinLength: 8}},
+			wantErr: true,
+		},
+		{
+			name:    "length_counted_in_runes",
+			args:    args{password: "пароль", policy: config.PasswordPolicy{MinLength: 6}},
+			wantErr: false,
+		},
+		{
+			name: "all_classes_present",
+			args: args{password: "Passw0rd!", policy: config.PasswordPolicy{
+				MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSpecial: true,
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "missing_digit",
+			args:    args{password: "Password", policy: config.PasswordPolicy{MinLength: 8, RequireDigit: true}},
+			wantErr: true,
+		},
+		{
+			name:    "missing_special",
+			args:    args{password: "Passw0rd", policy: config.PasswordPolicy{MinLength: 8, RequireSpecial: true}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Password = tt.args.policy
+			err := ValidatePassword(tt.args.password)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, cstmerr.ErrorWeakPassword, "ValidatePassword() should return error for test case: %v", tt.name)
+			} else {
+				assert.NoError(t, err, "ValidatePassword() failed for test case: %v", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// Состояния приглашения.
+const (
+	ReferralPending  = "pending"  // первый заказ приглашенного еще не обработан
+	ReferralRewarded = "rewarded" // бонусы начислены
+)
+
+// referralCodeAlphabet содержит символы реферального кода без легко путаемых 0/O и 1/I.
+// Длина алфавита делит 256, поэтому символы распределены равномерно.
+const referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// referralCodeLength задает длину реферального кода.
+const referralCodeLength = 8
+
+// referralCodeAttempts задает число попыток подобрать свободный код.
+const referralCodeAttempts = 3
+
+type ReferralResponse struct {
+	Login        string     `json:"login"`
+	Status       string     `json:"status"`
+	Bonus        float64    `json:"bonus"`
+	RegisteredAt time.Time  `json:"registered_at"`
+	RewardedAt   *time.Time `json:"rewarded_at,omitempty"`
+}
+
+type ReferralsResponse struct {
+	Code        string             `json:"code"`
+	Limit       int                `json:"limit"` // 0 - без ограничения
+	TotalEarned float64            `json:"total_earned"`
+	Referrals   []ReferralResponse `json:"referrals"`
+}
+
+// generateReferralCode генерирует случайный реферальный код.
+func generateReferralCode() (string, error) {
+	buf := make([]byte, referralCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate referral code: %w", err)
+	}
+	for i, b := range buf {
+		buf[i] = referralCodeAlphabet[int(b)%len(referralCodeAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// referralInvite проверяет реферальный код, переданный при регистрации, и формирует приглашение
+// с текущими настройками реферальной программы. Регистр и пробелы по краям кода не учитываются.
+//
+// Параметры:
+//   - code: реферальный код пригласившего пользователя.
+//
+// Возвращаемое значение:
+//   - *database.ReferralInvite: приглашение, nil, если код не передан.
+//   - error: cstmerr.ErrorInvalidReferralCode, если код не может быть реферальным.
+func referralInvite(code string) (*database.ReferralInvite, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, nil
+	}
+	if len(code) != referralCodeLength || strings.Trim(code, referralCodeAlphabet) != "" {
+		return nil, cstmerr.ErrorInvalidReferralCode
+	}
+	return &database.ReferralInvite{
+		Code:          code,
+		ReferrerBonus: config.ReferrerBonus,
+		RefereeBonus:  config.RefereeBonus,
+		Limit:         config.ReferralLimit,
+	}, nil
+}
+
+// newReferralsResponse формирует ответ со списком приглашенных и суммой начисленных за них бонусов.
+func newReferralsResponse(code string, limit int, referrals []database.Referral) ReferralsResponse {
+	response := ReferralsResponse{
+		Code:      code,
+		Limit:     limit,
+		Referrals: make([]ReferralResponse, len(referrals)),
+	}
+	for i, referral := range referrals {
+		item := ReferralResponse{
+			Login:        referral.RefereeLogin,
+			Status:       ReferralPending,
+			Bonus:        referral.Bonus,
+			RegisteredAt: referral.RegisteredAt,
+			RewardedAt:   referral.RewardedAt,
+		}
+		if referral.RewardedAt != nil {
+			item.Status = ReferralRewarded
+			response.TotalEarned += referral.Bonus
+		}
+		response.Referrals[i] = item
+	}
+	response.TotalEarned = roundPoints(response.TotalEarned)
+	return response
+}
+
+// GetReferrals выполняет бизнес-логику для получения реферального кода пользователя, приглашенных им пользователей
+// и начисленных за них бонусов. Пользователю, у которого еще нет кода, код создается.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращаемое значение:
+//   - ReferralsResponse: реферальный код и приглашенные пользователи, новые первыми.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func GetReferrals(ctx context.Context, userID int64) (ReferralsResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.GetReferrals")
+	defer span.End()
+
+	var code string
+	for attempt := 0; attempt < referralCodeAttempts && code == ""; attempt++ {
+		candidate, err := generateReferralCode()
+		if err != nil {
+			return ReferralsResponse{}, err
+		}
+		code, err = database.EnsureReferralCode(ctx, userID, candidate)
+		if err != nil {
+			return ReferralsResponse{}, err
+		}
+	}
+	if code == "" {
+		return ReferralsResponse{}, fmt.Errorf("failed to assign referral code to user %d", userID)
+	}
+
+	referrals, err := database.ListReferrals(ctx, userID)
+	if err != nil {
+		return ReferralsResponse{}, err
+	}
+	return newReferralsResponse(code, config.ReferralLimit, referrals), nil
+}
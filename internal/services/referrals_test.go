@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestGenerateReferralCode(t *testing.T) {
+	code, err := generateReferralCode()
+	require.NoError(t, err)
+	assert.Len(t, code, referralCodeLength)
+	assert.Empty(t, strings.Trim(code, referralCodeAlphabet))
+
+	other, err := generateReferralCode()
+	require.NoError(t, err)
+	assert.NotEqual(t, code, other)
+}
+
+func TestReferralInvite(t *testing.T) {
+	invite, err := referralInvite("  ")
+	require.NoError(t, err)
+	assert.Nil(t, invite)
+
+	invite, err = referralInvite(" k7m2qx9p ")
+	require.NoError(t, err)
+	require.NotNil(t, invite)
+	assert.Equal(t, "K7M2QX9P", invite.Code)
+	assert.Equal(t, config.ReferrerBonus, invite.ReferrerBonus)
+	assert.Equal(t, config.RefereeBonus, invite.RefereeBonus)
+	assert.Equal(t, config.ReferralLimit, invite.Limit)
+
+	for _, code := range []string{"K7M2QX9", "K7M2QX9PA", "K7M2QX9O", "K7M2-X9P"} {
+		_, err = referralInvite(code)
+		assert.ErrorIs(t, err, cstmerr.ErrorInvalidReferralCode, code)
+	}
+}
+
+func TestRegisterUser_InvalidReferralCode(t *testing.T) {
+	_, err := RegisterUser(context.Background(), "newcomer", "Str0ng!Passw0rd", "not a code", "127.0.0.1", "test")
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidReferralCode)
+}
+
+func TestNewReferralsResponse(t *testing.T) {
+	registeredAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	rewardedAt := registeredAt.Add(48 * time.Hour)
+	response := newReferralsResponse("K7M2QX9P", 50, []database.Referral{
+		{ID: 2, RefereeLogin: "bob", Bonus: 500, RegisteredAt: registeredAt.Add(time.Hour)},
+		{ID: 1, RefereeLogin: "alice", Bonus: 300.1, RegisteredAt: registeredAt, RewardedAt: &rewardedAt},
+	})
+
+	assert.Equal(t, "K7M2QX9P", response.Code)
+	assert.Equal(t, 50, response.Limit)
+	assert.Equal(t, 300.1, response.TotalEarned)
+	require.Len(t, response.Referrals, 2)
+	assert.Equal(t, ReferralPending, response.Referrals[0].Status)
+	assert.Nil(t, response.Referrals[0].RewardedAt)
+	assert.Equal(t, ReferralRewarded, response.Referrals[1].Status)
+	assert.Equal(t, &rewardedAt, response.Referrals[1].RewardedAt)
+
+	empty := newReferralsResponse("K7M2QX9P", 0, nil)
+	assert.NotNil(t, empty.Referrals)
+	assert.Zero(t, empty.TotalEarned)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+type ReversalRequest struct {
+	Amount float64 `json:"amount"` // сумма возврата, 0 - вернуть весь остаток списания
+	Reason string  `json:"reason"`
+}
+
+type ReversalResponse struct {
+	ID        int64     `json:"id"`
+	Order     string    `json:"order"`
+	Amount    float64   `json:"amount"`
+	Reason    string    `json:"reason"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReverseWithdrawalByAdmin выполняет бизнес-логику для возврата администратором баллов, списанных по заказу.
+// Возврат может быть частичным; без суммы возвращается весь невозвращенный остаток списания.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - orderNumber: номер заказа, по которому было списание.
+//   - req: сумма и причина возврата.
+//
+// Возвращаемое значение:
+//   - reversal: сохраненный возврат.
+//   - error: ошибка, если параметры некорректны, списание не найдено или сумма больше остатка списания.
+func ReverseWithdrawalByAdmin(ctx context.Context, adminID int64, orderNumber string, req ReversalRequest) (ReversalResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.ReverseWithdrawalByAdmin")
+	defer span.End()
+
+	return reverseWithdrawal(ctx, database.ReversalSourceAdmin, adminID, "", orderNumber, req)
+}
+
+// ReverseWithdrawalByMerchant выполняет бизнес-логику для возврата баллов мерчантом при отмене заказа, оплаченного баллами.
+// Правила те же, что и для возврата администратором, но вернуть можно только списание,
+// которое пользователь сделал в счет заказа этого мерчанта.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - merchant: мерчант, выполняющий возврат.
+//   - orderNumber: номер заказа, по которому было списание.
+//   - req: сумма и причина возврата.
+//
+// Возвращаемое значение:
+//   - reversal: сохраненный возврат.
+//   - error: ошибка, если мерчант не указан, параметры некорректны, списание не найдено или сумма больше остатка списания.
+func ReverseWithdrawalByMerchant(ctx context.Context, merchant Merchant, orderNumber string, req ReversalRequest) (ReversalResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.ReverseWithdrawalByMerchant")
+	defer span.End()
+
+	if merchant.Name == "" {
+		return ReversalResponse{}, cstmerr.ErrorInvalidAPIKey
+	}
+	return reverseWithdrawal(ctx, database.ReversalSourceMerchant, merchant.KeyID, merchant.Name, orderNumber, req)
+}
+
+func reverseWithdrawal(ctx context.Context, source string, actorID int64, merchant, orderNumber string, req ReversalRequest) (ReversalResponse, error) {
+	if req.Amount < 0 || math.IsNaN(req.Amount) || math.IsInf(req.Amount, 0) {
+		return ReversalResponse{}, cstmerr.ErrorInvalidAmount
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return ReversalResponse{}, cstmerr.ErrorReasonRequired
+	}
+
+	reversal, err := database.ReverseWithdrawal(ctx, database.WithdrawalReversal{
+		OrderNumber: strings.TrimSpace(orderNumber),
+		Amount:      req.Amount,
+		Reason:      reason,
+		Source:      source,
+		ActorID:     actorID,
+		Merchant:    merchant,
+	})
+	if err != nil {
+		return ReversalResponse{}, err
+	}
+	return newReversalResponse(reversal), nil
+}
+
+// attachReversals дополняет списания связанными с ними возвратами.
+func attachReversals(ctx context.Context, withdrawals []WithdrawResponse) error {
+	orderNumbers := make([]string, len(withdrawals))
+	for i, withdrawal := range withdrawals {
+		orderNumbers[i] = withdrawal.Order
+	}
+
+	reversals, err := database.FetchWithdrawalReversals(ctx, orderNumbers)
+	if err != nil {
+		return err
+	}
+
+	for i := range withdrawals {
+		for _, reversal := range reversals[withdrawals[i].Order] {
+			withdrawals[i].Refunded += reversal.Amount
+			withdrawals[i].Reversals = append(withdrawals[i].Reversals, newReversalResponse(reversal))
+		}
+	}
+	return nil
+}
+
+func newReversalResponse(reversal database.WithdrawalReversal) ReversalResponse {
+	return ReversalResponse{
+		ID:        reversal.ID,
+		Order:     reversal.OrderNumber,
+		Amount:    reversal.Amount,
+		Reason:    reversal.Reason,
+		Source:    reversal.Source,
+		CreatedAt: reversal.CreatedAt,
+	}
+}
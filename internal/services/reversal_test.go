@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestReverseWithdrawal_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ReversalRequest
+		wantErr error
+	}{
+		{
+			name:    "negative_amount",
+			req:     ReversalRequest{Amount: -10, Reason: "order cancelled"},
+			wantErr: cstmerr.ErrorInvalidAmount,
+		},
+		{
+			name:    "nan_amount",
+			req:     ReversalRequest{Amount: math.NaN(), Reason: "order cancelled"},
+			wantErr: cstmerr.ErrorInvalidAmount,
+		},
+		{
+			name:    "empty_reason",
+			req:     ReversalRequest{Amount: 10, Reason: "  "},
+			wantErr: cstmerr.ErrorReasonRequired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReverseWithdrawalByAdmin(context.Background(), 1, "2377225624", tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			_, err = ReverseWithdrawalByMerchant(context.Background(), Merchant{KeyID: 1, Name: "shop"}, "2377225624", tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestReverseWithdrawalByMerchant_RequiresMerchant(t *testing.T) {
+	// Без названия мерчанта нельзя проверить, что списание сделано в счет его заказа
+	_, err := ReverseWithdrawalByMerchant(context.Background(), Merchant{KeyID: 1}, "2377225624",
+		ReversalRequest{Amount: 10, Reason: "order cancelled"})
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidAPIKey)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+// Tier описывает уровень программы лояльности.
+type Tier struct {
+	Name       string  `json:"name"`
+	MinPoints  float64 `json:"min_points"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// TierResponse описывает текущий уровень пользователя и прогресс до следующего уровня.
+type TierResponse struct {
+	Tier             Tier       `json:"tier"`
+	QualifyingPoints float64    `json:"qualifying_points"` // начисления за последние WindowMonths месяцев
+	WindowMonths     int        `json:"window_months"`
+	NextTier         *Tier      `json:"next_tier,omitempty"`      // nil - достигнут наивысший уровень
+	PointsToNext     float64    `json:"points_to_next,omitempty"` // сколько баллов не хватает до следующего уровня
+	Progress         float64    `json:"progress"`                 // прогресс от порога текущего уровня до следующего, в процентах
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// FetchUserTier выполняет бизнес-логику для получения уровня пользователя и прогресса до следующего уровня.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//
+// Возвращаемое значение:
+//   - TierResponse: уровень пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchUserTier(ctx context.Context, userID int64) (TierResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.FetchUserTier")
+	defer span.End()
+
+	userTier, err := database.FetchUserTier(ctx, userID)
+	if err != nil {
+		return TierResponse{}, fmt.Errorf("failed to fetch user tier: %w", err)
+	}
+
+	response := tierProgress(config.Tiers, userTier.Tier, userTier.QualifyingPoints)
+	response.UpdatedAt = userTier.UpdatedAt
+	return response, nil
+}
+
+// tierProgress рассчитывает прогресс пользователя до следующего уровня.
+// Если уровня пользователя нет среди tiers, он определяется по сумме начислений.
+//
+// Параметры:
+//   - tiers: уровни программы лояльности по возрастанию порога.
+//   - current: название текущего уровня пользователя.
+//   - points: начисления пользователя за последние database.TierWindowMonths месяцев.
+//
+// Возвращаемое значение:
+//   - TierResponse: уровень пользователя и прогресс до следующего.
+func tierProgress(tiers []config.LoyaltyTier, current string, points float64) TierResponse {
+	response := TierResponse{QualifyingPoints: roundPoints(points), WindowMonths: database.TierWindowMonths}
+
+	index := -1
+	for i, tier := range tiers {
+		if tier.Name == current {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		for i, tier := range tiers {
+			if tier.MinPoints <= points {
+				index = i
+			}
+		}
+	}
+	if index < 0 {
+		response.Tier = Tier{Name: current, Multiplier: 1}
+		return response
+	}
+
+	tier := tiers[index]
+	response.Tier = Tier{Name: tier.Name, MinPoints: tier.MinPoints, Multiplier: tier.Multiplier}
+	if index == len(tiers)-1 {
+		response.Progress = 100
+		return response
+	}
+
+	next := tiers[index+1]
+	response.NextTier = &Tier{Name: next.Name, MinPoints: next.MinPoints, Multiplier: next.Multiplier}
+	response.PointsToNext = roundPoints(math.Max(0, next.MinPoints-points))
+	progress := (points - tier.MinPoints) / (next.MinPoints - tier.MinPoints)
+	response.Progress = roundPoints(math.Min(1, math.Max(0, progress)) * 100)
+	return response
+}
+
+// EvaluateTiers выполняет бизнес-логику ежесуточного пересчета уровней пользователей,
+// при котором уровень может понизиться, если начислений за последние 12 месяцев стало меньше порога.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func EvaluateTiers(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "services.EvaluateTiers")
+	defer span.End()
+
+	changed, err := database.EvaluateTiers(ctx)
+	if err != nil {
+		return err
+	}
+	config.LoggerFromContext(ctx).Info("Tiers evaluated", zap.Int64("changed", changed))
+	return nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+var testTiers = []config.LoyaltyTier{
+	{Name: "Basic", MinPoints: 0, Multiplier: 1},
+	{Name: "Silver", MinPoints: 1000, Multiplier: 1.1},
+	{Name: "Gold", MinPoints: 5000, Multiplier: 1.2},
+}
+
+func TestTierProgress(t *testing.T) {
+	tests := []struct {
+		name         string
+		current      string
+		points       float64
+		wantTier     string
+		wantNext     string
+		wantToNext   float64
+		wantProgress float64
+	}{
+		{name: "new_user", current: "Basic", points: 0, wantTier: "Basic", wantNext: "Silver", wantToNext: 1000, wantProgress: 0},
+		{name: "halfway", current: "Silver", points: 3000, wantTier: "Silver", wantNext: "Gold", wantToNext: 2000, wantProgress: 50},
+		{name: "top_tier", current: "Gold", points: 7000, wantTier: "Gold", wantProgress: 100},
+		// Уровень понижается только при ежесуточном пересчете, до него начислений может быть меньше порога
+		{name: "below_threshold", current: "Silver", points: 500, wantTier: "Silver", wantNext: "Gold", wantToNext: 4500, wantProgress: 0},
+		// Уровня нет в конфигурации, он определяется по начислениям
+		{name: "unknown_tier", current: "Bronze", points: 1200, wantTier: "Silver", wantNext: "Gold", wantToNext: 3800, wantProgress: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tierProgress(testTiers, tt.current, tt.points)
+			assert.Equal(t, tt.wantTier, got.Tier.Name)
+			if tt.wantNext == "" {
+				assert.Nil(t, got.NextTier)
+			} else {
+				require.NotNil(t, got.NextTier)
+				assert.Equal(t, tt.wantNext, got.NextTier.Name)
+			}
+			assert.Equal(t, tt.wantToNext, got.PointsToNext)
+			assert.Equal(t, tt.wantProgress, got.Progress)
+			assert.Equal(t, 12, got.WindowMonths)
+		})
+	}
+}
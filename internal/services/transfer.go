@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/notify"
+	"github.com/FollowLille/loyalty/internal/tracing"
+)
+
+type TransferRequest struct {
+	Recipient string  `json:"recipient"`
+	Amount    float64 `json:"amount"`
+}
+
+type TransferResponse struct {
+	ID        int64     `json:"id"`
+	Recipient string    `json:"recipient"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// validateTransfer проверяет запрос на перевод баллов без обращения к базе данных.
+//
+// Параметры:
+//   - req: запрос на перевод.
+//
+// Возвращаемое значение:
+//   - error: cstmerr.ErrorInvalidAmount или cstmerr.ErrorInvalidTransfer, если запрос некорректен,
+//     cstmerr.ErrorTransferLimitExceeded, если сумма больше допустимой для одного перевода.
+func validateTransfer(req TransferRequest) error {
+	if req.Amount <= 0 || math.IsNaN(req.Amount) || math.IsInf(req.Amount, 0) {
+		return cstmerr.ErrorInvalidAmount
+	}
+	if strings.TrimSpace(req.Recipient) == "" {
+		return fmt.Errorf("%w: recipient is required", cstmerr.ErrorInvalidTransfer)
+	}
+	if config.TransferMaxAmount > 0 && req.Amount > config.TransferMaxAmount {
+		return fmt.Errorf("%w: maximum transfer amount is %g", cstmerr.ErrorTransferLimitExceeded, config.TransferMaxAmount)
+	}
+	return nil
+}
+
+// TransferPoints выполняет бизнес-логику для перевода баллов другому пользователю.
+// Перевод выполняется атомарно с проверкой баланса и суточного лимита отправителя,
+// после успешного перевода получатель уведомляется через notify.Default.
+// Ошибка отправки уведомления не отменяет перевод.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - senderID: идентификатор отправителя.
+//   - req: запрос на перевод.
+//
+// Возвращаемое значение:
+//   - TransferResponse: выполненный перевод.
+//   - error: ошибка, если запрос некорректен, превышен лимит, недостаточно баллов, получатель не найден
+//     или произошла ошибка при выполнении запроса.
+func TransferPoints(ctx context.Context, senderID int64, req TransferRequest) (TransferResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.TransferPoints")
+	defer span.End()
+
+	req.Recipient = strings.TrimSpace(req.Recipient)
+	if err := validateTransfer(req); err != nil {
+		return TransferResponse{}, err
+	}
+
+	transfer, err := database.CreatePointTransfer(ctx, senderID, req.Recipient, req.Amount, config.TransferDailyLimit)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	message := fmt.Sprintf("You received %g points from %s.", transfer.Amount, transfer.SenderLogin)
+	if err := notify.Default.Notify(transfer.RecipientLogin, "Points received", message); err != nil {
+		config.LoggerFromContext(ctx).Error("Failed to notify transfer recipient", zap.Error(err), zap.Int64("transfer_id", transfer.ID))
+	}
+
+	return TransferResponse{
+		ID:        transfer.ID,
+		Recipient: transfer.RecipientLogin,
+		Amount:    transfer.Amount,
+		CreatedAt: transfer.CreatedAt,
+	}, nil
+}
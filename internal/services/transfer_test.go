@@ -0,0 +1,49 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestValidateTransfer(t *testing.T) {
+	maxAmount := config.TransferMaxAmount
+	config.TransferMaxAmount = 1000
+	defer func() { config.TransferMaxAmount = maxAmount }()
+
+	tests := []struct {
+		name    string
+		req     TransferRequest
+		wantErr error
+	}{
+		{name: "valid", req: TransferRequest{Recipient: "bob", Amount: 100}},
+		{name: "max_amount", req: TransferRequest{Recipient: "bob", Amount: 1000}},
+		{name: "zero_amount", req: TransferRequest{Recipient: "bob"}, wantErr: cstmerr.ErrorInvalidAmount},
+		{name: "negative_amount", req: TransferRequest{Recipient: "bob", Amount: -5}, wantErr: cstmerr.ErrorInvalidAmount},
+		{name: "nan_amount", req: TransferRequest{Recipient: "bob", Amount: math.NaN()}, wantErr: cstmerr.ErrorInvalidAmount},
+		{name: "empty_recipient", req: TransferRequest{Recipient: " ", Amount: 100}, wantErr: cstmerr.ErrorInvalidTransfer},
+		{name: "over_max_amount", req: TransferRequest{Recipient: "bob", Amount: 1000.01}, wantErr: cstmerr.ErrorTransferLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTransfer(tt.req)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestValidateTransfer_NoMaxAmount(t *testing.T) {
+	maxAmount := config.TransferMaxAmount
+	config.TransferMaxAmount = 0
+	defer func() { config.TransferMaxAmount = maxAmount }()
+
+	assert.NoError(t, validateTransfer(TransferRequest{Recipient: "bob", Amount: 1e9}))
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
+	"github.com/FollowLille/loyalty/internal/webhooks"
+)
+
+// WebhookEventTypes перечисляет типы событий, на которые можно подписаться.
+var WebhookEventTypes = []string{database.WebhookEventOrderProcessed, database.WebhookEventWithdrawalCreate}
+
+// webhookSecretPrefix - префикс секрета подписки, по которому его легко узнать в конфигурации получателя.
+const webhookSecretPrefix = "whsec_"
+
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+type WebhookResponse struct {
+	ID         int64      `json:"id"`
+	URL        string     `json:"url"`
+	EventTypes []string   `json:"event_types"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Secret     string     `json:"secret,omitempty"` // секрет подписи, возвращается только при создании
+}
+
+type WebhookAttemptResponse struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode *int      `json:"status_code,omitempty"`
+	Error      *string   `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64                    `json:"id"`
+	EventID        int64                    `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Status         string                   `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int                     `json:"last_status_code,omitempty"`
+	LastError      *string                  `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	AttemptLog     []WebhookAttemptResponse `json:"attempt_log"`
+}
+
+// webhookClient отправляет вебхуки диспетчера.
+var webhookClient = webhooks.NewClient()
+
+// CreateWebhook выполняет бизнес-логику для создания подписки на вебхуки.
+// Секрет для проверки подписи генерируется сервисом и возвращается только один раз.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - req: адрес получателя и типы событий.
+//
+// Возвращаемое значение:
+//   - WebhookResponse: созданная подписка вместе с секретом.
+//   - error: ошибка, если параметры некорректны или произошла ошибка при сохранении подписки.
+func CreateWebhook(ctx context.Context, adminID int64, req WebhookRequest) (WebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.CreateWebhook")
+	defer span.End()
+
+	target := strings.TrimSpace(req.URL)
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return WebhookResponse{}, fmt.Errorf("%w: url must be an absolute http or https URL", cstmerr.ErrorInvalidWebhook)
+	}
+	if len(req.EventTypes) == 0 {
+		return WebhookResponse{}, fmt.Errorf("%w: at least one event type is required", cstmerr.ErrorInvalidWebhook)
+	}
+	for _, eventType := range req.EventTypes {
+		if !isValidWebhookEventType(eventType) {
+			return WebhookResponse{}, fmt.Errorf("%w: unknown event type %s, allowed types are %s",
+				cstmerr.ErrorInvalidWebhook, eventType, strings.Join(WebhookEventTypes, ", "))
+		}
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return WebhookResponse{}, err
+	}
+
+	subscription, err := database.CreateWebhookSubscription(ctx, database.WebhookSubscription{
+		URL:        target,
+		Secret:     webhookSecretPrefix + secret,
+		EventTypes: req.EventTypes,
+		CreatedBy:  adminID,
+	})
+	if err != nil {
+		return WebhookResponse{}, err
+	}
+
+	response := newWebhookResponse(subscription)
+	response.Secret = subscription.Secret
+	return response, nil
+}
+
+// ListWebhooks выполняет бизнес-логику для получения списка подписок на вебхуки без секретов.
+//
+// Возвращаемое значение:
+//   - []WebhookResponse: подписки.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func ListWebhooks(ctx context.Context) ([]WebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.ListWebhooks")
+	defer span.End()
+
+	subscriptions, err := database.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]WebhookResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		response[i] = newWebhookResponse(subscription)
+	}
+	return response, nil
+}
+
+// RevokeWebhook выполняет бизнес-логику для отзыва подписки на вебхуки.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - subscriptionID: идентификатор подписки.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если подписка не найдена или произошла ошибка при выполнении запроса.
+func RevokeWebhook(ctx context.Context, adminID, subscriptionID int64) error {
+	ctx, span := tracing.Start(ctx, "services.RevokeWebhook")
+	defer span.End()
+
+	return database.RevokeWebhookSubscription(ctx, adminID, subscriptionID)
+}
+
+// ListWebhookDeliveries выполняет бизнес-логику для получения журнала доставок подписки.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - subscriptionID: идентификатор подписки.
+//   - status: состояние доставок (pending, delivered, dead), пустая строка - все.
+//   - limit: максимальное число доставок.
+//
+// Возвращаемое значение:
+//   - []WebhookDeliveryResponse: доставки с попытками, новые первыми.
+//   - error: ошибка, если фильтр некорректен, подписка не найдена или произошла ошибка при выполнении запроса.
+func ListWebhookDeliveries(ctx context.Context, subscriptionID int64, status string, limit int) ([]WebhookDeliveryResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.ListWebhookDeliveries")
+	defer span.End()
+
+	switch status {
+	case "", database.WebhookDeliveryPending, database.WebhookDeliveryDelivered, database.WebhookDeliveryDead:
+	default:
+		return nil, fmt.Errorf("%w: status must be one of %s, %s, %s", cstmerr.ErrorInvalidFilter,
+			database.WebhookDeliveryPending, database.WebhookDeliveryDelivered, database.WebhookDeliveryDead)
+	}
+
+	deliveries, err := database.ListWebhookDeliveries(ctx, subscriptionID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]WebhookDeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		response[i] = WebhookDeliveryResponse{
+			ID:             d.ID,
+			EventID:        d.OutboxID,
+			EventType:      d.EventType,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			DeliveredAt:    d.DeliveredAt,
+			CreatedAt:      d.CreatedAt,
+			AttemptLog:     make([]WebhookAttemptResponse, len(d.AttemptLog)),
+		}
+		if d.Status == database.WebhookDeliveryPending {
+			next := d.NextAttemptAt
+			response[i].NextAttemptAt = &next
+		}
+		for j, a := range d.AttemptLog {
+			response[i].AttemptLog[j] = WebhookAttemptResponse{
+				Attempt:    a.Attempt,
+				StatusCode: a.StatusCode,
+				Error:      a.Error,
+				DurationMS: a.Duration.Milliseconds(),
+				CreatedAt:  a.CreatedAt,
+			}
+		}
+	}
+	return response, nil
+}
+
+// RetryWebhookDelivery выполняет бизнес-логику для повторной отправки доставки в состоянии dead.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - adminID: идентификатор администратора.
+//   - deliveryID: идентификатор доставки.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если доставка не найдена или произошла ошибка при выполнении запроса.
+func RetryWebhookDelivery(ctx context.Context, adminID, deliveryID int64) error {
+	ctx, span := tracing.Start(ctx, "services.RetryWebhookDelivery")
+	defer span.End()
+
+	return database.RetryWebhookDelivery(ctx, adminID, deliveryID)
+}
+
+// DispatchWebhooks выполняет доставки, для которых наступило время очередной попытки.
+// Неудачная доставка откладывается с экспоненциально растущей задержкой,
+// после config.WebhookMaxAttempts попыток переводится в состояние dead.
+// Новая попытка начинается, только если она успеет завершиться до окончания аренды доставок,
+// иначе оставшиеся доставки заберет проход после окончания аренды, и ни одна доставка не будет отправлена дважды.
+//
+// Возвращаемое значение:
+//   - error: ошибка, если не удалось получить доставки из очереди.
+func DispatchWebhooks(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "services.DispatchWebhooks")
+	defer span.End()
+
+	leaseEnd := time.Now().Add(config.WebhookLease)
+	deliveries, err := database.ClaimWebhookDeliveries(ctx, config.WebhookBatchSize, config.WebhookLease)
+	if err != nil {
+		return err
+	}
+
+	for i, d := range deliveries {
+		if time.Now().Add(config.WebhookTimeout).After(leaseEnd) {
+			config.LoggerFromContext(ctx).Warn("Webhook lease is running out, postponing remaining deliveries",
+				zap.Int("postponed", len(deliveries)-i))
+			break
+		}
+		event := webhooks.Event{ID: d.OutboxID, Type: d.EventType, CreatedAt: d.EventCreatedAt, Data: d.Payload}
+		result := webhookClient.Send(ctx, d.URL, d.Secret, event)
+
+		attempt := database.WebhookAttempt{DeliveryID: d.ID, Attempt: d.Attempts + 1, Duration: result.Duration}
+		if result.StatusCode != 0 {
+			code := result.StatusCode
+			attempt.StatusCode = &code
+		}
+		if result.Err != nil {
+			message := result.Err.Error()
+			attempt.Error = &message
+		}
+
+		status, nextAttemptAt := nextWebhookState(attempt.Attempt, result, time.Now())
+		if err := database.SaveWebhookAttempt(ctx, attempt, status, nextAttemptAt); err != nil {
+			config.LoggerFromContext(ctx).Error("Failed to save webhook attempt", zap.Error(err), zap.Int64("delivery_id", d.ID))
+			continue
+		}
+
+		switch status {
+		case database.WebhookDeliveryDelivered:
+			config.LoggerFromContext(ctx).Info("Webhook delivered", zap.Int64("delivery_id", d.ID), zap.String("event_type", d.EventType))
+		case database.WebhookDeliveryDead:
+			config.LoggerFromContext(ctx).Warn("Webhook delivery is dead", zap.Int64("delivery_id", d.ID), zap.Error(result.Err))
+		default:
+			config.LoggerFromContext(ctx).Info("Webhook delivery failed, will retry",
+				zap.Int64("delivery_id", d.ID), zap.Error(result.Err), zap.Time("next_attempt_at", nextAttemptAt))
+		}
+	}
+	return nil
+}
+
+// nextWebhookState определяет состояние доставки и время следующей попытки по результату попытки.
+func nextWebhookState(attempt int, result webhooks.Result, now time.Time) (string, time.Time) {
+	if result.OK() {
+		return database.WebhookDeliveryDelivered, now
+	}
+	if attempt >= config.WebhookMaxAttempts {
+		return database.WebhookDeliveryDead, now
+	}
+	return database.WebhookDeliveryPending, now.Add(webhooks.Backoff(attempt))
+}
+
+func isValidWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookResponse(subscription database.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+		RevokedAt:  subscription.RevokedAt,
+	}
+}
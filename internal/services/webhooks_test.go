@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/FollowLille/loyalty/internal/config"
+	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/webhooks"
+)
+
+func TestCreateWebhook_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  WebhookRequest
+	}{
+		{
+			name: "empty_url",
+			req:  WebhookRequest{EventTypes: []string{database.WebhookEventOrderProcessed}},
+		},
+		{
+			name: "relative_url",
+			req:  WebhookRequest{URL: "/hooks", EventTypes: []string{database.WebhookEventOrderProcessed}},
+		},
+		{
+			name: "unsupported_scheme",
+			req:  WebhookRequest{URL: "ftp://partner.example/hooks", EventTypes: []string{database.WebhookEventOrderProcessed}},
+		},
+		{
+			name: "no_event_types",
+			req:  WebhookRequest{URL: "https://partner.example/hooks"},
+		},
+		{
+			name: "unknown_event_type",
+			req:  WebhookRequest{URL: "https://partner.example/hooks", EventTypes: []string{"user.deleted"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateWebhook(context.Background(), 1, tt.req)
+			assert.ErrorIs(t, err, cstmerr.ErrorInvalidWebhook, "CreateWebhook(context.Background()) should reject test case: %v", tt.name)
+		})
+	}
+}
+
+func TestListWebhookDeliveries_InvalidStatus(t *testing.T) {
+	_, err := ListWebhookDeliveries(context.Background(), 1, "lost", 10)
+	assert.ErrorIs(t, err, cstmerr.ErrorInvalidFilter)
+}
+
+func TestNextWebhookState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	failed := webhooks.Result{StatusCode: 500, Err: assert.AnError}
+
+	status, _ := nextWebhookState(1, webhooks.Result{StatusCode: 200}, now)
+	assert.Equal(t, database.WebhookDeliveryDelivered, status)
+
+	status, next := nextWebhookState(1, failed, now)
+	assert.Equal(t, database.WebhookDeliveryPending, status)
+	assert.Equal(t, now.Add(config.WebhookRetryBaseDelay), next)
+
+	status, _ = nextWebhookState(config.WebhookMaxAttempts, failed, now)
+	assert.Equal(t, database.WebhookDeliveryDead, status)
+}
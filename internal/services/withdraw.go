@@ -1,52 +1,92 @@
 package services
 
 import (
+	"context"
 	"errors"
-	"github.com/FollowLille/loyalty/internal/config"
-	"go.uber.org/zap"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"go.uber.org/zap"
+
+	"github.com/FollowLille/loyalty/internal/config"
 	"github.com/FollowLille/loyalty/internal/database"
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+	"github.com/FollowLille/loyalty/internal/tracing"
 	"github.com/FollowLille/loyalty/internal/utils"
 )
 
 type WithdrawRequest struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order    string  `json:"order"`
+	Sum      float64 `json:"sum"`
+	Merchant string  `json:"merchant,omitempty"` // мерчант, в счет заказа которого списываются баллы
 }
 
 type WithdrawResponse struct {
-	Order       string    `json:"order"`
-	Sum         float64   `json:"sum"`
-	ProcessedAt time.Time `json:"processed_at"`
+	Order       string             `json:"order"`
+	Sum         float64            `json:"sum"`
+	ProcessedAt time.Time          `json:"processed_at"`
+	Merchant    string             `json:"merchant,omitempty"`  // мерчант, в счет заказа которого списаны баллы
+	Refunded    float64            `json:"refunded,omitempty"`  // сумма, возвращенная по списанию
+	Reversals   []ReversalResponse `json:"reversals,omitempty"` // возвраты по списанию
+}
+
+// WithdrawalsQuery описывает параметры запроса списка списаний пользователя.
+type WithdrawalsQuery struct {
+	Limit   int        // размер страницы, 0 - размер по умолчанию
+	Cursor  string     // курсор следующей страницы из предыдущего ответа
+	From    *time.Time // списания не раньше указанного времени
+	To      *time.Time // списания раньше указанного времени
+	Summary bool       // рассчитать итоги по списаниям за период
+}
+
+// WithdrawalsSummary описывает итоги по списаниям пользователя за период.
+type WithdrawalsSummary struct {
+	Count    int64   `json:"count"`
+	Total    float64 `json:"total"`    // сумма списаний за вычетом возвратов
+	Refunded float64 `json:"refunded"` // сумма возвратов по этим списаниям
+}
+
+// WithdrawalsPage описывает страницу списка списаний пользователя.
+type WithdrawalsPage struct {
+	Withdrawals []WithdrawResponse  `json:"withdrawals"`
+	Summary     *WithdrawalsSummary `json:"summary,omitempty"`
+	NextCursor  string              `json:"next_cursor,omitempty"`
 }
 
 // ProcessWithdrawRequest выполняет бизнес-логику для обработки запроса на вывод средств.
+// Если указан мерчант, списание закрепляется за ним, и только он сможет вернуть его по API-ключу.
 // Если произошла ошибка при выполнении запроса, возвращает ошибку.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //   - req: запрос на вывод средств.
 //
 // Возвращаемое значение:
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func ProcessWithdrawRequest(userID int64, req WithdrawRequest) error {
-	if !utils.CheckLunar(req.Order) {
-		return errors.New("invalid order number")
-	}
+func ProcessWithdrawRequest(ctx context.Context, userID int64, req WithdrawRequest) error {
+	ctx, span := tracing.Start(ctx, "services.ProcessWithdrawRequest")
+	defer span.End()
 
-	currentBalance, _, err := database.FetchUserBalance(userID)
-	if err != nil {
-		return errors.New("failed to fetch user balance")
+	if !utils.CheckLunar(req.Order) {
+		return cstmerr.ErrorInvalidOrderNumber
 	}
 
-	if currentBalance < req.Sum {
-		return errors.New("insufficient balance")
+	// Баланс проверяется в RegisterWithdraw под блокировкой пользователя
+	merchant := strings.TrimSpace(req.Merchant)
+	if len(merchant) > maxMerchantNameLength {
+		return fmt.Errorf("%w: merchant name is longer than %d characters", cstmerr.ErrorInvalidRequest, maxMerchantNameLength)
 	}
 
-	if err := database.RegisterWithdraw(userID, req.Order, req.Sum); err != nil {
-		config.Logger.Error("Failed to register withdrawal", zap.Error(err))
-		return errors.New("failed to register withdrawal")
+	if err := database.RegisterWithdraw(ctx, userID, req.Order, req.Sum, merchant); err != nil {
+		if errors.Is(err, cstmerr.ErrorInsufficientBalance) || errors.Is(err, cstmerr.ErrorMerchantNotFound) ||
+			errors.Is(err, cstmerr.ErrorOrderAlreadyExists) {
+			return err
+		}
+		config.LoggerFromContext(ctx).Error("Failed to register withdrawal", zap.Error(err))
+		return fmt.Errorf("failed to register withdrawal: %w", err)
 	}
 
 	return nil
@@ -57,15 +97,19 @@ func ProcessWithdrawRequest(userID int64, req WithdrawRequest) error {
 // В случае успеха, возвращает список выводов пользователя.
 //
 // Параметры:
+//   - ctx: контекст запроса.
 //   - userID: идентификатор пользователя.
 //
 // Возвращаемое значение:
 //   - withdrawals: список выводов пользователя.
 //   - error: ошибка, если произошла ошибка при выполнении запроса.
-func FetchWithdrawals(userID int64) ([]WithdrawResponse, error) {
-	withdrawals, err := database.FetchUserWithdrawals(userID)
+func FetchWithdrawals(ctx context.Context, userID int64) ([]WithdrawResponse, error) {
+	ctx, span := tracing.Start(ctx, "services.FetchWithdrawals")
+	defer span.End()
+
+	withdrawals, err := database.FetchUserWithdrawals(ctx, userID)
 	if err != nil {
-		return nil, errors.New("failed to fetch user withdrawals")
+		return nil, fmt.Errorf("failed to fetch user withdrawals: %w", err)
 	}
 
 	response := make([]WithdrawResponse, len(withdrawals))
@@ -74,8 +118,107 @@ func FetchWithdrawals(userID int64) ([]WithdrawResponse, error) {
 			Order:       withdrawal.OrderNumber,
 			Sum:         withdrawal.Sum,
 			ProcessedAt: withdrawal.ProcessedAt,
+			Merchant:    withdrawal.Merchant,
 		}
 	}
+	if err := attachReversals(ctx, response); err != nil {
+		return nil, fmt.Errorf("failed to fetch withdrawal reversals: %w", err)
+	}
 
 	return response, nil
 }
+
+// FetchWithdrawalsPage выполняет бизнес-логику для получения страницы списка выводов пользователя.
+// Если запрошены итоги, они считаются по всем списаниям за период, а не только по текущей странице.
+// Если параметры запроса некорректны, возвращает ошибку cstmerr.ErrorInvalidFilter или cstmerr.ErrorInvalidCursor.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - userID: идентификатор пользователя.
+//   - query: параметры запроса.
+//
+// Возвращаемое значение:
+//   - page: страница выводов пользователя.
+//   - error: ошибка, если произошла ошибка при выполнении запроса.
+func FetchWithdrawalsPage(ctx context.Context, userID int64, query WithdrawalsQuery) (WithdrawalsPage, error) {
+	ctx, span := tracing.Start(ctx, "services.FetchWithdrawalsPage")
+	defer span.End()
+
+	filter, err := newWithdrawalsFilter(query)
+	if err != nil {
+		return WithdrawalsPage{}, err
+	}
+	limit := filter.Limit
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	filter.Limit++
+
+	withdrawals, err := database.FetchUserWithdrawalsPage(ctx, userID, filter)
+	if err != nil {
+		return WithdrawalsPage{}, fmt.Errorf("failed to fetch user withdrawals: %w", err)
+	}
+
+	var page WithdrawalsPage
+	if len(withdrawals) > limit {
+		withdrawals = withdrawals[:limit]
+		last := withdrawals[limit-1]
+		number, err := strconv.ParseInt(last.OrderNumber, 10, 64)
+		if err != nil {
+			return WithdrawalsPage{}, fmt.Errorf("failed to parse order number: %w", err)
+		}
+		page.NextCursor = encodeCursor(Cursor{Time: last.ProcessedAt, ID: number})
+	}
+
+	page.Withdrawals = make([]WithdrawResponse, len(withdrawals))
+	for i, withdrawal := range withdrawals {
+		page.Withdrawals[i] = WithdrawResponse{
+			Order:       withdrawal.OrderNumber,
+			Sum:         withdrawal.Sum,
+			ProcessedAt: withdrawal.ProcessedAt,
+			Merchant:    withdrawal.Merchant,
+		}
+	}
+	if err := attachReversals(ctx, page.Withdrawals); err != nil {
+		return WithdrawalsPage{}, fmt.Errorf("failed to fetch withdrawal reversals: %w", err)
+	}
+
+	if query.Summary {
+		summary, err := database.FetchUserWithdrawalsSummary(ctx, userID, filter.From, filter.To)
+		if err != nil {
+			return WithdrawalsPage{}, fmt.Errorf("failed to fetch user withdrawals summary: %w", err)
+		}
+		page.Summary = &WithdrawalsSummary{Count: summary.Count, Total: summary.Total, Refunded: summary.Refunded}
+	}
+
+	return page, nil
+}
+
+// newWithdrawalsFilter проверяет параметры запроса списка списаний и переводит их в фильтр базы данных.
+func newWithdrawalsFilter(query WithdrawalsQuery) (database.WithdrawalsFilter, error) {
+	limit, err := pageLimit(query.Limit)
+	if err != nil {
+		return database.WithdrawalsFilter{}, err
+	}
+	filter := database.WithdrawalsFilter{Limit: limit}
+
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return database.WithdrawalsFilter{}, fmt.Errorf("%w: from must be before to", cstmerr.ErrorInvalidFilter)
+	}
+	if query.From != nil {
+		from := query.From.UTC()
+		filter.From = &from
+	}
+	if query.To != nil {
+		to := query.To.UTC()
+		filter.To = &to
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return database.WithdrawalsFilter{}, err
+		}
+		filter.AfterTime = &cursor.Time
+		filter.AfterNumber = cursor.ID
+	}
+	return filter, nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cstmerr "github.com/FollowLille/loyalty/internal/errors"
+)
+
+func TestProcessWithdrawRequest_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     WithdrawRequest
+		wantErr error
+	}{
+		{
+			name:    "bad_order_number",
+			req:     WithdrawRequest{Order: "2377225625", Sum: 10},
+			wantErr: cstmerr.ErrorInvalidOrderNumber,
+		},
+		{
+			name:    "merchant_name_too_long",
+			req:     WithdrawRequest{Order: "2377225624", Sum: 10, Merchant: strings.Repeat("m", maxMerchantNameLength+1)},
+			wantErr: cstmerr.ErrorInvalidRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, ProcessWithdrawRequest(context.Background(), 1, tt.req), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package tracing настраивает трассировку OpenTelemetry в системе лояльности.
+// Трассы передаются в выбранный экспортер, контекст трассы распространяется по W3C traceparent.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортеры трасс.
+const (
+	ExporterOTLP   = "otlp"   // OTLP/HTTP, адрес задается переменными окружения OTEL_EXPORTER_OTLP_*
+	ExporterStdout = "stdout" // вывод трасс в стандартный вывод для локальной проверки
+	ExporterNone   = "none"   // трассы не записываются
+)
+
+// ServiceName - имя сервиса в трассах.
+const ServiceName = "gophermart"
+
+// instrumentationName - имя, под которым создаются спаны приложения.
+const instrumentationName = "github.com/FollowLille/loyalty"
+
+// Init настраивает глобальные TracerProvider и пропагатор W3C traceparent.
+// Пропагатор настраивается и без экспортера, чтобы входящий контекст трассы передавался в систему начислений.
+//
+// Параметры:
+//   - ctx: контекст инициализации.
+//   - exporter: экспортер трасс: otlp, stdout или none.
+//
+// Возвращает:
+//   - func(context.Context) error: функция, отправляющая накопленные трассы и останавливающая экспортер.
+//   - error: ошибка, если экспортер неизвестен или не удалось его создать.
+func Init(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	spanExporter, err := newExporter(ctx, exporter, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	if spanExporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var processor sdktrace.TracerProviderOption
+	if exporter == ExporterStdout {
+		// Спаны выводятся сразу по завершении, чтобы их было видно при локальной проверке
+		processor = sdktrace.WithSyncer(spanExporter)
+	} else {
+		processor = sdktrace.WithBatcher(spanExporter)
+	}
+	provider := sdktrace.NewTracerProvider(
+		processor,
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newExporter создает экспортер трасс по названию.
+//
+// Параметры:
+//   - ctx: контекст инициализации.
+//   - exporter: экспортер трасс: otlp, stdout или none.
+//   - w: куда выводить трассы экспортеру stdout.
+//
+// Возвращает:
+//   - sdktrace.SpanExporter: экспортер, nil для none.
+//   - error: ошибка, если экспортер неизвестен или не удалось его создать.
+func newExporter(ctx context.Context, exporter string, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch exporter {
+	case ExporterNone, "":
+		return nil, nil
+	case ExporterStdout:
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+		return spanExporter, nil
+	case ExporterOTLP:
+		spanExporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		return spanExporter, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s, %s or %s", exporter, ExporterOTLP, ExporterStdout, ExporterNone)
+	}
+}
+
+// Start начинает спан с указанным именем, дочерний по отношению к спану из ctx.
+//
+// Параметры:
+//   - ctx: родительский контекст.
+//   - name: имя спана.
+//   - attrs: атрибуты спана.
+//
+// Возвращает:
+//   - context.Context: контекст с новым спаном.
+//   - trace.Span: спан, который нужно завершить вызовом End.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartClient начинает спан исходящего запроса к внешней системе.
+//
+// Параметры:
+//   - ctx: родительский контекст.
+//   - name: имя спана.
+//   - attrs: атрибуты спана.
+//
+// Возвращает:
+//   - context.Context: контекст с новым спаном.
+//   - trace.Span: спан, который нужно завершить вызовом End.
+func StartClient(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// End завершает спан, отмечая его ошибкой, если err не nil.
+//
+// Параметры:
+//   - span: спан.
+//   - err: результат операции.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
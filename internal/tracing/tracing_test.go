@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans подменяет глобальный TracerProvider на записывающий спаны в память.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestNewExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "none", exporter: ExporterNone, wantNil: true},
+		{name: "empty", exporter: "", wantNil: true},
+		{name: "stdout", exporter: ExporterStdout},
+		{name: "unknown", exporter: "jaeger", wantNil: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := newExporter(context.Background(), tt.exporter, &bytes.Buffer{})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantNil, exporter == nil)
+		})
+	}
+}
+
+func TestEnd(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, span := Start(context.Background(), "ok")
+	End(span, nil)
+	_, span = StartClient(context.Background(), "failed")
+	End(span, errors.New("boom"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, trace.SpanKindClient, spans[1].SpanKind())
+	assert.Len(t, spans[1].Events(), 1)
+}
+
+func TestTraceparentPropagation(t *testing.T) {
+	recordSpans(t)
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	incoming := http.Header{}
+	incoming.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(incoming))
+
+	ctx, span := StartClient(ctx, "outgoing")
+	defer span.End()
+	outgoing := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(outgoing))
+
+	assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+	assert.Contains(t, outgoing.Get("traceparent"), traceID)
+	assert.NotContains(t, outgoing.Get("traceparent"), "00f067aa0ba902b7")
+}
@@ -0,0 +1,167 @@
+// Package webhooks предоставляет отправку исходящих вебхуков: формирование тела события,
+// подпись HMAC-SHA256 и расчет задержки перед повторной попыткой.
+// Очередь доставок хранится в базе данных, пакет отвечает только за один HTTP-запрос к получателю.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FollowLille/loyalty/internal/config"
+)
+
+// Заголовки запроса вебхука.
+const (
+	HeaderEvent     = "X-Webhook-Event"     // тип события
+	HeaderID        = "X-Webhook-Id"        // идентификатор события, одинаковый для всех повторов
+	HeaderTimestamp = "X-Webhook-Timestamp" // время отправки в секундах Unix
+	HeaderSignature = "X-Webhook-Signature" // подпись "sha256=<hex>"
+)
+
+// signaturePrefix - префикс значения заголовка HeaderSignature.
+const signaturePrefix = "sha256="
+
+// Event описывает тело запроса вебхука.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Result описывает результат попытки доставки.
+type Result struct {
+	StatusCode int           // код ответа получателя, 0 - ответ не получен
+	Duration   time.Duration // длительность запроса
+	Err        error         // ошибка отправки или ответ с кодом не 2xx
+}
+
+// OK сообщает, что получатель принял событие.
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// Client отправляет вебхуки получателям.
+type Client struct {
+	HTTP *http.Client
+}
+
+// NewClient создает клиента с таймаутом запроса config.WebhookTimeout.
+//
+// Возвращает:
+//   - *Client: клиент.
+func NewClient() *Client {
+	return &Client{HTTP: &http.Client{Timeout: config.WebhookTimeout}}
+}
+
+// Send отправляет событие получателю, подписывая тело секретом подписки.
+// Ответ с кодом 2xx считается успешной доставкой, перенаправления не выполняются.
+//
+// Параметры:
+//   - ctx: контекст запроса.
+//   - url: адрес получателя.
+//   - secret: секрет подписки.
+//   - event: событие.
+//
+// Возвращает:
+//   - Result: результат попытки.
+func (c *Client) Send(ctx context.Context, url, secret string, event Event) Result {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return Result{Err: fmt.Errorf("failed to marshal webhook event: %w", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: fmt.Errorf("failed to create webhook request: %w", err)}
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderID, strconv.FormatInt(event.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	client := *c.HTTP
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return Result{Duration: duration, Err: fmt.Errorf("failed to send webhook: %w", err)}
+	}
+	defer resp.Body.Close()
+	// Тело ответа не нужно, но вычитывается, чтобы соединение можно было переиспользовать
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	result := Result{StatusCode: resp.StatusCode, Duration: duration}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return result
+}
+
+// Sign вычисляет подпись тела вебхука: HMAC-SHA256 от строки "<timestamp>.<body>".
+// Метка времени входит в подпись, чтобы получатель мог отклонять повторно отправленные старые запросы.
+//
+// Параметры:
+//   - secret: секрет подписки.
+//   - timestamp: время отправки в секундах Unix.
+//   - body: тело запроса.
+//
+// Возвращает:
+//   - string: значение заголовка HeaderSignature.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись вебхука. Предназначена для получателей и тестов.
+//
+// Параметры:
+//   - secret: секрет подписки.
+//   - timestamp: значение заголовка HeaderTimestamp.
+//   - body: тело запроса.
+//   - signature: значение заголовка HeaderSignature.
+//
+// Возвращает:
+//   - bool: true, если подпись верна.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature))
+}
+
+// Backoff возвращает задержку перед следующей попыткой после неудачной попытки с номером attempt:
+// config.WebhookRetryBaseDelay, затем удваивается, но не больше config.WebhookRetryMaxDelay.
+//
+// Параметры:
+//   - attempt: номер неудачной попытки, начиная с 1.
+//
+// Возвращает:
+//   - time.Duration: задержка.
+func Backoff(attempt int) time.Duration {
+	delay := config.WebhookRetryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= config.WebhookRetryMaxDelay {
+			return config.WebhookRetryMaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,106 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Send(t *testing.T) {
+	const secret = "whsec_test"
+	event := Event{ID: 42, Type: "order.processed", CreatedAt: time.Now().UTC(), Data: json.RawMessage(`{"number":"12345678903"}`)}
+
+	var received Event
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if !Verify(secret, r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "order.processed", r.Header.Get(HeaderEvent))
+		assert.Equal(t, "42", r.Header.Get(HeaderID))
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	result := NewClient().Send(context.Background(), receiver.URL, secret, event)
+	require.True(t, result.OK(), "unexpected error: %v", result.Err)
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Equal(t, event.ID, received.ID)
+	assert.JSONEq(t, string(event.Data), string(received.Data))
+
+	result = NewClient().Send(context.Background(), receiver.URL, "wrong_secret", event)
+	assert.False(t, result.OK())
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+}
+
+func TestClient_SendFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name:    "server_error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+		},
+		{
+			name:    "redirect",
+			handler: func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/elsewhere", http.StatusFound) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := httptest.NewServer(tt.handler)
+			defer receiver.Close()
+
+			result := NewClient().Send(context.Background(), receiver.URL, "secret", Event{ID: 1, Type: "order.processed"})
+			assert.False(t, result.OK())
+			assert.NotZero(t, result.StatusCode)
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		receiver := httptest.NewServer(http.NotFoundHandler())
+		receiver.Close()
+
+		result := NewClient().Send(context.Background(), receiver.URL, "secret", Event{ID: 1, Type: "order.processed"})
+		assert.False(t, result.OK())
+		assert.Zero(t, result.StatusCode)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	signature := Sign("secret", 1700000000, body)
+
+	assert.True(t, Verify("secret", "1700000000", body, signature))
+	assert.False(t, Verify("secret", "1700000001", body, signature))
+	assert.False(t, Verify("other", "1700000000", body, signature))
+	assert.False(t, Verify("secret", "not-a-number", body, signature))
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 3, want: 2 * time.Minute},
+		{attempt: 7, want: 32 * time.Minute},
+		{attempt: 8, want: time.Hour},
+		{attempt: 20, want: time.Hour},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Backoff(tt.attempt), "attempt %d", tt.attempt)
+	}
+}